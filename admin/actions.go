@@ -0,0 +1,249 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+	"github.com/PulseDevelopmentGroup/0x626f74/web"
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// toggleRequest enables or disables a command in a guild (or everywhere,
+	// if the guild ID is empty)
+	toggleRequest struct {
+		Command string `json:"command"`
+		GuildID string `json:"guildID"`
+		Enabled bool   `json:"enabled"`
+	}
+
+	// removeRequest removes a simple command
+	removeRequest struct {
+		Command string `json:"command"`
+	}
+
+	// resetRequest resets a user's rate limit for a command
+	resetRequest struct {
+		Command string `json:"command"`
+		UserID  string `json:"userID"`
+	}
+
+	// result is the JSON response when a change is made
+	result struct {
+		Status string `json:"status"`
+		Note   string `json:"note,omitempty"`
+	}
+)
+
+/* Request bodies are small, anything bigger is a mistake */
+const maxBody = 1 << 20
+
+/* Changes to simple commands only last until the config is reloaded */
+const simpleNote = "Simple commands changed through the API are replaced " +
+	"when the config is reloaded, update the config to keep the change"
+
+/* === Endpoints === */
+
+// reload reloads the config, and applies it to the bot
+func (a *API) reload(w http.ResponseWriter, r *http.Request, caller string) {
+	if a.reloadFn == nil {
+		fail(w, http.StatusNotImplemented, "reloading isn't supported")
+		return
+	}
+
+	err := a.reloadFn()
+
+	if err != nil {
+		a.change(caller, "reload").WithError(err).Warn("Admin reload failed")
+		fail(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	a.change(caller, "reload").Info("Admin reloaded the config")
+	web.WriteJSON(w, http.StatusOK, result{Status: "reloaded"})
+}
+
+// toggle enables or disables a command
+func (a *API) toggle(w http.ResponseWriter, r *http.Request, caller string) {
+	var req toggleRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	name := strings.ToLower(req.Command)
+	if _, ok := a.mux.Commands[name]; !ok {
+		fail(w, http.StatusNotFound, fmt.Sprintf("there's no command %q", name))
+		return
+	}
+
+	status := "disabled"
+	if req.Enabled {
+		status = "enabled"
+		a.mux.Enable(name, req.GuildID)
+	} else {
+		a.mux.Disable(name, req.GuildID)
+	}
+
+	a.change(caller, "toggle").WithFields(logrus.Fields{
+		"command": name,
+		"guild":   req.GuildID,
+		"enabled": req.Enabled,
+	}).Infof("Admin %s a command", status)
+	web.WriteJSON(w, http.StatusOK, result{Status: status})
+}
+
+// setSimple adds a simple command, or replaces one with the same name
+func (a *API) setSimple(w http.ResponseWriter, r *http.Request, caller string) {
+	var v SimpleView
+	if !decode(w, r, &v) {
+		return
+	}
+
+	v.Command = strings.ToLower(strings.TrimSpace(v.Command))
+	if err := a.checkName(v.Command); err != nil {
+		fail(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(v.Content) == 0 && len(v.Responses) == 0 && v.Embed == nil &&
+		len(v.File) == 0 {
+		fail(w, http.StatusBadRequest, "simple commands need content, "+
+			"responses, an embed or a file")
+		return
+	}
+
+	s := v.simple()
+	if errs := a.mux.CheckSimpleCommand(s); len(errs) != 0 {
+		fail(w, http.StatusBadRequest, errs[0].Error())
+		return
+	}
+
+	_, exists := a.mux.GetSimple(v.Command)
+
+	a.config.Lock()
+	a.config.SimpleCommands[v.Command] = s
+	a.mux.RegisterSimple(s)
+	a.config.Unlock()
+
+	status := "added"
+	if exists {
+		status = "edited"
+	}
+
+	a.change(caller, "simple").WithField("command", v.Command).Infof(
+		"Admin %s a simple command", status,
+	)
+	web.WriteJSON(w, http.StatusOK, result{Status: status, Note: simpleNote})
+}
+
+// removeSimple removes a simple command
+func (a *API) removeSimple(
+	w http.ResponseWriter, r *http.Request, caller string,
+) {
+	var req removeRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	name := strings.ToLower(req.Command)
+	if err := a.checkName(name); err != nil {
+		fail(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, ok := a.mux.GetSimple(name); !ok {
+		fail(w, http.StatusNotFound, fmt.Sprintf("there's no simple command %q", name))
+		return
+	}
+
+	a.config.Lock()
+	delete(a.config.SimpleCommands, name)
+	a.mux.RemoveSimple(name)
+	a.config.Unlock()
+
+	a.change(caller, "simple").WithField("command", name).Info(
+		"Admin removed a simple command",
+	)
+	web.WriteJSON(w, http.StatusOK, result{Status: "removed", Note: simpleNote})
+}
+
+// resetRateLimit resets a user's rate limit for a command
+func (a *API) resetRateLimit(
+	w http.ResponseWriter, r *http.Request, caller string,
+) {
+	var req resetRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	if len(req.UserID) == 0 {
+		fail(w, http.StatusBadRequest, "a user ID is required")
+		return
+	}
+	if !a.mux.ResetRateLimit(req.Command, req.UserID) {
+		fail(w, http.StatusNotFound, fmt.Sprintf(
+			"there's no command %q with a rate limit", req.Command,
+		))
+		return
+	}
+
+	a.change(caller, "ratelimit").WithFields(logrus.Fields{
+		"command": strings.ToLower(req.Command),
+		"user":    req.UserID,
+	}).Info("Admin reset a rate limit")
+	web.WriteJSON(w, http.StatusOK, result{Status: "reset"})
+}
+
+/* === Helper Functions === */
+
+// checkName checks a simple command can be added, changed or removed with
+// the name. Commands and tags have to be managed their own way.
+func (a *API) checkName(name string) error {
+	if len(name) == 0 || strings.ContainsAny(name, " \t\n") {
+		return fmt.Errorf("%q isn't a valid command name", name)
+	}
+	if _, ok := a.mux.Commands[name]; ok {
+		return fmt.Errorf("%q is a command, not a simple command", name)
+	}
+	if a.tags != nil {
+		if _, ok := a.tags.Get(name); ok {
+			return fmt.Errorf("%q is a tag, manage it with the tag command", name)
+		}
+	}
+	return nil
+}
+
+// change returns a log entry for a change made by an admin
+func (a *API) change(caller, action string) *logrus.Entry {
+	return a.logs.Subsystem("admin").WithFields(logrus.Fields{
+		"admin":  caller,
+		"action": action,
+	})
+}
+
+// decode decodes a JSON request body, responding with an error if it can't
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBody))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		fail(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// simple converts the view back into a simple command
+func (v SimpleView) simple() multiplexer.SimpleCommand {
+	return multiplexer.SimpleCommand{
+		Command:   v.Command,
+		Content:   v.Content,
+		Responses: v.Responses,
+		HelpText:  v.HelpText,
+		Embed:     v.Embed,
+		File:      v.File,
+		Category:  v.Category,
+		Hidden:    v.Hidden,
+	}
+}
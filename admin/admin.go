@@ -0,0 +1,253 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/config"
+	"github.com/PulseDevelopmentGroup/0x626f74/log"
+	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+	"github.com/PulseDevelopmentGroup/0x626f74/stats"
+	"github.com/PulseDevelopmentGroup/0x626f74/tags"
+	"github.com/PulseDevelopmentGroup/0x626f74/web"
+)
+
+type (
+	// API serves information about the running bot to callers with an admin
+	// token, along with a dashboard which uses it, and lets them make changes
+	// (which are logged with their name). Initialized with New().
+	API struct {
+		mux      *multiplexer.Mux
+		logs     *log.Logs
+		config   *config.BotConfig
+		tags     *tags.Store
+		stats    *stats.Store
+		reloadFn func() error
+		env      map[string]string
+		tokens   map[string]string // Token to the name of its owner
+	}
+
+	// Options are what the API serves, and who can use it
+	Options struct {
+		Mux  *multiplexer.Mux
+		Logs *log.Logs
+		// Config is locked while it's read, and while simple commands are
+		// changed
+		Config *config.BotConfig
+		// Tags are protected from being changed as simple commands, if set
+		Tags *tags.Store
+		// Stats are exported by the stats endpoint, which isn't supported if
+		// it's nil. The database can't be opened elsewhere while the bot runs.
+		Stats *stats.Store
+		// Reload reloads the config and applies it to the multiplexer, locking
+		// the config itself. The reload endpoint isn't supported if it's nil.
+		Reload func() error
+
+		// Environment is shown along with the config. Secrets should already
+		// be redacted (see Redact).
+		Environment map[string]string
+		// Tokens maps each admin's name to their token (see ParseTokens)
+		Tokens map[string]string
+	}
+
+	// handler is an API endpoint, which is told who's calling it
+	handler func(w http.ResponseWriter, r *http.Request, caller string)
+
+	// apiError is the JSON response when a request fails
+	apiError struct {
+		Error string `json:"error"`
+	}
+)
+
+// New creates the API
+func New(opts Options) *API {
+	tokens := make(map[string]string)
+	for name, token := range opts.Tokens {
+		tokens[token] = name
+	}
+
+	return &API{
+		mux:      opts.Mux,
+		logs:     opts.Logs,
+		config:   opts.Config,
+		tags:     opts.Tags,
+		stats:    opts.Stats,
+		reloadFn: opts.Reload,
+		env:      opts.Environment,
+		tokens:   tokens,
+	}
+}
+
+// ParseTokens parses admin tokens in the format "name:token,name:token". Names
+// identify who made a request, in logs.
+func ParseTokens(s string) (map[string]string, error) {
+	tokens := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if len(pair) == 0 {
+			continue
+		}
+
+		i := strings.Index(pair, ":")
+		if i < 1 || i == len(pair)-1 {
+			return nil, fmt.Errorf("admin token %q isn't in the format name:token", pair)
+		}
+
+		name := pair[:i]
+		if _, ok := tokens[name]; ok {
+			return nil, fmt.Errorf("admin %q has more than one token", name)
+		}
+		tokens[name] = pair[i+1:]
+	}
+
+	return tokens, nil
+}
+
+// Register registers the API's endpoints (under /api/) and the dashboard
+// (/admin/) with the server.
+func (a *API) Register(s *web.Server) {
+	s.Handle("/admin/", http.HandlerFunc(dashboard))
+
+	s.Handle("/api/commands", a.endpoint(a.commands, nil))
+	s.Handle("/api/commands/toggle", a.endpoint(nil, a.toggle))
+	s.Handle("/api/simple", a.endpoint(a.simple, a.setSimple))
+	s.Handle("/api/simple/remove", a.endpoint(nil, a.removeSimple))
+	s.Handle("/api/config", a.endpoint(a.effectiveConfig, nil))
+	s.Handle("/api/reload", a.endpoint(nil, a.reload))
+	s.Handle("/api/errors", a.endpoint(a.errors, nil))
+	s.Handle("/api/ratelimits", a.endpoint(a.rateLimits, nil))
+	s.Handle("/api/ratelimits/reset", a.endpoint(nil, a.resetRateLimit))
+	s.Handle("/api/stats/export", a.endpoint(a.exportStats, nil))
+}
+
+/* === Endpoints === */
+
+func (a *API) commands(w http.ResponseWriter, r *http.Request, _ string) {
+	web.WriteJSON(w, http.StatusOK, a.mux.Reference())
+}
+
+func (a *API) simple(w http.ResponseWriter, r *http.Request, _ string) {
+	views := []SimpleView{}
+	for _, s := range a.mux.ListSimple() {
+		views = append(views, simpleView(s))
+	}
+	web.WriteJSON(w, http.StatusOK, views)
+}
+
+func (a *API) effectiveConfig(w http.ResponseWriter, r *http.Request, _ string) {
+	a.config.RLock()
+	defer a.config.RUnlock()
+
+	web.WriteJSON(w, http.StatusOK, configView(a.config, a.env))
+}
+
+func (a *API) errors(w http.ResponseWriter, r *http.Request, _ string) {
+	web.WriteJSON(w, http.StatusOK, a.logs.RecentErrors())
+}
+
+func (a *API) rateLimits(w http.ResponseWriter, r *http.Request, _ string) {
+	web.WriteJSON(w, http.StatusOK, a.mux.RateLimits())
+}
+
+// exportStats writes recorded command uses as csv or json (the format query
+// parameter), optionally only those within a window (ie. window=30d)
+func (a *API) exportStats(w http.ResponseWriter, r *http.Request, _ string) {
+	if a.stats == nil {
+		fail(w, http.StatusNotImplemented, "stats aren't being recorded")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", "csv":
+		format = "csv"
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	case "json":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	default:
+		fail(w, http.StatusBadRequest,
+			fmt.Sprintf("unknown export format %q, use csv or json", format))
+		return
+	}
+
+	var from time.Time
+	if window := r.URL.Query().Get("window"); len(window) != 0 {
+		d, err := stats.ParseWindow(window)
+		if err != nil {
+			fail(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		from = time.Now().Add(-d)
+	}
+
+	/* Headers are already sent if it fails part way, so it can only be logged */
+	if err := a.stats.Export(w, format, from, time.Time{}); err != nil {
+		a.logs.Subsystem("admin").WithError(err).Warn("Unable to export stats")
+	}
+}
+
+/* === Helper Functions === */
+
+// endpoint serves GET and POST requests with the supplied handlers (either of
+// which can be nil), to callers with a token.
+func (a *API) endpoint(get, post handler) http.Handler {
+	var allowed []string
+	if get != nil {
+		allowed = append(allowed, http.MethodGet, http.MethodHead)
+	}
+	if post != nil {
+		allowed = append(allowed, http.MethodPost)
+	}
+
+	return a.auth(func(w http.ResponseWriter, r *http.Request, caller string) {
+		switch {
+		case get != nil &&
+			(r.Method == http.MethodGet || r.Method == http.MethodHead):
+			get(w, r, caller)
+		case post != nil && r.Method == http.MethodPost:
+			post(w, r, caller)
+		default:
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			fail(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+	})
+}
+
+// auth only allows requests with a valid bearer token
+func (a *API) auth(h handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller, ok := a.caller(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			fail(w, http.StatusUnauthorized, "a valid admin token is required")
+			return
+		}
+		h(w, r, caller)
+	})
+}
+
+// caller finds the name of the admin whose token was sent with the request.
+// Every token is compared, so the time taken doesn't reveal anything.
+func (a *API) caller(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return "", false
+	}
+	sent := []byte(strings.TrimPrefix(header, "Bearer "))
+
+	name, found := "", false
+	for token, owner := range a.tokens {
+		if subtle.ConstantTimeCompare(sent, []byte(token)) == 1 {
+			name, found = owner, true
+		}
+	}
+	return name, found
+}
+
+// fail writes an error response
+func fail(w http.ResponseWriter, status int, msg string) {
+	web.WriteJSON(w, status, apiError{Error: msg})
+}
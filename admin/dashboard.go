@@ -0,0 +1,132 @@
+package admin
+
+import (
+	"net/http"
+)
+
+// dashboard serves the dashboard, which asks for an admin token and then reads
+// everything from the API. The page itself contains nothing secret.
+func dashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/admin/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte(dashboardHTML))
+}
+
+const dashboardHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>0x626f74 admin</title>
+<style>
+  body { font-family: sans-serif; margin: 2em; color: #222; }
+  h1 { font-size: 1.4em; }
+  h2 { font-size: 1.1em; margin-top: 2em; border-bottom: 1px solid #ccc; }
+  table { border-collapse: collapse; width: 100%; }
+  th, td { text-align: left; padding: .3em .6em; vertical-align: top; }
+  tr:nth-child(even) { background: #f4f4f4; }
+  code, pre { font-family: monospace; }
+  pre { background: #f4f4f4; padding: 1em; overflow: auto; }
+  .error { color: #b00; }
+  #login { display: none; }
+</style>
+</head>
+<body>
+<h1>0x626f74 admin</h1>
+
+<form id="login">
+  <label>Admin token <input type="password" id="token" autocomplete="off"></label>
+  <button type="submit">Sign in</button>
+  <span class="error" id="login-error"></span>
+</form>
+
+<div id="content"></div>
+
+<script>
+"use strict";
+
+function esc(s) {
+  const d = document.createElement("div");
+  d.textContent = s == null ? "" : String(s);
+  return d.innerHTML;
+}
+
+function table(rows, cols) {
+  if (!rows || rows.length === 0) return "<p>None</p>";
+  let h = "<table><tr>" + cols.map(c => "<th>" + esc(c[0]) + "</th>").join("") + "</tr>";
+  for (const r of rows) {
+    h += "<tr>" + cols.map(c => "<td>" + c[1](r) + "</td>").join("") + "</tr>";
+  }
+  return h + "</table>";
+}
+
+async function get(path) {
+  const res = await fetch(path, {
+    headers: { "Authorization": "Bearer " + sessionStorage.getItem("token") },
+  });
+  if (res.status === 401) throw new Error("unauthorized");
+  return res.json();
+}
+
+async function load() {
+  let commands, errors, limits, config;
+  try {
+    [commands, errors, limits, config] = await Promise.all([
+      get("/api/commands"), get("/api/errors"),
+      get("/api/ratelimits"), get("/api/config"),
+    ]);
+  } catch (e) {
+    sessionStorage.removeItem("token");
+    document.getElementById("login").style.display = "block";
+    document.getElementById("login-error").textContent =
+      e.message === "unauthorized" ? "That token isn't valid" : e.message;
+    return;
+  }
+
+  document.getElementById("content").innerHTML =
+    "<h2>Recent errors</h2>" + table(errors, [
+      ["Time", r => esc(new Date(r.time).toLocaleString())],
+      ["Severity", r => esc(r.severity)],
+      ["Invocation", r => "<code>" + esc(r.invocation) + "</code>"],
+      ["Command", r => "<a href='" + esc(r.messageURL) + "'><code>" + esc(r.text) + "</code></a>"],
+      ["User", r => esc(r.user)],
+      ["Channel", r => "#" + esc(r.channel)],
+      ["Message", r => esc(r.message)],
+      ["Error", r => "<span class='error'>" + esc(r.error) + "</span>"],
+    ]) +
+    "<h2>Rate limits</h2>" + table(limits, [
+      ["Command", r => "<code>" + esc(r.command) + "</code>"],
+      ["Limit", r => esc(r.max + " per " + r.window)],
+      ["Users", r => r.users.length === 0 ? "None" : r.users.map(u =>
+        esc(u.userID + ": " + u.uses + (u.limited ? " (limited)" : ""))).join("<br>")],
+    ]) +
+    "<h2>Commands</h2>" + table(commands, [
+      ["Usage", r => "<code>" + esc(r.usage) + "</code>"],
+      ["Category", r => esc(r.category)],
+      ["Help", r => esc(r.helpText)],
+      ["Kind", r => r.simple ? "Simple" : "Command"],
+      ["Hidden", r => r.hidden ? "Yes" : ""],
+    ]) +
+    "<h2>Config</h2><pre>" + esc(JSON.stringify(config, null, 2)) + "</pre>";
+}
+
+document.getElementById("login").addEventListener("submit", e => {
+  e.preventDefault();
+  sessionStorage.setItem("token", document.getElementById("token").value);
+  document.getElementById("login").style.display = "none";
+  load();
+});
+
+if (sessionStorage.getItem("token")) {
+  load();
+} else {
+  document.getElementById("login").style.display = "block";
+}
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,139 @@
+package admin
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/config"
+	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+)
+
+type (
+	// SimpleView is a simple command as it's shown by the API
+	SimpleView struct {
+		Command   string                   `json:"command"`
+		Content   string                   `json:"content,omitempty"`
+		Responses []string                 `json:"responses,omitempty"`
+		HelpText  string                   `json:"help,omitempty"`
+		Embed     *multiplexer.SimpleEmbed `json:"embed,omitempty"`
+		File      string                   `json:"file,omitempty"`
+		Category  string                   `json:"category,omitempty"`
+		Hidden    bool                     `json:"hidden,omitempty"`
+	}
+
+	// ResponderView is an auto-responder as it's shown by the API
+	ResponderView struct {
+		Name     string   `json:"name"`
+		Pattern  string   `json:"pattern,omitempty"`
+		Keywords []string `json:"keywords,omitempty"`
+		ChanIDs  []string `json:"channels,omitempty"`
+		RoleIDs  []string `json:"roles,omitempty"`
+		Cooldown string   `json:"cooldown,omitempty"`
+		Chance   int      `json:"chance,omitempty"`
+		Action   string   `json:"action"`
+		Reply    string   `json:"reply,omitempty"`
+		Reaction string   `json:"reaction,omitempty"`
+	}
+
+	// AuditView is the audit section of the config
+	AuditView struct {
+		Channel   string `json:"channel"`
+		CacheSize int    `json:"cacheSize"`
+	}
+
+	// ConfigView is the effective config, along with the environment
+	ConfigView struct {
+		Environment    map[string]string                          `json:"environment"`
+		Path           string                                     `json:"path"`
+		ErrorChannel   string                                     `json:"errorChannel"`
+		Audit          AuditView                                  `json:"audit"`
+		SimpleCommands []SimpleView                               `json:"simpleCommands"`
+		AutoResponders []ResponderView                            `json:"autoResponders"`
+		Permissions    map[string]*multiplexer.CommandPermissions `json:"permissions"`
+	}
+)
+
+// Redact hides a secret, so it can be shown whether it's set
+func Redact(secret string) string {
+	if len(secret) == 0 {
+		return ""
+	}
+	return "[redacted]"
+}
+
+// RedactURL hides any credentials in a URL (user info and the query string)
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Redact(raw)
+	}
+
+	if u.User != nil {
+		u.User = url.User("redacted")
+	}
+	if len(u.RawQuery) != 0 {
+		u.RawQuery = "redacted"
+	}
+	return u.String()
+}
+
+func simpleView(s multiplexer.SimpleCommand) SimpleView {
+	return SimpleView{
+		Command:   strings.ToLower(s.Command),
+		Content:   s.Content,
+		Responses: s.Responses,
+		HelpText:  s.HelpText,
+		Embed:     s.Embed,
+		File:      s.File,
+		Category:  s.Category,
+		Hidden:    s.Hidden,
+	}
+}
+
+func responderView(r *multiplexer.AutoResponder) ResponderView {
+	v := ResponderView{
+		Name:     r.Name,
+		Keywords: r.Keywords,
+		ChanIDs:  r.ChanIDs,
+		RoleIDs:  r.RoleIDs,
+		Chance:   r.Chance,
+		Action:   string(r.Action),
+		Reply:    r.Reply,
+		Reaction: r.Reaction,
+	}
+	if r.Pattern != nil {
+		v.Pattern = r.Pattern.String()
+	}
+	if r.Cooldown != 0 {
+		v.Cooldown = r.Cooldown.String()
+	}
+	return v
+}
+
+func configView(cfg *config.BotConfig, env map[string]string) ConfigView {
+	v := ConfigView{
+		Environment:  env,
+		Path:         RedactURL(cfg.Path),
+		ErrorChannel: cfg.ErrorChannel,
+		Audit: AuditView{
+			Channel:   cfg.AuditChannel,
+			CacheSize: cfg.AuditCacheSize,
+		},
+		SimpleCommands: []SimpleView{},
+		AutoResponders: []ResponderView{},
+		Permissions:    cfg.Permissions,
+	}
+
+	for _, s := range cfg.SimpleCommands {
+		v.SimpleCommands = append(v.SimpleCommands, simpleView(s))
+	}
+	sort.Slice(v.SimpleCommands, func(i, j int) bool {
+		return v.SimpleCommands[i].Command < v.SimpleCommands[j].Command
+	})
+
+	for _, r := range cfg.AutoResponders {
+		v.AutoResponders = append(v.AutoResponders, responderView(r))
+	}
+	return v
+}
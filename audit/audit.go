@@ -0,0 +1,510 @@
+package audit
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/PulseDevelopmentGroup/0x626f74/snowflake"
+	"github.com/PulseDevelopmentGroup/0x626f74/util"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// Auditor posts what moderators would want a record of (edited and
+	// deleted messages, members joining and leaving, and roles changing) to
+	// the audit channel. Recent messages are cached so deleted ones can be
+	// recovered. Initialized with New().
+	Auditor struct {
+		Logger *logrus.Entry
+
+		channel  string
+		messages *messageCache
+		mu       sync.RWMutex
+
+		/* Each member's last known roles, by guild then user */
+		roles   map[string]map[string][]string
+		rolesMu sync.Mutex
+	}
+
+	// entry is something to be posted to the audit channel
+	entry struct {
+		Title  string
+		Color  int
+		URL    string
+		User   *discordgo.User
+		Fields []*discordgo.MessageEmbedField
+	}
+)
+
+/* Colors of each kind of entry */
+const (
+	colorEdit   = 0x3498db
+	colorDelete = 0xe74c3c
+	colorJoin   = 0x2ecc71
+	colorLeave  = 0x95a5a6
+	colorRoles  = 0x9b59b6
+)
+
+/* Deleted messages shown when many are deleted, leaving room for a summary */
+const maxBulkFields = 20
+
+// New creates an auditor which posts to the channel, and keeps up to cacheSize
+// recent messages. Nothing is posted until the channel is set.
+func New(channel string, cacheSize int, logger *logrus.Entry) *Auditor {
+	return &Auditor{
+		Logger:   logger,
+		channel:  channel,
+		messages: newMessageCache(cacheSize),
+		roles:    make(map[string]map[string][]string),
+	}
+}
+
+// SetChannel changes the channel entries are posted to. Nothing is posted if
+// it's empty, but messages are still cached.
+func (a *Auditor) SetChannel(channel string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.channel = channel
+}
+
+// SetCacheSize changes how many recent messages are kept, keeping the newest
+func (a *Auditor) SetCacheSize(size int) {
+	a.messages.resize(size)
+}
+
+// Register adds the auditor's listeners to the multiplexer
+func (a *Auditor) Register(m *multiplexer.Mux) {
+	m.OnMessageCreate("audit", a.messageCreate)
+	m.OnMessageUpdate("audit", a.messageUpdate)
+	m.OnMessageDelete("audit", a.messageDelete)
+	m.OnMessageDeleteBulk("audit", a.messageDeleteBulk)
+	m.OnMemberJoin("audit", a.memberJoin)
+	m.OnMemberLeave("audit", a.memberLeave)
+	m.OnMemberUpdate("audit", a.memberUpdate)
+	m.OnGuildCreate("audit", a.guildCreate)
+}
+
+// SelfAssign records a member giving themselves a role (or taking it away)
+// with a command. Unless the gateway reported it first, the role change this
+// caused isn't posted again. Does nothing if the auditor is nil.
+func (a *Auditor) SelfAssign(
+	ctx *multiplexer.Context, member *discordgo.Member, roleID string, given bool,
+) {
+	if a == nil {
+		return
+	}
+
+	roles := make([]string, 0, len(member.Roles)+1)
+	for _, r := range member.Roles {
+		if r != roleID {
+			roles = append(roles, r)
+		}
+	}
+	if given {
+		roles = append(roles, roleID)
+	}
+	a.setRoles(ctx.Message.GuildID, member.User.ID, roles)
+
+	title, field := "🏷️ Role taken", "➖ Role"
+	if given {
+		title, field = "🏷️ Role given", "➕ Role"
+	}
+
+	a.post(ctx.Session, entry{
+		Title: fmt.Sprintf("%s with `%s%s`", title, ctx.Prefix, ctx.Command),
+		Color: colorRoles,
+		URL: util.GetMsgURL(
+			ctx.Message.GuildID, ctx.Message.ChannelID, ctx.Message.ID,
+		),
+		User: ctx.Message.Author,
+		Fields: []*discordgo.MessageEmbedField{
+			userField(ctx.Message.Author),
+			channelField(ctx.Message.ChannelID),
+			{Name: field, Value: "<@&" + roleID + ">", Inline: true},
+		},
+	})
+}
+
+/* === Listeners === */
+
+// messageCreate caches messages, so they can be shown once they're edited or
+// deleted
+func (a *Auditor) messageCreate(s session.Session, m *discordgo.MessageCreate) {
+	if len(m.GuildID) == 0 {
+		return
+	}
+	a.messages.put(cacheable(m.Message))
+}
+
+// messageUpdate posts a message's content before and after it was edited
+func (a *Auditor) messageUpdate(s session.Session, m *discordgo.MessageUpdate) {
+	/* Embeds being added to a message are updates without an author */
+	if len(m.GuildID) == 0 || m.Author == nil || m.Author.Bot {
+		return
+	}
+
+	before := a.messages.get(m.ID)
+	if before == nil && m.BeforeUpdate != nil {
+		before = cacheable(m.BeforeUpdate)
+	}
+
+	after := cacheable(m.Message)
+	if before != nil {
+		after.Time = before.Time
+	}
+	a.messages.put(after)
+
+	if before != nil && before.Content == m.Content {
+		return
+	}
+
+	beforeText := "*Not in the cache of recent messages*"
+	if before != nil {
+		beforeText = content(before)
+	}
+
+	a.post(s, entry{
+		Title: "✏️ Message edited",
+		Color: colorEdit,
+		URL:   util.GetMsgURL(m.GuildID, m.ChannelID, m.ID),
+		User:  m.Author,
+		Fields: []*discordgo.MessageEmbedField{
+			userField(m.Author),
+			channelField(m.ChannelID),
+			{Name: "📄 Before", Value: beforeText},
+			{Name: "📝 After", Value: content(after)},
+		},
+	}, m.ChannelID)
+}
+
+// messageDelete posts a deleted message's content, if it was cached
+func (a *Auditor) messageDelete(s session.Session, m *discordgo.MessageDelete) {
+	if len(m.GuildID) == 0 {
+		return
+	}
+
+	deleted := a.messages.take(m.ID)
+	if deleted == nil && m.BeforeDelete != nil {
+		deleted = cacheable(m.BeforeDelete)
+	}
+
+	/* The bot cleans up after itself, which isn't worth recording */
+	if deleted != nil && deleted.Author != nil && deleted.Author.Bot {
+		return
+	}
+
+	if deleted == nil {
+		a.post(s, entry{
+			Title: "🗑️ Message deleted",
+			Color: colorDelete,
+			Fields: []*discordgo.MessageEmbedField{
+				channelField(m.ChannelID),
+				{
+					Name:  "📄 Content",
+					Value: "*Not in the cache of recent messages*",
+				},
+			},
+		}, m.ChannelID)
+		return
+	}
+
+	a.post(s, entry{
+		Title: "🗑️ Message deleted",
+		Color: colorDelete,
+		User:  deleted.Author,
+		Fields: []*discordgo.MessageEmbedField{
+			userField(deleted.Author),
+			channelField(deleted.ChannelID),
+			{Name: "🕒 Sent", Value: sent(deleted.Time), Inline: true},
+			{Name: "📄 Content", Value: content(deleted)},
+		},
+	}, m.ChannelID)
+}
+
+// messageDeleteBulk posts the content of as many of the deleted messages as
+// were cached
+func (a *Auditor) messageDeleteBulk(
+	s session.Session, m *discordgo.MessageDeleteBulk,
+) {
+	if len(m.GuildID) == 0 {
+		return
+	}
+
+	var recovered []*cachedMessage
+	missing := 0
+	for _, id := range m.Messages {
+		deleted := a.messages.take(id)
+		switch {
+		case deleted == nil:
+			missing++
+		case deleted.Author == nil || !deleted.Author.Bot:
+			recovered = append(recovered, deleted)
+		}
+	}
+
+	fields := []*discordgo.MessageEmbedField{
+		channelField(m.ChannelID),
+		{
+			Name:   "🔢 Deleted",
+			Value:  fmt.Sprintf("%d (%d not cached)", len(m.Messages), missing),
+			Inline: true,
+		},
+	}
+	for i, d := range recovered {
+		if i == maxBulkFields {
+			fields = append(fields, &discordgo.MessageEmbedField{
+				Name:  "➕ More",
+				Value: fmt.Sprintf("%d more messages", len(recovered)-i),
+			})
+			break
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  fmt.Sprintf("%s • %s", username(d.Author), sent(d.Time)),
+			Value: content(d),
+		})
+	}
+
+	a.post(s, entry{
+		Title:  fmt.Sprintf("🗑️ %d messages deleted", len(m.Messages)),
+		Color:  colorDelete,
+		Fields: fields,
+	}, m.ChannelID)
+}
+
+// memberJoin posts members joining, with how old their account is
+func (a *Auditor) memberJoin(s session.Session, m *discordgo.GuildMemberAdd) {
+	a.setRoles(m.GuildID, m.User.ID, m.Roles)
+
+	created := "unknown"
+	if t, err := snowflake.Time(m.User.ID); err == nil {
+		created = sent(t)
+	}
+
+	a.post(s, entry{
+		Title: "📥 Member joined",
+		Color: colorJoin,
+		User:  m.User,
+		Fields: []*discordgo.MessageEmbedField{
+			userField(m.User),
+			{Name: "🎂 Account created", Value: created, Inline: true},
+		},
+	})
+}
+
+// memberLeave posts members leaving, with the roles they had
+func (a *Auditor) memberLeave(s session.Session, m *discordgo.GuildMemberRemove) {
+	roles, ok := a.takeRoles(m.GuildID, m.User.ID)
+	if !ok {
+		roles = m.Roles
+	}
+
+	a.post(s, entry{
+		Title: "📤 Member left",
+		Color: colorLeave,
+		User:  m.User,
+		Fields: []*discordgo.MessageEmbedField{
+			userField(m.User),
+			{Name: "🏷️ Roles", Value: roleList(roles)},
+		},
+	})
+}
+
+// memberUpdate posts the roles a member was given or had taken away. Changes
+// can only be worked out for members whose roles were already known.
+func (a *Auditor) memberUpdate(s session.Session, m *discordgo.GuildMemberUpdate) {
+	previous, known := a.setRoles(m.GuildID, m.User.ID, m.Roles)
+	if !known {
+		a.Logger.WithField("user", m.User.ID).Debug(
+			"Roles changed for a member whose roles weren't known",
+		)
+		return
+	}
+
+	added := difference(m.Roles, previous)
+	removed := difference(previous, m.Roles)
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+
+	fields := []*discordgo.MessageEmbedField{userField(m.User)}
+	if len(added) != 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name: "➕ Given", Value: roleList(added), Inline: true,
+		})
+	}
+	if len(removed) != 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name: "➖ Taken", Value: roleList(removed), Inline: true,
+		})
+	}
+
+	a.post(s, entry{
+		Title:  "🏷️ Roles changed",
+		Color:  colorRoles,
+		User:   m.User,
+		Fields: fields,
+	})
+}
+
+// guildCreate learns the roles of the members in the guild, so later changes
+// can be worked out
+func (a *Auditor) guildCreate(s session.Session, g *discordgo.GuildCreate) {
+	for _, m := range g.Members {
+		if m.User != nil {
+			a.setRoles(g.ID, m.User.ID, m.Roles)
+		}
+	}
+}
+
+/* === Helper Functions === */
+
+// post sends an entry to the audit channel. Events which happened in the audit
+// channel itself aren't posted, so the bot can't audit its own entries.
+func (a *Auditor) post(s session.Session, e entry, channelID ...string) {
+	a.mu.RLock()
+	channel := a.channel
+	a.mu.RUnlock()
+
+	if len(channel) == 0 {
+		return
+	}
+	if len(channelID) != 0 && channelID[0] == channel {
+		return
+	}
+
+	if _, err := s.ChannelMessageSendEmbed(channel, e.embed()); err != nil {
+		a.Logger.WithError(err).WithField("entry", e.Title).Warn(
+			"Unable to post to the audit channel",
+		)
+	}
+}
+
+// embed builds the embed for an entry, laid out like error reports
+func (e entry) embed() *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Color:     e.Color,
+		Title:     e.Title,
+		URL:       e.URL,
+		Timestamp: time.Now().UTC().Format("2006-01-02T15:04:05.000Z"),
+		Fields:    e.Fields,
+	}
+	if e.User != nil {
+		embed.Author = &discordgo.MessageEmbedAuthor{
+			IconURL: e.User.AvatarURL(""),
+			Name:    username(e.User),
+		}
+		embed.Footer = &discordgo.MessageEmbedFooter{
+			Text: "User ID " + e.User.ID,
+		}
+	}
+	util.LimitEmbed(embed)
+	return embed
+}
+
+// setRoles remembers a member's roles, returning the roles they had before
+// and whether they were known
+func (a *Auditor) setRoles(guildID, userID string, roles []string) (
+	[]string, bool,
+) {
+	a.rolesMu.Lock()
+	defer a.rolesMu.Unlock()
+
+	members, ok := a.roles[guildID]
+	if !ok {
+		members = make(map[string][]string)
+		a.roles[guildID] = members
+	}
+
+	previous, known := members[userID]
+	members[userID] = append([]string(nil), roles...)
+	return previous, known
+}
+
+// takeRoles forgets a member's roles, returning them if they were known
+func (a *Auditor) takeRoles(guildID, userID string) ([]string, bool) {
+	a.rolesMu.Lock()
+	defer a.rolesMu.Unlock()
+
+	roles, ok := a.roles[guildID][userID]
+	delete(a.roles[guildID], userID)
+	return roles, ok
+}
+
+// userField is the field showing who an entry is about
+func userField(u *discordgo.User) *discordgo.MessageEmbedField {
+	if u == nil {
+		return &discordgo.MessageEmbedField{
+			Name: "🚶 User", Value: "unknown", Inline: true,
+		}
+	}
+	return &discordgo.MessageEmbedField{
+		Name:   "🚶 User",
+		Value:  fmt.Sprintf("%s (%s)", u.Mention(), username(u)),
+		Inline: true,
+	}
+}
+
+// channelField is the field showing where an entry happened
+func channelField(channelID string) *discordgo.MessageEmbedField {
+	return &discordgo.MessageEmbedField{
+		Name: "#️⃣ Channel", Value: "<#" + channelID + ">", Inline: true,
+	}
+}
+
+// username is the user's name and discriminator
+func username(u *discordgo.User) string {
+	if u == nil {
+		return "unknown"
+	}
+	return u.String()
+}
+
+// content is a cached message's content, with any attachments listed after it
+func content(m *cachedMessage) string {
+	lines := make([]string, 0, len(m.Files)+1)
+	if len(m.Content) != 0 {
+		lines = append(lines, m.Content)
+	}
+	for _, f := range m.Files {
+		lines = append(lines, "📎 "+f)
+	}
+	if len(lines) == 0 {
+		return "*No content*"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sent formats when something happened
+func sent(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05 MST")
+}
+
+// roleList mentions each of the roles
+func roleList(roles []string) string {
+	if len(roles) == 0 {
+		return "*None*"
+	}
+
+	mentions := make([]string, len(roles))
+	for i, r := range roles {
+		mentions[i] = "<@&" + r + ">"
+	}
+	return strings.Join(mentions, " ")
+}
+
+// difference returns the roles in a which aren't in b
+func difference(a, b []string) []string {
+	var out []string
+	for _, r := range a {
+		if !util.ArrayContains(b, r, false) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,136 @@
+package audit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type (
+	// cachedMessage is what's kept of a message, so it can be shown once it's
+	// been edited or deleted
+	cachedMessage struct {
+		ID        string
+		GuildID   string
+		ChannelID string
+		Author    *discordgo.User
+		Content   string
+		Files     []string
+		Time      time.Time
+	}
+
+	// messageCache keeps the most recent messages, oldest replaced first
+	messageCache struct {
+		messages map[string]*cachedMessage
+		order    []string
+		next     int
+		mu       sync.Mutex
+	}
+)
+
+// newMessageCache creates a cache which keeps up to size messages. Nothing is
+// kept if size is 0.
+func newMessageCache(size int) *messageCache {
+	return &messageCache{
+		messages: make(map[string]*cachedMessage),
+		order:    make([]string, size),
+	}
+}
+
+// put adds a message to the cache, or replaces it if it's already cached
+func (c *messageCache) put(m *cachedMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.order) == 0 {
+		return
+	}
+
+	if _, ok := c.messages[m.ID]; ok {
+		c.messages[m.ID] = m
+		return
+	}
+
+	delete(c.messages, c.order[c.next])
+	c.order[c.next] = m.ID
+	c.next = (c.next + 1) % len(c.order)
+	c.messages[m.ID] = m
+}
+
+// get returns a cached message, or nil if it isn't cached
+func (c *messageCache) get(id string) *cachedMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.messages[id]
+}
+
+// take removes a message from the cache, returning it (or nil if it wasn't
+// cached)
+func (c *messageCache) take(id string) *cachedMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	m := c.messages[id]
+	delete(c.messages, id)
+	return m
+}
+
+// resize changes how many messages are kept, keeping the newest ones
+func (c *messageCache) resize(size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if size == len(c.order) {
+		return
+	}
+
+	/* Walk from the newest message back, keeping as many as fit */
+	order := make([]string, size)
+	kept := make(map[string]*cachedMessage)
+	n := 0
+	for i := 1; i <= len(c.order) && n < size; i++ {
+		id := c.order[(c.next-i+len(c.order))%len(c.order)]
+		if m, ok := c.messages[id]; ok {
+			kept[id] = m
+			n++
+		}
+	}
+
+	/* Put them back oldest first */
+	i := 0
+	for j := len(c.order); j >= 1 && i < n; j-- {
+		id := c.order[(c.next-j+len(c.order))%len(c.order)]
+		if _, ok := kept[id]; ok {
+			order[i] = id
+			i++
+		}
+	}
+
+	c.messages = kept
+	c.order = order
+	c.next = 0
+	if size != 0 {
+		c.next = n % size
+	}
+}
+
+// cacheable converts a message to what's kept of it
+func cacheable(m *discordgo.Message) *cachedMessage {
+	cached := &cachedMessage{
+		ID:        m.ID,
+		GuildID:   m.GuildID,
+		ChannelID: m.ChannelID,
+		Author:    m.Author,
+		Content:   m.Content,
+		Time:      time.Now(),
+	}
+	if t, err := m.Timestamp.Parse(); err == nil {
+		cached.Time = t
+	}
+	for _, a := range m.Attachments {
+		cached.Files = append(cached.Files, a.URL)
+	}
+	return cached
+}
@@ -1,16 +1,34 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/PulseDevelopmentGroup/0x626f74/admin"
+	"github.com/PulseDevelopmentGroup/0x626f74/audit"
 	"github.com/PulseDevelopmentGroup/0x626f74/command"
 	"github.com/PulseDevelopmentGroup/0x626f74/config"
+	"github.com/PulseDevelopmentGroup/0x626f74/console"
 	"github.com/PulseDevelopmentGroup/0x626f74/log"
+	"github.com/PulseDevelopmentGroup/0x626f74/metrics"
 	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+	"github.com/PulseDevelopmentGroup/0x626f74/reactor"
+	"github.com/PulseDevelopmentGroup/0x626f74/replay"
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/PulseDevelopmentGroup/0x626f74/stats"
+	"github.com/PulseDevelopmentGroup/0x626f74/tags"
+	"github.com/PulseDevelopmentGroup/0x626f74/trace"
+	"github.com/PulseDevelopmentGroup/0x626f74/web"
 
 	"github.com/bwmarrin/discordgo"
 	goenv "github.com/caarlos0/env/v6"
@@ -19,12 +37,23 @@ import (
 )
 
 type environment struct {
-	Token          string `env:"BOT_TOKEN"`
-	PerspectiveKey string `env:"PERSPECTIVE_KEY"`
-	Debug          bool   `env:"DEBUG" envDefault:"false"`
-	DataDir        string `env:"DATA_DIR" envDefault:"data/"`
-	ConfigURL      string `env:"CONFIG_URL"`
-	Fuzzy          bool   `env:"USE_FUZZY" envDefault:"false"`
+	Token          string  `env:"BOT_TOKEN"`
+	PerspectiveKey string  `env:"PERSPECTIVE_KEY"`
+	Debug          bool    `env:"DEBUG" envDefault:"false"`
+	DataDir        string  `env:"DATA_DIR" envDefault:"data/"`
+	ConfigURL      string  `env:"CONFIG_URL"`
+	Fuzzy          bool    `env:"USE_FUZZY" envDefault:"false"`
+	HTTPAddr       string  `env:"HTTP_ADDR" envDefault:":8080"`
+	AdminTokens    string  `env:"ADMIN_TOKENS"`
+	LogFormat      string  `env:"LOG_FORMAT"`
+	LogRedact      string  `env:"LOG_REDACT" envDefault:"all"`
+	LogSampleRate  float64 `env:"LOG_SAMPLE_RATE" envDefault:"1"`
+	OTLPEndpoint   string  `env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	TraceFile      string  `env:"TRACE_FILE"`
+
+	ErrorWebhook string        `env:"ERROR_WEBHOOK"`
+	ErrorFile    string        `env:"ERROR_FILE"`
+	ErrorWindow  time.Duration `env:"ERROR_WINDOW" envDefault:"1m"`
 }
 
 var (
@@ -32,7 +61,59 @@ var (
 	cfg  *config.BotConfig
 	logs *log.Logs
 
+	/* Why the config last failed to reload, if it did. Guarded by cfg's lock. */
+	reloadErr error
+
 	prefix = "!"
+
+	dumpCommands = flag.String(
+		"dump-commands", "",
+		"Write the command reference (Markdown and JSON) to the directory and exit",
+	)
+
+	consoleMode = flag.Bool(
+		"console", false,
+		"Send messages from stdin to a fake session instead of connecting to Discord",
+	)
+	consoleUser = flag.String(
+		"console-user", "", "The user ID messages are sent from in console mode",
+	)
+	consoleRoles = flag.String(
+		"console-roles", "",
+		"Comma separated role IDs (or id=name) the console user has",
+	)
+	consoleChannel = flag.String(
+		"console-channel", "", "The channel ID messages are sent to in console mode",
+	)
+
+	recordPath = flag.String(
+		"record", "",
+		"Record events, and the bot's responses, to a scrubbed JSONL transcript",
+	)
+	recordContent = flag.Bool(
+		"record-content", false,
+		"Keep the content of messages which aren't commands when recording",
+	)
+	replayPath = flag.String(
+		"replay", "",
+		"Replay a transcript against a fake session and compare the bot's output",
+	)
+	goldenPath = flag.String(
+		"golden", "",
+		"Compare replayed output to this file instead of the recorded responses",
+	)
+	updateGolden = flag.Bool(
+		"update-golden", false, "Write the replayed output to the golden file",
+	)
+
+	exportStats = flag.String(
+		"export-stats", "",
+		"Write recorded command uses to stdout as csv or json, and exit "+
+			"(while the bot runs, use the admin API's /api/stats/export)",
+	)
+	exportWindow = flag.String(
+		"export-window", "", "Only export uses within this window (ie. 30d)",
+	)
 )
 
 func init() {
@@ -58,9 +139,31 @@ func init() {
 
 	/* Define logging setup */
 	logs = log.New(env.Debug, cfg.ErrorChannel)
+	logs.Reporter.WebhookURL = env.ErrorWebhook
+	logs.Reporter.File = env.ErrorFile
+	logs.Reporter.Window = env.ErrorWindow
+
+	if len(env.LogFormat) != 0 {
+		format, err := log.ParseFormat(env.LogFormat)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		logs.SetFormat(format)
+	}
 }
 
 func main() {
+	flag.Parse()
+
+	/* Export stats without starting the bot */
+	if len(*exportStats) != 0 {
+		if err := runExport(); err != nil {
+			logs.Primary.WithError(err).Fatal("Unable to export stats")
+		}
+		return
+	}
+
 	/* Initialize DiscordGo */
 	logs.Primary.Info("Starting Bot...")
 	dg, err := discordgo.New("Bot " + env.Token)
@@ -69,6 +172,52 @@ func main() {
 	}
 	logs.Primary.Info("Bot started")
 
+	/* When the bot is only being run locally, nothing is saved to (or loaded
+	from) the data directory, so it can't change the real bot's data */
+	local := len(*dumpCommands) != 0 || *consoleMode || len(*replayPath) != 0
+	dataPath := func(name string) string {
+		if local {
+			return ""
+		}
+		return env.DataDir + name
+	}
+
+	/* Load tags */
+	tagStore, err := tags.Open(dataPath("tags.json"))
+	if err != nil {
+		logs.Primary.WithError(err).Fatalf("Unable to load tags")
+	}
+
+	/* Decide how much of each message is logged */
+	redaction, err := log.ParseRedaction(env.LogRedact)
+	if err != nil {
+		logs.Primary.WithError(err).Fatal("Invalid LOG_REDACT")
+	}
+	if env.LogSampleRate < 0 || env.LogSampleRate > 1 {
+		logs.Primary.Fatal("LOG_SAMPLE_RATE must be between 0 and 1")
+	}
+	privacy, err := log.OpenPrivacy(
+		dataPath("privacy.json"), redaction, env.LogSampleRate,
+	)
+	if err != nil {
+		logs.Primary.WithError(err).Fatal("Unable to load privacy settings")
+	}
+	logs.UsePrivacy(privacy)
+
+	/* Record command uses, unless the bot is only being run locally */
+	var statsStore *stats.Store
+	if !local {
+		statsStore, err = stats.Open(env.DataDir + "stats.db")
+		if err != nil {
+			logs.Primary.WithError(err).Fatalf("Unable to open stats")
+		}
+		defer statsStore.Close()
+
+		statsStore.OnError = func(err error) {
+			logs.Primary.WithError(err).Warn("Unable to record command uses")
+		}
+	}
+
 	/* Initialize Mux */
 	mux, err := multiplexer.New(prefix)
 	if err != nil {
@@ -77,6 +226,29 @@ func main() {
 
 	/* Use the logging middleware with the multiplexer */
 	mux.UseMiddleware(logs.MuxMiddleware)
+	mux.UseEventMiddleware(logs.EventMiddleware)
+	mux.UseObserver(logs.Observe)
+	if statsStore != nil {
+		mux.UseObserver(statsStore.Observe)
+	}
+
+	/* Trace requests commands make, as part of their invocation */
+	http.DefaultTransport = &trace.Transport{Base: http.DefaultTransport}
+
+	/* Export each invocation's trace, if there's somewhere to send them */
+	if len(env.OTLPEndpoint) != 0 || len(env.TraceFile) != 0 {
+		traces := trace.NewExporter("0x626f74", env.OTLPEndpoint, env.TraceFile)
+		defer traces.Close()
+
+		traces.OnError = func(err error) {
+			logs.Subsystem("trace").WithError(err).Warn("Unable to export traces")
+		}
+		mux.UseObserver(func(
+			ctx *multiplexer.Context, _ multiplexer.Outcome, _ time.Duration,
+		) {
+			traces.Export(ctx.Trace)
+		})
+	}
 
 	/* Set Permissions */
 	mux.SetPermissions(cfg.Permissions)
@@ -86,33 +258,54 @@ func main() {
 		CommandNotFound: "Command not found.",
 		NoPermissions:   "You do not have permissions to execute that command.",
 		RateLimited:     "You've used this command too many times, wait a bit and try again.",
+		Disabled:        "That command is currently disabled.",
 	})
 
+	/* Recover from (and report) panics in commands and event listeners */
+	mux.SetPanicHandler(logs.Panic)
+
+	/* Post edits, deletes and membership changes to the audit channel */
+	auditor := audit.New(
+		cfg.AuditChannel, cfg.AuditCacheSize, logs.Subsystem("audit"),
+	)
+
+	/* Watch reactions for paginated embeds */
+	react := reactor.New(nil)
+	react.Logger = logs.Subsystem("reactor")
+
+	/* Rate limits count uses over a window */
+	shortWindow, longWindow := 5*time.Minute, 30*time.Minute
+
 	/* === Register all the things === */
 	mux.Register(
 		command.Wiki{
-			Command:      "wikirace",
-			HelpText:     "Start a wikirace",
-			RateLimitMax: 3,
-			RateLimitDB:  cache.New(5*time.Minute, 5*time.Minute),
-			Logger:       logs,
+			Command:         "wikirace",
+			HelpText:        "Start a wikirace",
+			RateLimitMax:    3,
+			RateLimitWindow: shortWindow,
+			RateLimitDB:     cache.New(shortWindow, shortWindow),
+			Logger:          logs,
 		},
 		command.Gatekeeper{
 			Command:  "role",
 			HelpText: "Manage your access to roles, and their related channels",
 			Logger:   logs,
+			Reactor:  react,
+			Audit:    auditor,
 		},
-		command.Help{
+		&command.Help{
 			Command:  "help",
 			HelpText: "Displays help  information regarding the bot's commands",
 			Logger:   logs,
+			Reactor:  react,
 		},
 		command.Inspire{
-			Command:      "inspire",
-			HelpText:     "Get an inspirational quote from inspirobot.me",
-			RateLimitMax: 3,
-			RateLimitDB:  cache.New(5*time.Minute, 5*time.Minute),
-			Logger:       logs,
+			Command:         "inspire",
+			HelpText:        "Get an inspirational quote from inspirobot.me",
+			RateLimitMax:    3,
+			RateLimitWindow: shortWindow,
+			RateLimitDB:     cache.New(shortWindow, shortWindow),
+			Logger:          logs,
 		},
 		command.JPEG{
 			Command:  "jpeg",
@@ -120,28 +313,69 @@ func main() {
 			Logger:   logs,
 		},
 		command.LMGTFY{
-			Command:      "googlehelp",
-			HelpText:     "In case someone isn't familiar with Google",
-			RateLimitMax: 2,
-			RateLimitDB:  cache.New(30*time.Minute, 30*time.Minute),
+			Command:         "googlehelp",
+			HelpText:        "In case someone isn't familiar with Google",
+			RateLimitMax:    2,
+			RateLimitWindow: longWindow,
+			RateLimitDB:     cache.New(longWindow, longWindow),
 		},
 		command.Toxic{
-			Command:      "toxic",
-			HelpText:     "Someone really acting up? Get a toxicity rating.",
-			Logger:       logs,
-			Key:          env.PerspectiveKey,
-			RateLimitDB:  cache.New(5*time.Minute, 5*time.Minute),
-			RateLimitMax: 5,
+			Command:         "toxic",
+			HelpText:        "Someone really acting up? Get a toxicity rating.",
+			Logger:          logs,
+			Key:             env.PerspectiveKey,
+			RateLimitWindow: shortWindow,
+			RateLimitDB:     cache.New(shortWindow, shortWindow),
+			RateLimitMax:    5,
+		},
+		command.Stats{
+			Command:  "stats",
+			HelpText: "See which commands are used, and by who",
+			Logger:   logs,
+			Store:    statsStore,
+		},
+		command.Privacy{
+			Command:  "privacy",
+			HelpText: "Opt out of having your messages logged",
+			Logger:   logs,
+		},
+		&command.LogLevel{
+			Command:  "loglevel",
+			HelpText: "See or change how much each part of the bot logs",
+			Logger:   logs,
+		},
+		&command.Tag{
+			Command:         "tag",
+			HelpText:        "Create your own simple commands",
+			Store:           tagStore,
+			Logger:          logs,
+			RateLimitMax:    5,
+			RateLimitWindow: shortWindow,
+			RateLimitDB:     cache.New(shortWindow, shortWindow),
 		},
 	)
 
-	for k := range cfg.SimpleCommands {
-		k := k
-		mux.RegisterSimple(multiplexer.SimpleCommand{
-			Command:  k,
-			Content:  cfg.SimpleCommands[k],
-			HelpText: "This is a simple command",
-		})
+	mux.SetDataDir(env.DataDir)
+	for _, c := range cfg.SimpleCommands {
+		mux.RegisterSimple(c)
+	}
+
+	/* Tags are registered last so they can never shadow another command */
+	for _, t := range tagStore.List() {
+		if mux.IsCommand(t.Name) {
+			logs.Subsystem("config").WithField("tag", t.Name).Warn(
+				"Tag conflicts with an existing command, skipping",
+			)
+			continue
+		}
+		mux.RegisterSimple(command.TagCommand(t))
+	}
+
+	mux.RegisterResponder(cfg.AutoResponders...)
+
+	/* Flag any broken simple commands (bad templates, missing files) */
+	for _, err := range mux.CheckSimple() {
+		logs.Subsystem("config").WithError(err).Warn("Problem with simple command")
 	}
 
 	/* Configure multiplexer options */
@@ -159,10 +393,70 @@ func main() {
 		mux.UseFuzzy()
 	}
 
+	/* Flip through paginated embeds */
+	mux.OnReactionAdd("reactor", react.Handle)
+
+	/* Keep recent messages, and audit changes to them and to members */
+	auditor.Register(mux)
+
+	/* Set the bot's status whenever it (re)connects */
+	mux.OnReady("status", setStatus)
+
 	/* === End Register === */
 
-	/* Handle commands and start DiscordGo */
-	dg.AddHandler(mux.Handle)
+	/* Write the command reference without connecting to Discord */
+	if len(*dumpCommands) != 0 {
+		if err := mux.WriteReference(*dumpCommands); err != nil {
+			logs.Primary.WithError(err).Fatal("Unable to write command reference")
+		}
+		logs.Primary.Infof("Wrote command reference to %s", *dumpCommands)
+		return
+	}
+
+	/* Try out commands locally, without connecting to Discord */
+	if *consoleMode {
+		runConsole(mux)
+		return
+	}
+
+	/* Check the bot's responses to a recorded transcript */
+	if len(*replayPath) != 0 {
+		os.Exit(runReplay(mux))
+	}
+
+	/* Serve health checks and metrics */
+	if len(env.HTTPAddr) != 0 {
+		server := startServer(dg, mux, react, tagStore, statsStore, auditor)
+		defer server.Close()
+	}
+
+	/* Handle commands and events, and start DiscordGo */
+	if len(*recordPath) != 0 {
+		f, err := os.Create(*recordPath)
+		if err != nil {
+			logs.Primary.WithError(err).Fatal("Unable to create the transcript")
+		}
+		defer f.Close()
+
+		rec := replay.NewRecorder(f, prefix)
+		rec.KeepContent = *recordContent
+		dg.AddHandler(func(s *discordgo.Session, e interface{}) {
+			if err := rec.Event(e); err != nil {
+				logs.Primary.WithError(err).Warn("Unable to record event")
+			}
+
+			wrapped := rec.Session(session.Wrap(s))
+			if m, ok := e.(*discordgo.MessageCreate); ok {
+				mux.HandleMessage(wrapped, m)
+				return
+			}
+			mux.HandleEvent(wrapped, e)
+		})
+		logs.Primary.Infof("Recording to %s", *recordPath)
+	} else {
+		dg.AddHandler(mux.Handle)
+		mux.AttachEvents(dg)
+	}
 
 	err = dg.Open()
 	if err != nil {
@@ -172,8 +466,253 @@ func main() {
 		return
 	}
 
+	defer dg.Close()
+
+	/* Wait for interrupt */
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+	<-sc
+}
+
+// startServer starts the HTTP server, and starts collecting metrics. The bot is
+// ready once it's connected to Discord. The admin API is only served if there
+// are admin tokens.
+func startServer(
+	dg *discordgo.Session,
+	mux *multiplexer.Mux,
+	react *reactor.Reactor,
+	tagStore *tags.Store,
+	statsStore *stats.Store,
+	auditor *audit.Auditor,
+) *web.Server {
+	registry := metrics.NewRegistry()
+	logs.UseMetrics(registry)
+	registry.GaugeFunc(
+		"bot_reactor_watches", "Messages being watched for reactions",
+		func() float64 { return float64(react.Size()) },
+	)
+
+	/* Count failed requests, including DiscordGo's (which uses the default
+	transport) */
+	http.DefaultTransport = &metrics.Transport{
+		Base: http.DefaultTransport, OnError: logs.HTTPError,
+	}
+
+	var connected int32
+	dg.AddHandler(func(_ *discordgo.Session, _ *discordgo.Ready) {
+		atomic.StoreInt32(&connected, 1)
+	})
+	dg.AddHandler(func(_ *discordgo.Session, _ *discordgo.Resumed) {
+		atomic.StoreInt32(&connected, 1)
+	})
+	dg.AddHandler(func(_ *discordgo.Session, _ *discordgo.Disconnect) {
+		atomic.StoreInt32(&connected, 0)
+	})
+
+	server := web.New(env.HTTPAddr, registry)
+	server.AddCheck("gateway", func() error {
+		if atomic.LoadInt32(&connected) == 0 {
+			return errors.New("not connected to Discord")
+		}
+		return nil
+	})
+	server.AddCheck("config", func() error {
+		cfg.RLock()
+		defer cfg.RUnlock()
+
+		if reloadErr != nil {
+			return fmt.Errorf("the last reload failed: %v", reloadErr)
+		}
+		return nil
+	})
+
+	tokens, err := admin.ParseTokens(env.AdminTokens)
+	if err != nil {
+		logs.Primary.WithError(err).Fatal("Unable to parse admin tokens")
+	}
+	if len(tokens) != 0 {
+		admin.New(admin.Options{
+			Mux:    mux,
+			Logs:   logs,
+			Config: cfg,
+			Tags:   tagStore,
+			Stats:  statsStore,
+			Reload: func() error { return reloadConfig(mux, tagStore, auditor) },
+			Environment: map[string]string{
+				"BOT_TOKEN":                   admin.Redact(env.Token),
+				"PERSPECTIVE_KEY":             admin.Redact(env.PerspectiveKey),
+				"DEBUG":                       strconv.FormatBool(env.Debug),
+				"DATA_DIR":                    env.DataDir,
+				"CONFIG_URL":                  admin.RedactURL(env.ConfigURL),
+				"USE_FUZZY":                   strconv.FormatBool(env.Fuzzy),
+				"HTTP_ADDR":                   env.HTTPAddr,
+				"ADMIN_TOKENS":                admin.Redact(env.AdminTokens),
+				"LOG_FORMAT":                  env.LogFormat,
+				"LOG_REDACT":                  env.LogRedact,
+				"LOG_SAMPLE_RATE":             strconv.FormatFloat(env.LogSampleRate, 'f', -1, 64),
+				"OTEL_EXPORTER_OTLP_ENDPOINT": admin.RedactURL(env.OTLPEndpoint),
+				"TRACE_FILE":                  env.TraceFile,
+				"ERROR_WEBHOOK":               admin.Redact(env.ErrorWebhook),
+				"ERROR_FILE":                  env.ErrorFile,
+				"ERROR_WINDOW":                env.ErrorWindow.String(),
+			},
+			Tokens: tokens,
+		}).Register(server)
+	}
+
+	if err := server.Start(); err != nil {
+		logs.Primary.WithError(err).Fatal("Unable to start the HTTP server")
+	}
+	logs.Primary.Infof("Serving health checks and metrics on %s", env.HTTPAddr)
+
+	return server
+}
+
+// reloadConfig reloads the config, and applies it to the multiplexer and the
+// auditor. Tags which were shadowed by a simple command that's been removed
+// are registered. If it fails, the bot isn't ready until it's reloaded.
+func reloadConfig(
+	mux *multiplexer.Mux, tagStore *tags.Store, auditor *audit.Auditor,
+) error {
+	/* Held throughout, so the multiplexer always matches the config */
+	cfg.Lock()
+	defer cfg.Unlock()
+
+	old := cfg.SimpleCommands
+	reloadErr = cfg.Update()
+	if reloadErr != nil {
+		return reloadErr
+	}
+
+	mux.SetPermissions(cfg.Permissions)
+	logs.Reporter.SetChannel(cfg.ErrorChannel)
+	auditor.SetChannel(cfg.AuditChannel)
+	auditor.SetCacheSize(cfg.AuditCacheSize)
+
+	for name := range old {
+		mux.RemoveSimple(name)
+	}
+	for _, c := range cfg.SimpleCommands {
+		mux.RegisterSimple(c)
+	}
+	for _, t := range tagStore.List() {
+		if !mux.IsCommand(t.Name) {
+			mux.RegisterSimple(command.TagCommand(t))
+		}
+	}
+
+	mux.ClearResponders()
+	mux.RegisterResponder(cfg.AutoResponders...)
+
+	for _, err := range mux.CheckSimple() {
+		logs.Subsystem("config").WithError(err).Warn("Problem with simple command")
+	}
+	return nil
+}
+
+// runExport writes the recorded command uses to stdout. The running bot holds
+// the database, so while it runs the admin API has to be used instead.
+func runExport() error {
+	var from time.Time
+	if len(*exportWindow) != 0 {
+		window, err := stats.ParseWindow(*exportWindow)
+		if err != nil {
+			return err
+		}
+		from = time.Now().Add(-window)
+	}
+
+	store, err := stats.Open(env.DataDir + "stats.db")
+	if errors.Is(err, stats.ErrInUse) {
+		return fmt.Errorf(
+			"%w. If the bot is running, export from its admin API instead "+
+				"(GET /api/stats/export?format=csv&window=30d)", err,
+		)
+	}
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	return store.Export(os.Stdout, *exportStats, from, time.Time{})
+}
+
+// runConsole runs the console on stdin and stdout until stdin is closed
+func runConsole(mux *multiplexer.Mux) {
+	var roles []string
+	if len(*consoleRoles) != 0 {
+		roles = strings.Split(*consoleRoles, ",")
+	}
+
+	c, err := console.New(mux, console.Options{
+		UserID:    *consoleUser,
+		Roles:     roles,
+		ChannelID: *consoleChannel,
+	}, os.Stdout)
+	if err != nil {
+		logs.Primary.WithError(err).Fatal("Unable to start the console")
+	}
+
+	if err := c.Run(os.Stdin); err != nil {
+		logs.Primary.WithError(err).Error("Problem reading from the console")
+	}
+}
+
+// runReplay replays the transcript, and compares the bot's output to the golden
+// file, or to what it did when the transcript was recorded. The exit code is
+// returned.
+func runReplay(mux *multiplexer.Mux) int {
+	f, err := os.Open(*replayPath)
+	if err != nil {
+		logs.Primary.WithError(err).Error("Unable to open the transcript")
+		return 1
+	}
+	defer f.Close()
+
+	result, err := replay.Replay(mux, f)
+	if err != nil {
+		logs.Primary.WithError(err).Error("Unable to replay the transcript")
+		return 1
+	}
+	got := replay.Render(result.Actions)
+
+	if *updateGolden {
+		if len(*goldenPath) == 0 {
+			logs.Primary.Error("--update-golden needs a --golden file")
+			return 1
+		}
+		if err := ioutil.WriteFile(*goldenPath, []byte(got), 0644); err != nil {
+			logs.Primary.WithError(err).Error("Unable to write the golden file")
+			return 1
+		}
+		logs.Primary.Infof("Wrote %s", *goldenPath)
+		return 0
+	}
+
+	want := replay.Render(result.Recorded)
+	if len(*goldenPath) != 0 {
+		b, err := ioutil.ReadFile(*goldenPath)
+		if err != nil {
+			logs.Primary.WithError(err).Error("Unable to read the golden file")
+			return 1
+		}
+		want = string(b)
+	}
+
+	if diff := replay.Diff(want, got); len(diff) != 0 {
+		fmt.Print(diff)
+		logs.Primary.Error("Replayed output doesn't match")
+		return 1
+	}
+
+	logs.Primary.Info("Replayed output matches")
+	return 0
+}
+
+// setStatus sets the bot's "Watching you" status
+func setStatus(s session.Session, r *discordgo.Ready) {
 	idle := 0
-	dg.UpdateStatusComplex(discordgo.UpdateStatusData{
+	s.UpdateStatusComplex(discordgo.UpdateStatusData{
 		IdleSince: &idle,
 		Game: &discordgo.Game{
 			Name: "you",
@@ -185,11 +724,4 @@ func main() {
 		},
 		Status: "online",
 	})
-
-	defer dg.Close()
-
-	/* Wait for interrupt */
-	sc := make(chan os.Signal, 1)
-	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
-	<-sc
 }
@@ -0,0 +1,128 @@
+package command
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/log"
+	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+	"github.com/PulseDevelopmentGroup/0x626f74/reactor"
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/bwmarrin/discordgo"
+)
+
+type (
+	// testBot is a multiplexer wired up like the bot, with a fake session
+	// holding guild "g", channel "c" and user "2"
+	testBot struct {
+		mux   *multiplexer.Mux
+		fake  *session.Fake
+		react *reactor.Reactor
+		logs  *log.Logs
+	}
+
+	// roundTripper stubs HTTP requests made with the default client
+	roundTripper func(*http.Request) (*http.Response, error)
+)
+
+func (fn roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
+
+// newTestBot creates a multiplexer with the commands returned by register,
+// which is given the bot's logs and reactor. Reactions are handled by the
+// reactor, like they are by the bot.
+func newTestBot(
+	t *testing.T, register func(*testBot) []multiplexer.Command,
+) *testBot {
+	mux, err := multiplexer.New("!")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &testBot{
+		mux:   mux,
+		fake:  session.NewFake(&discordgo.User{ID: "1", Username: "bot", Bot: true}),
+		react: reactor.New(nil),
+		logs:  log.New(true, ""),
+	}
+
+	mux.Register(register(b)...)
+	mux.OnReactionAdd("reactor", b.react.Handle)
+	mux.Initialize()
+
+	b.fake.AddGuild(&discordgo.Guild{
+		ID:       "g",
+		Channels: []*discordgo.Channel{{ID: "c", Name: "general"}},
+		Members: []*discordgo.Member{{
+			User: &discordgo.User{ID: "2", Username: "user"},
+		}},
+	})
+	return b
+}
+
+// send sends a message from the user, and waits for it to be handled. The
+// actions it caused are returned.
+func (b *testBot) send(content string) []session.Action {
+	return b.sendMessage(&discordgo.Message{Content: content})
+}
+
+// sendMessage sends a message (filling in who it's from and where), and
+// waits for it to be handled. The actions it caused are returned.
+func (b *testBot) sendMessage(msg *discordgo.Message) []session.Action {
+	msg.ChannelID, msg.GuildID = "c", "g"
+	if msg.Author == nil {
+		msg.Author = &discordgo.User{ID: "2", Username: "user"}
+	}
+
+	b.fake.ClearActions()
+	stored := b.fake.AddMessage(msg)
+	/* Gateway events are copies, which edits to the message don't change */
+	copied := *stored
+	b.mux.HandleMessage(b.fake, &discordgo.MessageCreate{Message: &copied})
+	b.mux.Wait()
+	return b.fake.Actions()
+}
+
+// reactTo adds the user's reaction to a message, and waits for it to be handled.
+// The actions it caused are returned.
+func (b *testBot) reactTo(messageID, emoji string) []session.Action {
+	b.fake.ClearActions()
+	b.mux.HandleEvent(b.fake, &discordgo.MessageReactionAdd{
+		MessageReaction: &discordgo.MessageReaction{
+			UserID: "2", MessageID: messageID, ChannelID: "c", GuildID: "g",
+			Emoji: discordgo.Emoji{Name: emoji},
+		},
+	})
+	b.mux.Wait()
+	return b.fake.Actions()
+}
+
+// stubHTTP replaces the default transport until the test ends
+func stubHTTP(t *testing.T, fn roundTripper) {
+	original := http.DefaultTransport
+	http.DefaultTransport = fn
+	t.Cleanup(func() { http.DefaultTransport = original })
+}
+
+// respond creates a response with the body
+func respond(status int, body []byte) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+	}
+}
+
+// ofType filters actions by their type
+func ofType(actions []session.Action, t session.ActionType) []session.Action {
+	var out []session.Action
+	for _, a := range actions {
+		if a.Type == t {
+			out = append(out, a)
+		}
+	}
+	return out
+}
@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/PulseDevelopmentGroup/0x626f74/audit"
 	"github.com/PulseDevelopmentGroup/0x626f74/log"
 	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+	"github.com/PulseDevelopmentGroup/0x626f74/reactor"
 	"github.com/PulseDevelopmentGroup/0x626f74/util"
+	"github.com/bwmarrin/discordgo"
 )
 
 // Gatekeeper is a bot command
@@ -14,12 +17,18 @@ type Gatekeeper struct {
 	Command  string
 	HelpText string
 
-	Logger *log.Logs
+	Logger  *log.Logs
+	Reactor *reactor.Reactor
+	// Audit records roles being given and taken, if set
+	Audit *audit.Auditor
 }
 
 const (
 	unknownCommand = "Unknown command. Usage: `!role [give|take] [Role Name]`"
 	backendError   = "Backend error: `%q`"
+
+	/* The number of roles shown on each page of the role list */
+	rolesPerPage = 15
 )
 
 // Init is called by the multiplexer before the bot starts to initialize any
@@ -49,14 +58,22 @@ func (c Gatekeeper) Handle(ctx *multiplexer.Context) {
 
 	/* If there are no arguments (Give/Take). Provide the user with options */
 	if len(ctx.Arguments) == 0 {
-		var msg strings.Builder
-		msg.WriteString("Available roles are: ")
-
-		for _, n := range printNames {
-			msg.WriteString(fmt.Sprintf("\n- `%s`", n))
+		lines := make([]string, len(printNames))
+		for i, n := range printNames {
+			lines[i] = fmt.Sprintf("- `%s`", n)
 		}
 
-		ctx.ChannelSend(msg.String())
+		pages := reactor.NewPaginator(reactor.PageLines(
+			discordgo.MessageEmbed{Title: "Available roles"},
+			lines, rolesPerPage,
+		), 0)
+
+		msg, err := ctx.EmbedSend(pages.First())
+		if err != nil {
+			c.Logger.CmdErr(ctx, err, "There was a problem sending the roles")
+			return
+		}
+		pages.Attach(c.Reactor, ctx.Session, msg)
 		return
 	}
 
@@ -107,7 +124,11 @@ func (c Gatekeeper) Handle(ctx *multiplexer.Context) {
 			)
 			return
 		}
-		ctx.Session.GuildMemberRoleAdd(guildID, userID, roleID)
+		if err := ctx.Session.GuildMemberRoleAdd(guildID, userID, roleID); err != nil {
+			c.Logger.CmdErr(ctx, err, "There was a problem giving you the role")
+			return
+		}
+		c.Audit.SelfAssign(ctx, member, roleID, true)
 		ctx.ChannelSendf(
 			"You have been given role `%s`, %s", req, member.Mention(),
 		)
@@ -122,7 +143,11 @@ func (c Gatekeeper) Handle(ctx *multiplexer.Context) {
 		)
 		return
 	}
-	ctx.Session.GuildMemberRoleRemove(guildID, userID, roleID)
+	if err := ctx.Session.GuildMemberRoleRemove(guildID, userID, roleID); err != nil {
+		c.Logger.CmdErr(ctx, err, "There was a problem taking the role")
+		return
+	}
+	c.Audit.SelfAssign(ctx, member, roleID, false)
 	ctx.ChannelSendf(
 		"Taking role `%s` away, %s", req, member.Mention(),
 	)
@@ -139,14 +164,18 @@ func (c Gatekeeper) HandleHelp(ctx *multiplexer.Context) bool {
 			"leave, use the `!%s take [opt-in name]` command.",
 		c.Command, c.Command, c.Command,
 	)
-	return false
+	return true
 }
 
 // Settings is called by the multiplexer on startup to process any settings
 // associated with that command.
 func (c Gatekeeper) Settings() *multiplexer.CommandSettings {
 	return &multiplexer.CommandSettings{
-		Command:  c.Command,
-		HelpText: c.HelpText,
+		Command:     c.Command,
+		HelpText:    c.HelpText,
+		IgnoreEdits: true,
+		Category:    "Server",
+		Usage:       "[give|take] [role name]",
+		Examples:    []string{"", "give gaming", "take gaming"},
 	}
 }
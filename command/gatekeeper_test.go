@@ -0,0 +1,134 @@
+package command
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+	"github.com/PulseDevelopmentGroup/0x626f74/reactor"
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/bwmarrin/discordgo"
+)
+
+// newGatekeeperBot creates a bot with the role command, and requestable roles
+// with the names (which are prefixed with ":"). There's also a role which
+// can't be requested, "mod".
+func newGatekeeperBot(t *testing.T, names ...string) *testBot {
+	b := newTestBot(t, func(b *testBot) []multiplexer.Command {
+		return []multiplexer.Command{Gatekeeper{
+			Command: "role", HelpText: "Manage your roles",
+			Logger: b.logs, Reactor: b.react,
+		}}
+	})
+
+	b.fake.AddRole("g", &discordgo.Role{ID: "mod", Name: "mod"})
+	for _, name := range names {
+		b.fake.AddRole("g", &discordgo.Role{ID: name, Name: ":" + name})
+	}
+	return b
+}
+
+func TestGatekeeperGive(t *testing.T) {
+	b := newGatekeeperBot(t, "gaming", "art")
+
+	actions := b.send("!role give Gaming")
+	added := ofType(actions, session.ActionRoleAdd)
+	if len(added) != 1 || added[0].UserID != "2" || added[0].RoleID != "gaming" {
+		t.Errorf("giving the role made role changes %+v", added)
+	}
+	sent := ofType(actions, session.ActionSend)
+	if len(sent) != 1 ||
+		sent[0].Message.Content != "You have been given role `gaming`, <@!2>" {
+		t.Errorf("giving the role sent %+v", sent)
+	}
+
+	actions = b.send("!role g gaming")
+	if len(ofType(actions, session.ActionRoleAdd)) != 0 {
+		t.Error("a role the user already has was given again")
+	}
+	sent = ofType(actions, session.ActionSend)
+	if len(sent) != 1 ||
+		sent[0].Message.Content != "You appear to already have that role, <@!2>" {
+		t.Errorf("giving the role again sent %+v", sent)
+	}
+}
+
+func TestGatekeeperTake(t *testing.T) {
+	b := newGatekeeperBot(t, "gaming")
+
+	actions := b.send("!role take gaming")
+	if len(ofType(actions, session.ActionRoleRemove)) != 0 {
+		t.Error("a role the user doesn't have was taken")
+	}
+
+	b.send("!role give gaming")
+	actions = b.send("!role take gaming")
+	removed := ofType(actions, session.ActionRoleRemove)
+	if len(removed) != 1 || removed[0].UserID != "2" || removed[0].RoleID != "gaming" {
+		t.Errorf("taking the role made role changes %+v", removed)
+	}
+	sent := ofType(actions, session.ActionSend)
+	if len(sent) != 1 || sent[0].Message.Content != "Taking role `gaming` away, <@!2>" {
+		t.Errorf("taking the role sent %+v", sent)
+	}
+}
+
+func TestGatekeeperInvalid(t *testing.T) {
+	b := newGatekeeperBot(t, "gaming")
+
+	tests := []struct {
+		content string
+		reply   string
+	}{
+		{"!role give mod", "Unable to find role `mod`"},
+		{"!role give", unknownCommand},
+		{"!role steal gaming", unknownCommand},
+	}
+
+	for _, tt := range tests {
+		actions := b.send(tt.content)
+		if len(ofType(actions, session.ActionRoleAdd)) != 0 {
+			t.Errorf("%q gave a role", tt.content)
+		}
+		sent := ofType(actions, session.ActionSend)
+		if len(sent) != 1 || sent[0].Message.Content != tt.reply {
+			t.Errorf("%q sent %+v, want %q", tt.content, sent, tt.reply)
+		}
+	}
+}
+
+func TestGatekeeperList(t *testing.T) {
+	var names []string
+	for i := 0; i < rolesPerPage+1; i++ {
+		names = append(names, fmt.Sprintf("role%02d", i))
+	}
+	b := newGatekeeperBot(t, names...)
+
+	actions := b.send("!role")
+	sent := ofType(actions, session.ActionSend)
+	if len(sent) != 1 || len(sent[0].Message.Embeds) != 1 {
+		t.Fatalf("listing the roles sent %+v", sent)
+	}
+	embed := sent[0].Message.Embeds[0]
+	if embed.Title != "Available roles" {
+		t.Errorf("the role list's title is %q", embed.Title)
+	}
+
+	var reactions []string
+	for _, a := range ofType(actions, session.ActionReact) {
+		reactions = append(reactions, a.Emoji)
+	}
+	if len(reactions) != 2 ||
+		reactions[0] != reactor.PreviousPage || reactions[1] != reactor.NextPage {
+		t.Errorf("the role list was reacted to with %v", reactions)
+	}
+
+	/* The last role is on the second page */
+	edits := ofType(b.reactTo(sent[0].MessageID, reactor.NextPage), session.ActionEdit)
+	if len(edits) != 1 {
+		t.Fatalf("flipping the role list made edits %+v", edits)
+	}
+	if d := edits[0].Message.Embeds[0].Description; d != "- `role15`" {
+		t.Errorf("the second page of roles is %q", d)
+	}
+}
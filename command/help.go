@@ -1,10 +1,13 @@
 package command
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/PulseDevelopmentGroup/0x626f74/log"
 	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+	"github.com/PulseDevelopmentGroup/0x626f74/reactor"
 	"github.com/bwmarrin/discordgo"
 )
 
@@ -13,82 +16,185 @@ type Help struct {
 	Command  string
 	HelpText string
 
-	Logger *log.Logs
+	Logger  *log.Logs
+	Reactor *reactor.Reactor
+
+	mux *multiplexer.Mux
 }
 
-var (
-	helpHandlers = make(map[string]func(ctx *multiplexer.Context) bool)
-	helpFields   []*discordgo.MessageEmbedField
+const (
+	/* The number of categories shown on each page of help */
+	helpPerPage = 6
+
+	/* Commands without a category are listed last, under this heading */
+	otherCategory = "Other"
+
+	/* Discord's limit on the length of an embed field's value */
+	maxFieldLength = 1024
 )
 
 // Init is called by the multiplexer before the bot starts to initialize any
 // variables the command needs.
-func (c Help) Init(m *multiplexer.Mux) {
-	i := 0
-	for k, v := range m.Commands {
-		msg := v.Settings().HelpText
-
-		/* If there is no description, omit command from help */
-		if len(msg) == 0 {
-			continue
-		}
-
-		helpHandlers[strings.ToLower(k)] = v.HandleHelp
+func (c *Help) Init(m *multiplexer.Mux) {
+	c.mux = m
 
-		helpFields = append(helpFields, &discordgo.MessageEmbedField{
-			Name:   m.Prefix + k,
-			Value:  msg,
-			Inline: true,
-		})
-		i++
-	}
-
-	c.Logger.Command.WithField("command", c.Command).Infof(
-		"Loaded help handlers and messages for %d commands", i,
+	c.Logger.CommandLog(c.Command).Infof(
+		"Loaded help for %d commands", len(m.Commands),
 	)
 }
 
 // Handle is called by the multiplexer whenever a user triggers the command.
-func (c Help) Handle(ctx *multiplexer.Context) {
+func (c *Help) Handle(ctx *multiplexer.Context) {
 	if len(ctx.Arguments) == 0 {
-		ctx.Session.ChannelMessageSendEmbed(ctx.Message.ChannelID,
-			&discordgo.MessageEmbed{
-				Title:       ":regional_indicator_h::regional_indicator_e::regional_indicator_l::regional_indicator_p:",
-				Author:      &discordgo.MessageEmbedAuthor{},
-				Color:       0xfdd329,
-				Description: "Available commands:",
-				Fields:      helpFields,
-			})
+		pages := reactor.NewPaginator(reactor.PageFields(
+			discordgo.MessageEmbed{
+				Title:  ":regional_indicator_h::regional_indicator_e::regional_indicator_l::regional_indicator_p:",
+				Author: &discordgo.MessageEmbedAuthor{},
+				Description: fmt.Sprintf(
+					"Available commands (`%s%s [command]` for more):",
+					ctx.Prefix, c.Command,
+				),
+			},
+			c.fields(ctx), helpPerPage,
+		), 0)
+
+		msg, err := ctx.EmbedSend(pages.First())
+		if err != nil {
+			c.Logger.CmdErr(ctx, err, "There was a problem sending the help")
+			return
+		}
+		pages.Attach(c.Reactor, ctx.Session, msg)
 		return
 	}
 
-	cmd := strings.ToLower(ctx.Arguments[0])
-	handler, ok := helpHandlers[cmd]
+	cmd := strings.ToLower(strings.TrimPrefix(ctx.Arguments[0], ctx.Prefix))
+
+	if simple, ok := c.mux.GetSimple(cmd); ok {
+		ctx.EmbedSend(&discordgo.MessageEmbed{
+			Title:       ctx.Prefix + simple.Command,
+			Description: simple.HelpText,
+		})
+		return
+	}
+
+	handler, ok := c.mux.Commands[cmd]
 	if !ok {
-		ctx.ChannelSendf("Unable to find help handler for command: %s", cmd)
+		ctx.ChannelSendf("Unable to find help for command: %s", cmd)
 		return
 	}
 
-	if !handler(ctx) {
-		ctx.ChannelSend(
-			"Help handler not specified... guess you're out of luck",
-		)
+	/* Fall back to the generated usage when there's no help handler */
+	if !handler.HandleHelp(ctx) {
+		ctx.EmbedSend(usageEmbed(ctx.Prefix, handler.Settings()))
 	}
 }
 
 // HandleHelp is called by whatever help command is in place when a user enters
 // "!help [command name]". If the help command is not being handled, return
 // false.
-func (c Help) HandleHelp(ctx *multiplexer.Context) bool {
+func (c *Help) HandleHelp(ctx *multiplexer.Context) bool {
 	ctx.ChannelSend("Are you sure _you_ don't need help?")
 	return true
 }
 
 // Settings is called by the multiplexer on startup to process any settings
 // associated with that command.
-func (c Help) Settings() *multiplexer.CommandSettings {
+func (c *Help) Settings() *multiplexer.CommandSettings {
 	return &multiplexer.CommandSettings{
 		Command:  c.Command,
 		HelpText: c.HelpText,
+		Category: "Utility",
+		Usage:    "[command]",
+		Examples: []string{"", "role"},
+	}
+}
+
+// fields lists the commands the user can run in the current channel, with a
+// field for each category. Categories are sorted by name, and the commands in
+// them by name too.
+func (c *Help) fields(ctx *multiplexer.Context) []*discordgo.MessageEmbedField {
+	categories := make(map[string][]string)
+	add := func(name, category, help string, hidden bool) {
+		if hidden || len(help) == 0 || !c.mux.CanRun(ctx, name) {
+			return
+		}
+
+		if len(category) == 0 {
+			category = otherCategory
+		}
+		categories[category] = append(categories[category], fmt.Sprintf(
+			"`%s%s` %s", ctx.Prefix, name, help,
+		))
 	}
+
+	for name, cmd := range c.mux.Commands {
+		s := cmd.Settings()
+		add(name, s.Category, s.HelpText, s.Hidden)
+	}
+	for _, s := range c.mux.ListSimple() {
+		add(strings.ToLower(s.Command), s.Category, s.HelpText, s.Hidden)
+	}
+
+	names := make([]string, 0, len(categories))
+	for name := range categories {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		/* Uncategorised commands always go last */
+		if names[i] == otherCategory || names[j] == otherCategory {
+			return names[j] == otherCategory && names[i] != otherCategory
+		}
+		return names[i] < names[j]
+	})
+
+	var fields []*discordgo.MessageEmbedField
+	for _, name := range names {
+		lines := categories[name]
+		sort.Strings(lines)
+
+		/* Long categories are split over several fields */
+		var value strings.Builder
+		for _, l := range lines {
+			if value.Len() != 0 && value.Len()+len(l)+1 > maxFieldLength {
+				fields = append(fields, &discordgo.MessageEmbedField{
+					Name: name, Value: value.String(),
+				})
+				value.Reset()
+			}
+
+			if value.Len() != 0 {
+				value.WriteString("\n")
+			}
+			value.WriteString(l)
+		}
+
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name: name, Value: value.String(),
+		})
+	}
+
+	return fields
+}
+
+// usageEmbed describes how a command is used, based on its settings
+func usageEmbed(
+	prefix string, s *multiplexer.CommandSettings,
+) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:       prefix + s.Command,
+		Description: s.HelpText,
+		Fields: []*discordgo.MessageEmbedField{{
+			Name:  "Usage",
+			Value: "`" + s.UsageText(prefix) + "`",
+		}},
+	}
+
+	if examples := s.ExampleTexts(prefix); len(examples) != 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "Examples",
+			Value: "`" + strings.Join(examples, "`\n`") + "`",
+		})
+	}
+
+	return embed
 }
@@ -0,0 +1,135 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+	"github.com/PulseDevelopmentGroup/0x626f74/reactor"
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/bwmarrin/discordgo"
+)
+
+// usageCommand has no help of its own, so its usage is shown instead
+type usageCommand struct{}
+
+func (usageCommand) Init(m *multiplexer.Mux)                  {}
+func (usageCommand) Handle(ctx *multiplexer.Context)          {}
+func (usageCommand) HandleHelp(ctx *multiplexer.Context) bool { return false }
+func (usageCommand) Settings() *multiplexer.CommandSettings {
+	return &multiplexer.CommandSettings{
+		Command: "usage", HelpText: "Show usage", Category: "Utility",
+		Usage: "[thing]", Examples: []string{"thing"},
+	}
+}
+
+// newHelpBot creates a bot with the help and role commands, along with simple
+// commands in enough categories to need two pages
+func newHelpBot(t *testing.T) *testBot {
+	b := newTestBot(t, func(b *testBot) []multiplexer.Command {
+		return []multiplexer.Command{
+			&Help{Command: "help", HelpText: "Get help", Logger: b.logs, Reactor: b.react},
+			Gatekeeper{Command: "role", HelpText: "Manage your roles", Logger: b.logs},
+			usageCommand{},
+		}
+	})
+
+	b.mux.RegisterSimple(
+		multiplexer.SimpleCommand{Command: "a", Content: "a", HelpText: "A", Category: "A"},
+		multiplexer.SimpleCommand{Command: "b", Content: "b", HelpText: "B", Category: "B"},
+		multiplexer.SimpleCommand{Command: "c", Content: "c", HelpText: "C", Category: "C"},
+		multiplexer.SimpleCommand{Command: "d", Content: "d", HelpText: "D", Category: "D"},
+		multiplexer.SimpleCommand{Command: "e", Content: "e", HelpText: "E"},
+		multiplexer.SimpleCommand{
+			Command: "secret", Content: "shh", HelpText: "Secret", Category: "A",
+			Hidden: true,
+		},
+	)
+	return b
+}
+
+// fieldNames lists the names of an embed's fields
+func fieldNames(embed *discordgo.MessageEmbed) []string {
+	var names []string
+	for _, f := range embed.Fields {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func TestHelpList(t *testing.T) {
+	b := newHelpBot(t)
+
+	actions := b.send("!help")
+	sent := ofType(actions, session.ActionSend)
+	if len(sent) != 1 || len(sent[0].Message.Embeds) != 1 {
+		t.Fatalf("help sent %+v", sent)
+	}
+
+	/* Categories are sorted, and a page holds six of them */
+	first := sent[0].Message.Embeds[0]
+	if names := fieldNames(first); len(names) != helpPerPage ||
+		names[0] != "A" || names[5] != "Utility" {
+		t.Errorf("the first page of help has the categories %v", names)
+	}
+	if v := fieldValues(first)["A"]; v != "`!a` A" {
+		t.Errorf("category A lists %q, hidden commands shouldn't be listed", v)
+	}
+
+	var reactions []string
+	for _, a := range ofType(actions, session.ActionReact) {
+		reactions = append(reactions, a.Emoji)
+	}
+	if len(reactions) != 2 ||
+		reactions[0] != reactor.PreviousPage || reactions[1] != reactor.NextPage {
+		t.Errorf("help was reacted to with %v", reactions)
+	}
+
+	/* Uncategorised commands are listed last */
+	edits := ofType(b.reactTo(sent[0].MessageID, reactor.NextPage), session.ActionEdit)
+	if len(edits) != 1 {
+		t.Fatalf("flipping help made edits %+v", edits)
+	}
+	second := edits[0].Message.Embeds[0]
+	if names := fieldNames(second); len(names) != 1 || names[0] != otherCategory {
+		t.Errorf("the second page of help has the categories %v", names)
+	}
+	if v := fieldValues(second)[otherCategory]; v != "`!e` E" {
+		t.Errorf("the other category lists %q", v)
+	}
+}
+
+func TestHelpCommand(t *testing.T) {
+	b := newHelpBot(t)
+
+	/* Commands with their own help send it */
+	for _, content := range []string{"!help role", "!help !ROLE"} {
+		sent := ofType(b.send(content), session.ActionSend)
+		if len(sent) != 1 ||
+			!strings.HasPrefix(sent[0].Message.Content, "This server") {
+			t.Errorf("%q sent %+v, want the role command's help", content, sent)
+		}
+	}
+
+	sent := ofType(b.send("!help a"), session.ActionSend)
+	if len(sent) != 1 || len(sent[0].Message.Embeds) != 1 ||
+		sent[0].Message.Embeds[0].Title != "!a" ||
+		sent[0].Message.Embeds[0].Description != "A" {
+		t.Errorf("help for a simple command sent %+v", sent)
+	}
+
+	sent = ofType(b.send("!help usage"), session.ActionSend)
+	if len(sent) != 1 || len(sent[0].Message.Embeds) != 1 {
+		t.Fatalf("help for a command without any sent %+v", sent)
+	}
+	usage := fieldValues(sent[0].Message.Embeds[0])
+	if usage["Usage"] != "`!usage [thing]`" || usage["Examples"] != "`!usage thing`" {
+		t.Errorf("the usage embed's fields are %v", usage)
+	}
+
+	sent = ofType(b.send("!help nothing"), session.ActionSend)
+	if len(sent) != 1 ||
+		sent[0].Message.Content != "Unable to find help for command: nothing" {
+		t.Errorf("help for an unknown command sent %+v", sent)
+	}
+}
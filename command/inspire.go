@@ -3,6 +3,7 @@ package command
 import (
 	"io/ioutil"
 	"net/http"
+	"time"
 
 	"github.com/PulseDevelopmentGroup/0x626f74/log"
 	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
@@ -17,8 +18,9 @@ type Inspire struct {
 
 	Logger *log.Logs
 
-	RateLimitMax int
-	RateLimitDB  *cache.Cache
+	RateLimitMax    int
+	RateLimitDB     *cache.Cache
+	RateLimitWindow time.Duration
 }
 
 // Init is called by the multiplexer before the bot starts to initialize any
@@ -29,7 +31,7 @@ func (c Inspire) Init(m *multiplexer.Mux) {
 
 // Handle is called by the multiplexer whenever a user triggers the command.
 func (c Inspire) Handle(ctx *multiplexer.Context) {
-	resp, err := http.Get("http://inspirobot.me/api?generate=true")
+	resp, err := ctx.Get("http://inspirobot.me/api?generate=true")
 	if err != nil {
 		c.Logger.CmdErr(ctx, err, "There was an error contacting the InspiroBot API")
 		return
@@ -43,17 +45,15 @@ func (c Inspire) Handle(ctx *multiplexer.Context) {
 			return
 		}
 
-		_, err = ctx.Session.ChannelMessageSendEmbed(ctx.Message.ChannelID,
-			&discordgo.MessageEmbed{
-				Footer: &discordgo.MessageEmbedFooter{
-					Text: "Like: 😄 | Delete: ❌",
-				},
-				Color: 0x6dd3ff,
-				Image: &discordgo.MessageEmbedImage{
-					URL: string(body),
-				},
+		_, err = ctx.EmbedSend(&discordgo.MessageEmbed{
+			Footer: &discordgo.MessageEmbedFooter{
+				Text: "Like: 😄 | Delete: ❌",
+			},
+			Color: 0x6dd3ff,
+			Image: &discordgo.MessageEmbedImage{
+				URL: string(body),
 			},
-		)
+		})
 
 		if err != nil {
 			c.Logger.CmdErr(ctx, err, "There was an issue sending the embed")
@@ -137,9 +137,11 @@ func (c Inspire) HandleHelp(ctx *multiplexer.Context) bool {
 // associated with that command.
 func (c Inspire) Settings() *multiplexer.CommandSettings {
 	return &multiplexer.CommandSettings{
-		Command:      c.Command,
-		HelpText:     c.HelpText,
-		RateLimitMax: c.RateLimitMax,
-		RateLimitDB:  c.RateLimitDB,
+		Command:         c.Command,
+		HelpText:        c.HelpText,
+		RateLimitMax:    c.RateLimitMax,
+		RateLimitDB:     c.RateLimitDB,
+		RateLimitWindow: c.RateLimitWindow,
+		Category:        "Fun",
 	}
 }
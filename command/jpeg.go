@@ -2,16 +2,12 @@ package command
 
 import (
 	"bytes"
-	"fmt"
 	"image"
 	"image/jpeg"
-	"net/http"
 	"regexp"
 
 	"github.com/PulseDevelopmentGroup/0x626f74/log"
 	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
-	"github.com/PulseDevelopmentGroup/0x626f74/util"
-	"github.com/bwmarrin/discordgo"
 	"github.com/disintegration/imaging"
 )
 
@@ -48,7 +44,7 @@ func (c JPEG) Handle(ctx *multiplexer.Context) {
 	}
 
 	for _, url := range urls {
-		req, err := http.Get(url)
+		req, err := ctx.Get(url)
 		if err != nil {
 			c.Logger.CmdErr(ctx, err, "There was a problem getting the attachment")
 			return
@@ -74,22 +70,23 @@ func (c JPEG) Handle(ctx *multiplexer.Context) {
 			return
 		}
 
-		ctx.Session.ChannelFileSend(
-			ctx.Message.ChannelID,
-			"compressed.jpeg",
-			&buf,
-		)
+		ctx.FileSend("compressed.jpeg", &buf)
 	}
 }
 
 func (c JPEG) getURLs(ctx *multiplexer.Context) ([]string, error) {
 	var urls []string
 
-	if len(ctx.Arguments) != 0 && urlRE.MatchString(ctx.Arguments[0]) {
-		return append(urls, ctx.Arguments[0]), nil
+	arg := ""
+	if len(ctx.Arguments) != 0 {
+		arg = ctx.Arguments[0]
 	}
 
-	message, err := c.getMessage(ctx)
+	if urlRE.MatchString(arg) {
+		return append(urls, arg), nil
+	}
+
+	message, err := ctx.TargetMessage(arg)
 	if err != nil {
 		return urls, err
 	}
@@ -107,43 +104,13 @@ func (c JPEG) getURLs(ctx *multiplexer.Context) ([]string, error) {
 	return urls, nil
 }
 
-func (c JPEG) getMessage(ctx *multiplexer.Context) (*discordgo.Message, error) {
-	if len(ctx.Arguments) == 0 {
-		messages, err := ctx.Session.ChannelMessages(
-			ctx.Message.ChannelID, 1, ctx.Message.ID, "", "",
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		return messages[len(messages)-1], nil
-	}
-
-	if util.IsID(ctx.Arguments[0]) {
-		message, err := ctx.Session.ChannelMessage(
-			ctx.Message.ChannelID, ctx.Arguments[0],
-		)
-		if err != nil {
-			return nil, fmt.Errorf(
-				"unable to get message with ID '%s'", ctx.Arguments[0],
-			)
-		}
-
-		return message, nil
-	}
-
-	return nil, fmt.Errorf(
-		"'%s' doesn't look like a message ID or URL", ctx.Arguments[0],
-	)
-}
-
 // HandleHelp is called by whatever help command is in place when a user enters
 // "!help [command name]". If the help command is not being handled, return
 // false.
 func (c JPEG) HandleHelp(ctx *multiplexer.Context) bool {
 	ctx.ChannelSend(
-		"`!jpeg` to JPEGify the image that was just sent.\n" +
-			"`!jpeg [message ID]` to JPEGify a specific image in this channel.",
+		"`!jpeg` to JPEGify the image that was just sent (or the message you're replying to).\n" +
+			"`!jpeg [message ID or link]` to JPEGify a specific image.",
 	)
 	return true
 }
@@ -154,5 +121,7 @@ func (c JPEG) Settings() *multiplexer.CommandSettings {
 	return &multiplexer.CommandSettings{
 		Command:  c.Command,
 		HelpText: c.HelpText,
+		Category: "Fun",
+		Usage:    "[message ID or link]",
 	}
 }
@@ -0,0 +1,104 @@
+package command
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/bwmarrin/discordgo"
+)
+
+// newJPEGBot creates a bot with the jpeg command. Images are served from the
+// URLs, and anything else is a 404.
+func newJPEGBot(t *testing.T, images map[string][]byte) *testBot {
+	stubHTTP(t, func(r *http.Request) (*http.Response, error) {
+		if b, ok := images[r.URL.String()]; ok {
+			return respond(http.StatusOK, b), nil
+		}
+		return respond(http.StatusNotFound, nil), nil
+	})
+
+	return newTestBot(t, func(b *testBot) []multiplexer.Command {
+		return []multiplexer.Command{JPEG{
+			Command: "jpeg", HelpText: "JPEG an image", Logger: b.logs,
+		}}
+	})
+}
+
+// testPNG encodes a small image as a PNG
+func testPNG(t *testing.T) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for x := 0; x < 16; x++ {
+		img.Set(x, x, color.RGBA{R: 255, A: 255})
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// checkJPEG checks that the actions sent a single JPEG
+func checkJPEG(t *testing.T, actions []session.Action) {
+	t.Helper()
+
+	sent := ofType(actions, session.ActionSend)
+	if len(sent) != 1 || len(sent[0].Files) != 1 {
+		t.Fatalf("JPEGing sent %+v", sent)
+	}
+	file := sent[0].Files[0]
+	if file.Name != "compressed.jpeg" {
+		t.Errorf("the file is named %q", file.Name)
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(file.Data)); err != nil {
+		t.Errorf("the file isn't a JPEG: %v", err)
+	}
+}
+
+func TestJPEGLink(t *testing.T) {
+	b := newJPEGBot(t, map[string][]byte{
+		"https://example.com/cat.png": testPNG(t),
+	})
+
+	actions := b.send("!jpeg https://example.com/cat.png")
+	if len(ofType(actions, session.ActionTyping)) != 1 {
+		t.Error("the bot didn't type while JPEGing")
+	}
+	checkJPEG(t, actions)
+}
+
+func TestJPEGAttachment(t *testing.T) {
+	b := newJPEGBot(t, map[string][]byte{
+		"https://media.example.com/dog.png": testPNG(t),
+	})
+
+	b.sendMessage(&discordgo.Message{
+		Author: &discordgo.User{ID: "3", Username: "other"},
+		Attachments: []*discordgo.MessageAttachment{{
+			Filename: "dog.png", ProxyURL: "https://media.example.com/dog.png",
+		}},
+	})
+	checkJPEG(t, b.send("!jpeg"))
+}
+
+func TestJPEGNotAnImage(t *testing.T) {
+	b := newJPEGBot(t, map[string][]byte{
+		"https://example.com/fake.png": []byte("not an image"),
+	})
+
+	actions := b.send("!jpeg https://example.com/fake.png")
+	sent := ofType(actions, session.ActionSend)
+	if len(sent) != 1 || len(sent[0].Files) != 0 || !strings.Contains(
+		sent[0].Message.Content, "There was a problem decoding the image",
+	) {
+		t.Errorf("JPEGing something else sent %+v", sent)
+	}
+}
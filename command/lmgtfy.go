@@ -2,6 +2,7 @@ package command
 
 import (
 	"strings"
+	"time"
 
 	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
 	"github.com/patrickmn/go-cache"
@@ -12,8 +13,9 @@ type LMGTFY struct {
 	Command  string
 	HelpText string
 
-	RateLimitMax int
-	RateLimitDB  *cache.Cache
+	RateLimitMax    int
+	RateLimitDB     *cache.Cache
+	RateLimitWindow time.Duration
 }
 
 var query = "https://lmgtfy.com/?q=%s&iie=1"
@@ -51,9 +53,13 @@ func (c LMGTFY) HandleHelp(ctx *multiplexer.Context) bool {
 // associated with that command.
 func (c LMGTFY) Settings() *multiplexer.CommandSettings {
 	return &multiplexer.CommandSettings{
-		Command:      c.Command,
-		HelpText:     c.HelpText,
-		RateLimitDB:  c.RateLimitDB,
-		RateLimitMax: c.RateLimitMax,
+		Command:         c.Command,
+		HelpText:        c.HelpText,
+		RateLimitDB:     c.RateLimitDB,
+		RateLimitWindow: c.RateLimitWindow,
+		RateLimitMax:    c.RateLimitMax,
+		Category:        "Fun",
+		Usage:           "[question]",
+		Examples:        []string{"how do I exit vim"},
 	}
 }
@@ -0,0 +1,117 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/log"
+	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+	"github.com/sirupsen/logrus"
+)
+
+// LogLevel is a bot command which shows and changes the log level of each part
+// of the bot. It's restricted, so only those given permission can use it.
+type LogLevel struct {
+	Command  string
+	HelpText string
+
+	Logger *log.Logs
+
+	mux *multiplexer.Mux
+}
+
+// Init is called by the multiplexer before the bot starts to initialize any
+// variables the command needs.
+func (c *LogLevel) Init(m *multiplexer.Mux) {
+	c.mux = m
+}
+
+// Handle is called by the multiplexer whenever a user triggers the command.
+func (c *LogLevel) Handle(ctx *multiplexer.Context) {
+	switch len(ctx.Arguments) {
+	case 0:
+		var sb strings.Builder
+		sb.WriteString("```\n")
+		for _, name := range c.Logger.Subsystems() {
+			fmt.Fprintf(&sb, "%-24s %s\n", name, c.Logger.Level(name))
+		}
+		sb.WriteString("```")
+		ctx.ChannelSend(sb.String())
+	case 1:
+		name := strings.ToLower(ctx.Arguments[0])
+		if !c.isSubsystem(name) {
+			c.unknownSubsystem(ctx, name)
+			return
+		}
+		ctx.ChannelSendf("`%s` logs at `%s`", name, c.Logger.Level(name))
+	default:
+		name := strings.ToLower(ctx.Arguments[0])
+		if !c.isSubsystem(name) {
+			c.unknownSubsystem(ctx, name)
+			return
+		}
+
+		level, err := logrus.ParseLevel(ctx.Arguments[1])
+		if err != nil {
+			ctx.ChannelSendf(
+				"`%s` isn't a level, try trace, debug, info, warn or error",
+				ctx.Arguments[1],
+			)
+			return
+		}
+
+		c.Logger.SetLevel(name, level)
+		c.Logger.Primary.WithFields(logrus.Fields{
+			"subsystem": name,
+			"level":     level.String(),
+			"user":      ctx.Message.Author.Username,
+		}).Warn("Log level changed")
+
+		ctx.ChannelSendf("`%s` now logs at `%s`", name, level)
+	}
+}
+
+// HandleHelp is called by whatever help command is in place when a user enters
+// "!help [command name]". If the help command is not being handled, return
+// false.
+func (c *LogLevel) HandleHelp(ctx *multiplexer.Context) bool {
+	return false
+}
+
+// Settings is called by the multiplexer on startup to process any settings
+// associated with that command.
+func (c *LogLevel) Settings() *multiplexer.CommandSettings {
+	return &multiplexer.CommandSettings{
+		Command:    c.Command,
+		HelpText:   c.HelpText,
+		Category:   "Server",
+		Usage:      "[subsystem] [level]",
+		Examples:   []string{"", "mux debug", "command.wikirace warn"},
+		Hidden:     true,
+		Restricted: true,
+	}
+}
+
+// isSubsystem checks if the name is a subsystem which exists, or the subsystem
+// of a registered command (which doesn't exist until the command logs)
+func (c *LogLevel) isSubsystem(name string) bool {
+	for _, s := range c.Logger.Subsystems() {
+		if s == name {
+			return true
+		}
+	}
+
+	if cmd := strings.TrimPrefix(name, log.SubsystemCommand+"."); cmd != name {
+		_, ok := c.mux.Commands[cmd]
+		return ok
+	}
+	return false
+}
+
+// unknownSubsystem tells the user the subsystem they asked for doesn't exist
+func (c *LogLevel) unknownSubsystem(ctx *multiplexer.Context, name string) {
+	ctx.ChannelSendf(
+		"There's no `%s` subsystem, use `%s%s` to list them",
+		name, ctx.Prefix, c.Command,
+	)
+}
@@ -0,0 +1,90 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/log"
+	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+)
+
+// Privacy is a bot command which lets users opt out of having their messages
+// logged
+type Privacy struct {
+	Command  string
+	HelpText string
+
+	Logger *log.Logs
+}
+
+// Init is called by the multiplexer before the bot starts to initialize any
+// variables the command needs.
+func (c Privacy) Init(m *multiplexer.Mux) {
+	// Nothing to init
+}
+
+// Handle is called by the multiplexer whenever a user triggers the command.
+func (c Privacy) Handle(ctx *multiplexer.Context) {
+	p := c.Logger.Privacy()
+	userID := ctx.Message.Author.ID
+
+	if len(ctx.Arguments) == 0 {
+		if p.OptedOut(userID) {
+			ctx.ChannelSendf(
+				"Your messages aren't logged. Use `%s%s optin` to change that.",
+				ctx.Prefix, c.Command,
+			)
+			return
+		}
+
+		ctx.ChannelSendf(
+			"When logging is turned up, the commands you use may be logged "+
+				"(redacting `%s`). Use `%s%s optout` to stop that.",
+			p.Redaction, ctx.Prefix, c.Command,
+		)
+		return
+	}
+
+	var optOut bool
+	switch strings.ToLower(ctx.Arguments[0]) {
+	case "optout", "out":
+		optOut = true
+	case "optin", "in":
+		optOut = false
+	default:
+		ctx.ChannelSendf(
+			"Usage: `%s%s [optout|optin]`", ctx.Prefix, c.Command,
+		)
+		return
+	}
+
+	if err := p.SetOptedOut(userID, optOut); err != nil {
+		c.Logger.CmdErr(ctx, err, "Unable to save your privacy setting")
+		return
+	}
+
+	if optOut {
+		ctx.ChannelSend("Done, your messages won't be logged.")
+	} else {
+		ctx.ChannelSend("Done, your messages may be logged again.")
+	}
+}
+
+// HandleHelp is called by whatever help command is in place when a user enters
+// "!help [command name]". If the help command is not being handled, return
+// false.
+func (c Privacy) HandleHelp(ctx *multiplexer.Context) bool {
+	return false
+}
+
+// Settings is called by the multiplexer on startup to process any settings
+// associated with that command.
+func (c Privacy) Settings() *multiplexer.CommandSettings {
+	return &multiplexer.CommandSettings{
+		Command:     c.Command,
+		HelpText:    c.HelpText,
+		Category:    "Utility",
+		Usage:       "[optout|optin]",
+		Examples:    []string{"", "optout", "optin"},
+		IgnoreEdits: true,
+	}
+}
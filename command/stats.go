@@ -0,0 +1,202 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/log"
+	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+	"github.com/PulseDevelopmentGroup/0x626f74/stats"
+	"github.com/bwmarrin/discordgo"
+)
+
+// Stats is a bot command which shows how commands are being used in the guild
+type Stats struct {
+	Command  string
+	HelpText string
+
+	Logger *log.Logs
+	// Store is nil when stats aren't being recorded (ie. in console mode)
+	Store *stats.Store
+}
+
+const (
+	/* Window used when none is given */
+	defaultStatsWindow = 7 * 24 * time.Hour
+	/* How many commands and users are listed */
+	statsTop = 5
+	/* How many buckets the trend is split into */
+	statsBuckets = 7
+	/* Width of the longest bar in the trend */
+	statsBarWidth = 16
+)
+
+// Init is called by the multiplexer before the bot starts to initialize any
+// variables the command needs.
+func (c Stats) Init(m *multiplexer.Mux) {
+	// Nothing to init
+}
+
+// Handle is called by the multiplexer whenever a user triggers the command.
+func (c Stats) Handle(ctx *multiplexer.Context) {
+	if c.Store == nil {
+		ctx.ChannelSend("Stats aren't being recorded right now.")
+		return
+	}
+
+	window := defaultStatsWindow
+	command := ""
+	for _, arg := range ctx.Arguments {
+		if w, err := stats.ParseWindow(arg); err == nil {
+			window = w
+			continue
+		}
+		command = strings.ToLower(strings.TrimPrefix(arg, ctx.Prefix))
+	}
+
+	to := time.Now()
+	from := to.Add(-window)
+	sum, err := c.Store.Summarize(from, to, stats.Filter{
+		GuildID: ctx.Message.GuildID,
+		Command: command,
+	}, statsBuckets)
+	if err != nil {
+		c.Logger.CmdErr(ctx, err, "Unable to read the stats")
+		return
+	}
+
+	title := "📊 Command stats"
+	if len(command) != 0 {
+		title = fmt.Sprintf("📊 Stats for `%s%s`", ctx.Prefix, command)
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title: title,
+		Color: 0x6dd3ff,
+		Description: fmt.Sprintf(
+			"%d uses in the last %s (%d rate limited, %d failed)",
+			sum.Uses, windowText(window), sum.RateLimited, sum.Failed,
+		),
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf(
+				"%sstats [window] [command], ie. %sstats 30d wikirace",
+				ctx.Prefix, ctx.Prefix,
+			),
+		},
+	}
+
+	if sum.Uses == 0 {
+		ctx.EmbedSend(embed)
+		return
+	}
+
+	if len(command) == 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name: "Top commands",
+			Value: topList(sum.Commands, func(key string) string {
+				return "`" + ctx.Prefix + key + "`"
+			}),
+			Inline: true,
+		})
+	}
+	embed.Fields = append(embed.Fields,
+		&discordgo.MessageEmbedField{
+			Name: "Top users",
+			Value: topList(sum.Users, func(key string) string {
+				return "<@" + key + ">"
+			}),
+			Inline: true,
+		},
+		&discordgo.MessageEmbedField{
+			Name:  "Trend",
+			Value: trend(sum.Trend, window/statsBuckets),
+		},
+	)
+
+	if _, err := ctx.EmbedSend(embed); err != nil {
+		c.Logger.CmdErr(ctx, err, "There was an issue sending the stats")
+	}
+}
+
+// HandleHelp is called by whatever help command is in place when a user enters
+// "!help [command name]". If the help command is not being handled, return
+// false.
+func (c Stats) HandleHelp(ctx *multiplexer.Context) bool {
+	return false
+}
+
+// Settings is called by the multiplexer on startup to process any settings
+// associated with that command.
+func (c Stats) Settings() *multiplexer.CommandSettings {
+	return &multiplexer.CommandSettings{
+		Command:  c.Command,
+		HelpText: c.HelpText,
+		Category: "Utility",
+		Usage:    "[window] [command]",
+		Examples: []string{"", "24h", "30d wikirace"},
+	}
+}
+
+/* === Helper Functions === */
+
+// topList lists the most used keys, one per line
+func topList(counts []stats.Count, name func(key string) string) string {
+	var sb strings.Builder
+	for i, c := range counts {
+		if i == statsTop {
+			fmt.Fprintf(&sb, "...and %d more", len(counts)-statsTop)
+			break
+		}
+
+		fmt.Fprintf(&sb, "%d. %s %d", i+1, name(c.Key), c.Uses)
+		if c.RateLimited != 0 {
+			fmt.Fprintf(&sb, " (%d limited)", c.RateLimited)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// trend draws the uses in each bucket as a bar chart
+func trend(buckets []stats.Bucket, size time.Duration) string {
+	layout := "Jan 02"
+	if size < 24*time.Hour {
+		layout = "Jan 02 15:04"
+	}
+
+	max := 0
+	for _, b := range buckets {
+		if b.Uses > max {
+			max = b.Uses
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("```\n")
+	for _, b := range buckets {
+		width := 0
+		if max != 0 {
+			width = (b.Uses*statsBarWidth + max - 1) / max
+		}
+
+		fmt.Fprintf(
+			&sb, "%-12s %s %d\n",
+			b.Start.UTC().Format(layout), strings.Repeat("█", width), b.Uses,
+		)
+	}
+	sb.WriteString("```")
+	return sb.String()
+}
+
+// windowText describes a window in days, if it's a whole number of them
+func windowText(d time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case d == day:
+		return "day"
+	case d%day == 0:
+		return fmt.Sprintf("%d days", d/day)
+	}
+	return d.String()
+}
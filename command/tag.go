@@ -0,0 +1,309 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/log"
+	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+	"github.com/PulseDevelopmentGroup/0x626f74/tags"
+	"github.com/PulseDevelopmentGroup/0x626f74/util"
+	"github.com/bwmarrin/discordgo"
+	"github.com/patrickmn/go-cache"
+)
+
+// Tag is a bot command
+type Tag struct {
+	Command  string
+	HelpText string
+
+	Store  *tags.Store
+	Logger *log.Logs
+
+	RateLimitMax    int
+	RateLimitDB     *cache.Cache
+	RateLimitWindow time.Duration
+
+	mux *multiplexer.Mux
+}
+
+const tagUsage = "Usage: `!%s [add|edit|remove|info|list] [name] [content]`"
+
+// Init is called by the multiplexer before the bot starts to initialize any
+// variables the command needs.
+func (c *Tag) Init(m *multiplexer.Mux) {
+	c.mux = m
+}
+
+// Handle is called by the multiplexer whenever a user triggers the command.
+func (c *Tag) Handle(ctx *multiplexer.Context) {
+	/* Split the raw message so tag content keeps its formatting */
+	fields := strings.Fields(ctx.Message.Content)
+	if len(fields) < 2 {
+		ctx.ChannelSendf(tagUsage, c.Command)
+		return
+	}
+
+	sub := strings.ToLower(fields[1])
+	if sub == "list" || sub == "l" {
+		c.list(ctx)
+		return
+	}
+
+	if len(fields) < 3 {
+		ctx.ChannelSendf(tagUsage, c.Command)
+		return
+	}
+
+	name := strings.ToLower(fields[2])
+	content := util.TrimFields(ctx.Message.Content, 3)
+
+	switch sub {
+	case "add", "a", "create":
+		c.add(ctx, name, content)
+	case "edit", "e":
+		c.edit(ctx, name, content)
+	case "remove", "r", "rm", "delete":
+		c.remove(ctx, name)
+	case "info", "i":
+		c.info(ctx, name)
+	default:
+		ctx.ChannelSendf(tagUsage, c.Command)
+	}
+}
+
+func (c *Tag) add(ctx *multiplexer.Context, name, content string) {
+	/* Anyone can make the bot say anything with a tag, so who can create
+	them has to be configured */
+	if _, ok := c.mux.GetPermissions(c.Command + ".create"); !ok {
+		ctx.ChannelSendf(
+			"Creating tags isn't enabled, `%s.create` permissions need to be "+
+				"set in the config.", c.Command,
+		)
+		return
+	}
+	if !c.allowed(ctx, "create") {
+		ctx.ChannelSend("You do not have permissions to create tags.")
+		return
+	}
+
+	if err := tags.ValidName(name); err != nil {
+		ctx.ChannelSendf("Unable to create tag: %s", err)
+		return
+	}
+
+	/* Tags can't shadow commands, simple commands or other tags */
+	if c.mux.IsCommand(name) {
+		ctx.ChannelSendf("`%s%s` is already a command.", ctx.Prefix, name)
+		return
+	}
+
+	if err := checkTemplate(name, content); err != nil {
+		ctx.ChannelSendf("Unable to create tag: %s", err)
+		return
+	}
+
+	tag := tags.Tag{
+		Name:    name,
+		Content: content,
+		OwnerID: ctx.Message.Author.ID,
+		Owner:   ctx.Message.Author.Username,
+	}
+	if err := c.Store.Add(tag); err != nil {
+		ctx.ChannelSendf("Unable to create tag: %s", err)
+		return
+	}
+
+	c.mux.RegisterSimple(TagCommand(tag))
+	ctx.ChannelSendf("Created tag `%s%s`.", ctx.Prefix, name)
+}
+
+func (c *Tag) edit(ctx *multiplexer.Context, name, content string) {
+	tag, ok := c.Store.Get(name)
+	if !ok {
+		ctx.ChannelSendf("Unable to find tag `%s`.", name)
+		return
+	}
+
+	if !c.canManage(ctx, tag) {
+		ctx.ChannelSend("You can only edit tags you own.")
+		return
+	}
+
+	if err := checkTemplate(name, content); err != nil {
+		ctx.ChannelSendf("Unable to edit tag: %s", err)
+		return
+	}
+
+	if err := c.Store.Edit(name, content); err != nil {
+		ctx.ChannelSendf("Unable to edit tag: %s", err)
+		return
+	}
+
+	tag.Content = content
+	c.mux.RegisterSimple(TagCommand(tag))
+	ctx.ChannelSendf("Updated tag `%s%s`.", ctx.Prefix, name)
+}
+
+func (c *Tag) remove(ctx *multiplexer.Context, name string) {
+	tag, ok := c.Store.Get(name)
+	if !ok {
+		ctx.ChannelSendf("Unable to find tag `%s`.", name)
+		return
+	}
+
+	if !c.canManage(ctx, tag) {
+		ctx.ChannelSend("You can only remove tags you own.")
+		return
+	}
+
+	if err := c.Store.Remove(name); err != nil {
+		c.Logger.CmdErr(ctx, err, "There was a problem removing the tag")
+		return
+	}
+
+	c.mux.RemoveSimple(name)
+	ctx.ChannelSendf("Removed tag `%s%s`.", ctx.Prefix, name)
+}
+
+func (c *Tag) info(ctx *multiplexer.Context, name string) {
+	tag, ok := c.Store.Get(name)
+	if !ok {
+		ctx.ChannelSendf("Unable to find tag `%s`.", name)
+		return
+	}
+
+	fields := []*discordgo.MessageEmbedField{
+		{
+			Name:   "🚶 Owner",
+			Value:  fmt.Sprintf("<@%s>", tag.OwnerID),
+			Inline: true,
+		},
+		{
+			Name:   "📅 Created",
+			Value:  tag.Created.Format("Jan 2, 2006"),
+			Inline: true,
+		},
+	}
+
+	if !tag.Edited.IsZero() {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "🖊️ Edited",
+			Value:  tag.Edited.Format("Jan 2, 2006"),
+			Inline: true,
+		})
+	}
+
+	ctx.EmbedSend(&discordgo.MessageEmbed{
+		Title:  "🏷️ " + ctx.Prefix + tag.Name,
+		Fields: fields,
+	})
+}
+
+func (c *Tag) list(ctx *multiplexer.Context) {
+	all := c.Store.List()
+	if len(all) == 0 {
+		ctx.ChannelSendf(
+			"There aren't any tags yet. Create one with `%s%s add [name] [content]`",
+			ctx.Prefix, c.Command,
+		)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Available tags:")
+	for _, t := range all {
+		sb.WriteString(fmt.Sprintf(" `%s`", t.Name))
+	}
+
+	ctx.ChannelSendLong(sb.String())
+}
+
+// canManage checks if the user can edit or remove the supplied tag. Owners can
+// always manage their own tags.
+func (c *Tag) canManage(ctx *multiplexer.Context, tag tags.Tag) bool {
+	if tag.OwnerID == ctx.Message.Author.ID {
+		return true
+	}
+
+	return c.allowed(ctx, "manage")
+}
+
+// allowed checks the user against the "[command].[action]" permissions in the
+// config. No permissions specified means nobody is allowed.
+func (c *Tag) allowed(ctx *multiplexer.Context, action string) bool {
+	p, ok := c.mux.GetPermissions(c.Command + "." + action)
+	if !ok {
+		return false
+	}
+
+	member, err := ctx.Member()
+	if err != nil {
+		c.Logger.CommandLog(c.Command).WithError(err).Warn(
+			"Unable to get member for tag",
+		)
+		return false
+	}
+
+	return multiplexer.CheckPermissions(
+		p, ctx.Message.Author.ID, member.Roles, ctx.Message.ChannelID,
+	)
+}
+
+// checkTemplate makes sure template tags are valid before they're saved
+func checkTemplate(name, content string) error {
+	if !multiplexer.IsTemplate(content) {
+		return nil
+	}
+
+	_, err := multiplexer.ParseTemplate(name, content)
+	return err
+}
+
+// TagCommand converts a tag into a simple command which can be registered
+// with the multiplexer.
+func TagCommand(t tags.Tag) multiplexer.SimpleCommand {
+	return multiplexer.SimpleCommand{
+		Command:  t.Name,
+		Content:  t.Content,
+		HelpText: fmt.Sprintf("Tag created by %s", t.Owner),
+		Category: "Tags",
+		/* Tags are listed with "!tag list" rather than in help */
+		Hidden: true,
+	}
+}
+
+// HandleHelp is called by whatever help command is in place when a user enters
+// "!help [command name]". If the help command is not being handled, return
+// false.
+func (c *Tag) HandleHelp(ctx *multiplexer.Context) bool {
+	ctx.ChannelSendf(
+		"Tags are custom commands, which can be created by anyone with "+
+			"permission. Tags can use templates "+
+			"like `{{.User.Mention}}` or `{{index .Args 0}}`.\n"+
+			"`!%s add [name] [content]` to create a new tag\n"+
+			"`!%s edit [name] [content]` to change a tag you own\n"+
+			"`!%s remove [name]` to remove a tag you own\n"+
+			"`!%s info [name]` to see who made a tag and when\n"+
+			"`!%s list` to list every tag",
+		c.Command, c.Command, c.Command, c.Command, c.Command,
+	)
+	return true
+}
+
+// Settings is called by the multiplexer on startup to process any settings
+// associated with that command.
+func (c *Tag) Settings() *multiplexer.CommandSettings {
+	return &multiplexer.CommandSettings{
+		Command:         c.Command,
+		HelpText:        c.HelpText,
+		RateLimitMax:    c.RateLimitMax,
+		RateLimitDB:     c.RateLimitDB,
+		RateLimitWindow: c.RateLimitWindow,
+		IgnoreEdits:     true,
+		Category:        "Utility",
+		Usage:           "[add|edit|remove|info|list] [name] [content]",
+		Examples:        []string{"add hello Hello {{.User.Mention}}!", "info hello", "list"},
+	}
+}
@@ -0,0 +1,71 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/PulseDevelopmentGroup/0x626f74/tags"
+	"github.com/bwmarrin/discordgo"
+)
+
+// newTagBot creates a bot with the tag command, and tags kept in memory
+func newTagBot(t *testing.T) *testBot {
+	store, err := tags.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return newTestBot(t, func(b *testBot) []multiplexer.Command {
+		return []multiplexer.Command{&Tag{
+			Command: "tag", HelpText: "Manage tags", Store: store, Logger: b.logs,
+		}}
+	})
+}
+
+func TestTagCreatePermissions(t *testing.T) {
+	b := newTagBot(t)
+
+	/* Nobody can create tags until it's configured */
+	sent := ofType(b.send("!tag add hi hello"), session.ActionSend)
+	if len(sent) != 1 || sent[0].Message.Content !=
+		"Creating tags isn't enabled, `tag.create` permissions need to be set in the config." {
+		t.Errorf("creating a tag without permissions sent %+v", sent)
+	}
+
+	b.mux.SetPermissions(map[string]*multiplexer.CommandPermissions{
+		"tag.create": {UserIDs: []string{"3"}},
+	})
+	sent = ofType(b.send("!tag add hi hello"), session.ActionSend)
+	if len(sent) != 1 ||
+		sent[0].Message.Content != "You do not have permissions to create tags." {
+		t.Errorf("creating a tag without being allowed sent %+v", sent)
+	}
+
+	b.mux.SetPermissions(map[string]*multiplexer.CommandPermissions{
+		"tag.create": {UserIDs: []string{"2"}},
+	})
+	sent = ofType(b.send("!tag add hi hello"), session.ActionSend)
+	if len(sent) != 1 || sent[0].Message.Content != "Created tag `!hi`." {
+		t.Errorf("creating a tag sent %+v", sent)
+	}
+}
+
+func TestTagMentions(t *testing.T) {
+	b := newTagBot(t)
+	b.mux.SetPermissions(map[string]*multiplexer.CommandPermissions{
+		"tag.create": {UserIDs: []string{"2"}},
+	})
+	b.send(`!tag add ping {{join .Args " "}}`)
+
+	/* Tags can only ping users, whatever they say */
+	sent := ofType(b.send("!ping @everyone <@&123>"), session.ActionSend)
+	if len(sent) != 1 || sent[0].Message.Content != "@everyone <@&123>" {
+		t.Fatalf("running the tag sent %+v", sent)
+	}
+	allowed := sent[0].AllowedMentions
+	if allowed == nil || len(allowed.Parse) != 1 ||
+		allowed.Parse[0] != discordgo.AllowedMentionTypeUsers {
+		t.Errorf("the tag was allowed to mention %+v", allowed)
+	}
+}
@@ -9,9 +9,12 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/PulseDevelopmentGroup/0x626f74/log"
 	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/PulseDevelopmentGroup/0x626f74/snowflake"
 	"github.com/PulseDevelopmentGroup/0x626f74/util"
 	"github.com/bwmarrin/discordgo"
 	"github.com/patrickmn/go-cache"
@@ -27,8 +30,9 @@ type (
 
 		Logger *log.Logs
 
-		RateLimitMax int
-		RateLimitDB  *cache.Cache
+		RateLimitMax    int
+		RateLimitDB     *cache.Cache
+		RateLimitWindow time.Duration
 	}
 
 	response struct {
@@ -97,7 +101,9 @@ func (c Toxic) Handle(ctx *multiplexer.Context) {
 	for _, msg := range messages {
 		/* Convert mentions to text */
 		var err error
-		content, err = msg.ContentWithMoreMentionsReplaced(ctx.Session)
+		content, err = session.ContentWithMentionsReplaced(
+			ctx.Session, msg,
+		)
 		if err != nil {
 			content = msg.Content
 		}
@@ -161,13 +167,13 @@ func (c Toxic) Handle(ctx *multiplexer.Context) {
 		embed.Description = fmt.Sprintf("Report based on the last %d messages sent", len(ratings))
 		embed.Fields = fields
 	}
-	ctx.Session.ChannelMessageSendEmbed(ctx.Message.ChannelID, embed)
+	ctx.EmbedSend(embed)
 }
 
 func (c Toxic) getRatings(
 	message string, ctx *multiplexer.Context,
 ) (map[string]float32, error) {
-	req, err := http.NewRequest(
+	req, err := ctx.NewRequest(
 		"POST",
 		fmt.Sprintf(fmtURL, c.Key),
 		bytes.NewBuffer([]byte(fmt.Sprintf(fmtRequest, message))),
@@ -213,33 +219,21 @@ func (c Toxic) getRatings(
 func (c Toxic) getMessages(ctx *multiplexer.Context) ([]*discordgo.Message, error) {
 	ctx.Session.ChannelTyping(ctx.Message.ChannelID)
 
-	var (
-		messages []*discordgo.Message
-		message  *discordgo.Message
-	)
+	var messages []*discordgo.Message
 
-	/* No arguments? Grab the previous message */
-	if len(ctx.Arguments) == 0 {
-		latestMessages, err := ctx.Session.ChannelMessages(
-			ctx.Message.ChannelID, 1, ctx.Message.ID, "", "",
-		)
-		if err != nil {
-			return messages, err
-		}
-		message = latestMessages[len(latestMessages)-1]
-		/* Is the argument supplied a generic ID? Grab that message */
-	} else if util.IsID(ctx.Arguments[0]) {
-		var err error
-		message, err = ctx.Session.ChannelMessage(
-			ctx.Message.ChannelID, ctx.Arguments[0],
-		)
+	/* Replying to a message, or no arguments, a message ID or a link? Grab
+	that message */
+	arg := ""
+	if len(ctx.Arguments) != 0 {
+		arg = ctx.Arguments[0]
+	}
+
+	if len(arg) == 0 || ctx.IsTargeting(arg) {
+		message, err := ctx.TargetMessage(arg)
 		if err != nil {
 			return messages, err
 		}
-	}
 
-	/* If either of the two above statements were true, return with the message */
-	if len(ctx.Arguments) == 0 || util.IsID(ctx.Arguments[0]) {
 		if len(message.Content) <= 1 {
 			if len(message.Embeds) >= 1 {
 				return messages, fmt.Errorf("unable to process embeds")
@@ -250,9 +244,9 @@ func (c Toxic) getMessages(ctx *multiplexer.Context) ([]*discordgo.Message, erro
 	}
 
 	/* Is the argument supplied a username? Grab their last few messages */
-	if util.IsUser(ctx.Arguments[0]) {
+	if userID, ok := snowflake.ParseUser(ctx.Arguments[0]); ok {
 		/* Convert the funky string to a user strictly for the error checking */
-		user, err := ctx.Session.User(util.GetID(ctx.Arguments[0]))
+		user, err := ctx.Session.User(userID)
 		if err != nil {
 			return messages, err
 		}
@@ -303,7 +297,7 @@ func (c Toxic) getMessages(ctx *multiplexer.Context) ([]*discordgo.Message, erro
 	}
 
 	return messages, fmt.Errorf(
-		"the argument '%s' doesn't seem to be a message ID or username",
+		"the argument '%s' doesn't seem to be a message ID, link or username",
 		ctx.Arguments[0],
 	)
 }
@@ -327,9 +321,9 @@ func (c Toxic) fixKey(key string) string {
 // "!help [command name]".
 func (c Toxic) HandleHelp(ctx *multiplexer.Context) bool {
 	ctx.ChannelSendf(
-		"`!%s` to check the previous message's toxicity levels\n"+
+		"`!%s` to check the previous message's (or the message you're replying to) toxicity levels\n"+
 			"`!%s [username] [# messages]` to check how toxic the user in question has been\n"+
-			"`!%s [message ID]` to check how toxic a specific message was\n",
+			"`!%s [message ID or link]` to check how toxic a specific message was\n",
 		c.Command, c.Command, c.Command,
 	)
 	return true
@@ -339,9 +333,14 @@ func (c Toxic) HandleHelp(ctx *multiplexer.Context) bool {
 // associated with that command.
 func (c Toxic) Settings() *multiplexer.CommandSettings {
 	return &multiplexer.CommandSettings{
-		Command:      c.Command,
-		HelpText:     c.HelpText,
-		RateLimitDB:  c.RateLimitDB,
-		RateLimitMax: c.RateLimitMax,
+		Command:         c.Command,
+		HelpText:        c.HelpText,
+		RateLimitDB:     c.RateLimitDB,
+		RateLimitWindow: c.RateLimitWindow,
+		RateLimitMax:    c.RateLimitMax,
+		DeleteReplies:   true,
+		Category:        "Moderation",
+		Usage:           "[user or message ID/link] [# messages]",
+		Examples:        []string{"", "@someone 10"},
 	}
 }
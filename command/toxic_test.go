@@ -0,0 +1,168 @@
+package command
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/bwmarrin/discordgo"
+)
+
+/* Mentions are only parsed with real IDs */
+const otherUser = "80351110224678912"
+
+// newToxicBot creates a bot with the toxic command. The Perspective API is
+// stubbed, rating every message with the scores, and the text of each
+// message rated is returned as it's rated.
+func newToxicBot(
+	t *testing.T, key string, scores map[string]float32,
+) (*testBot, func() []string) {
+	var (
+		rated []string
+		mu    sync.Mutex
+	)
+
+	stubHTTP(t, func(r *http.Request) (*http.Response, error) {
+		if r.URL.Host != "commentanalyzer.googleapis.com" ||
+			r.URL.Query().Get("key") != key {
+			t.Errorf("unexpected request to %s", r.URL)
+			return respond(http.StatusNotFound, nil), nil
+		}
+
+		var req struct {
+			Comment struct {
+				Text string `json:"text"`
+			} `json:"comment"`
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("the request %q isn't valid: %v", body, err)
+		}
+		mu.Lock()
+		rated = append(rated, req.Comment.Text)
+		mu.Unlock()
+
+		res := response{AttributeScores: make(map[string]*attribute)}
+		for k, v := range scores {
+			a := &attribute{}
+			a.SummaryScore.Value = v
+			res.AttributeScores[k] = a
+		}
+		b, _ := json.Marshal(res)
+		return respond(http.StatusOK, b), nil
+	})
+
+	b := newTestBot(t, func(b *testBot) []multiplexer.Command {
+		return []multiplexer.Command{Toxic{
+			Command: "toxic", HelpText: "Rate toxicity", Key: key, Logger: b.logs,
+		}}
+	})
+	b.fake.AddUser(&discordgo.User{ID: otherUser, Username: "other"})
+
+	return b, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), rated...)
+	}
+}
+
+// fieldValues maps an embed's field names to their values
+func fieldValues(embed *discordgo.MessageEmbed) map[string]string {
+	values := make(map[string]string)
+	for _, f := range embed.Fields {
+		values[f.Name] = f.Value
+	}
+	return values
+}
+
+func TestToxicMessage(t *testing.T) {
+	b, rated := newToxicBot(t, "secret", map[string]float32{
+		"TOXICITY": 0.9, "IDENTITY_ATTACK": 0.05,
+	})
+
+	b.sendMessage(&discordgo.Message{
+		Content: "you are all terrible",
+		Author:  &discordgo.User{ID: otherUser, Username: "other"},
+	})
+	actions := b.send("!toxic")
+
+	if len(ofType(actions, session.ActionTyping)) != 1 {
+		t.Error("the bot didn't type while rating the message")
+	}
+	if r := rated(); len(r) != 1 || r[0] != "you are all terrible" {
+		t.Errorf("rated %q, want the previous message", r)
+	}
+
+	sent := ofType(actions, session.ActionSend)
+	if len(sent) != 1 || len(sent[0].Message.Embeds) != 1 {
+		t.Fatalf("rating the message sent %+v", sent)
+	}
+	embed := sent[0].Message.Embeds[0]
+	if embed.Title != "📝 Message Toxicity Report" ||
+		embed.Description != `"you are all terrible"` ||
+		embed.Author.Name != "other" {
+		t.Errorf("the report is %+v", embed)
+	}
+
+	values := fieldValues(embed)
+	if values["Toxicity ☣️"] != "90%" || values["Identity Attack 👺"] != "5%" {
+		t.Errorf("the report's scores are %v", values)
+	}
+}
+
+func TestToxicUser(t *testing.T) {
+	b, rated := newToxicBot(t, "secret", map[string]float32{"THREAT": 0.5})
+
+	/* Earlier messages are only added to the history */
+	for _, content := range []string{"first", "!toxic", "second"} {
+		b.fake.AddMessage(&discordgo.Message{
+			ChannelID: "c", GuildID: "g", Content: content,
+			Author: &discordgo.User{ID: otherUser, Username: "other"},
+		})
+	}
+	b.fake.AddMessage(&discordgo.Message{
+		ChannelID: "c", GuildID: "g", Content: "not theirs",
+		Author: &discordgo.User{ID: "2", Username: "user"},
+	})
+	actions := b.send("!toxic <@" + otherUser + "> 10")
+
+	/* Commands aren't rated, and the newest messages come first */
+	if r := rated(); strings.Join(r, ",") != "second,first" {
+		t.Errorf("rated %q, want the user's messages", r)
+	}
+
+	sent := ofType(actions, session.ActionSend)
+	if len(sent) != 1 || len(sent[0].Message.Embeds) != 1 {
+		t.Fatalf("rating the user sent %+v", sent)
+	}
+	embed := sent[0].Message.Embeds[0]
+	if embed.Title != "📝 User Toxicity Report" ||
+		embed.Description != "Report based on the last 2 messages sent" {
+		t.Errorf("the report is %+v", embed)
+	}
+	if v := fieldValues(embed)["Threat 🔫"]; v != "50%" {
+		t.Errorf("the average threat is %q", v)
+	}
+}
+
+func TestToxicWithoutKey(t *testing.T) {
+	b, rated := newToxicBot(t, "", nil)
+
+	b.send("something to rate")
+	actions := b.send("!toxic")
+
+	if r := rated(); len(r) != 0 {
+		t.Errorf("rated %q without a key", r)
+	}
+	sent := ofType(actions, session.ActionSend)
+	if len(sent) != 1 || !strings.Contains(
+		sent[0].Message.Content, "Perspective API key not specified",
+	) {
+		t.Errorf("rating without a key sent %+v", sent)
+	}
+}
@@ -4,8 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"net/http"
 	"strings"
+	"time"
 
 	"github.com/PulseDevelopmentGroup/0x626f74/log"
 	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
@@ -20,8 +20,9 @@ type Wiki struct {
 
 	Logger *log.Logs
 
-	RateLimitMax int
-	RateLimitDB  *cache.Cache
+	RateLimitMax    int
+	RateLimitDB     *cache.Cache
+	RateLimitWindow time.Duration
 }
 
 type (
@@ -46,7 +47,7 @@ func (c Wiki) Init(m *multiplexer.Mux) {
 
 // Handle is called by the multiplexer whenever a user triggers the command.
 func (c Wiki) Handle(ctx *multiplexer.Context) {
-	resp, err := http.Get("https://en.wikipedia.org/w/api.php?action=query&format=json&list=random&rnnamespace=0&rnlimit=2")
+	resp, err := ctx.Get("https://en.wikipedia.org/w/api.php?action=query&format=json&list=random&rnnamespace=0&rnlimit=2")
 	if err != nil {
 		c.Logger.CmdErr(ctx, err, "Unable to get random wikipedia page")
 		return
@@ -67,35 +68,34 @@ func (c Wiki) Handle(ctx *multiplexer.Context) {
 
 	articles := search.Query.Random[:2]
 
-	ctx.Session.ChannelMessageSendEmbed(ctx.Message.ChannelID,
-		&discordgo.MessageEmbed{
-			Title:       "Wikipedia Race",
-			Author:      &discordgo.MessageEmbedAuthor{},
-			Color:       0x0080ff,
-			Description: "Start at the start and use only blue links in the article to get to the end page!",
-			Fields: []*discordgo.MessageEmbedField{
-				{
-					Name: "Start:vertical_traffic_light:",
-					Value: fmt.Sprintf(
-						"[%s](%s%d)",
-						articles[0].Title,
-						"https://en.wikipedia.org/?curid=",
-						articles[0].ID,
-					),
-					Inline: false,
-				},
-				{
-					Name: "End :checkered_flag:",
-					Value: fmt.Sprintf(
-						"[%s](%s%d)",
-						articles[1].Title,
-						"https://en.wikipedia.org/?curid=",
-						articles[1].ID,
-					),
-					Inline: false,
-				},
+	ctx.EmbedSend(&discordgo.MessageEmbed{
+		Title:       "Wikipedia Race",
+		Author:      &discordgo.MessageEmbedAuthor{},
+		Color:       0x0080ff,
+		Description: "Start at the start and use only blue links in the article to get to the end page!",
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name: "Start:vertical_traffic_light:",
+				Value: fmt.Sprintf(
+					"[%s](%s%d)",
+					articles[0].Title,
+					"https://en.wikipedia.org/?curid=",
+					articles[0].ID,
+				),
+				Inline: false,
 			},
-		})
+			{
+				Name: "End :checkered_flag:",
+				Value: fmt.Sprintf(
+					"[%s](%s%d)",
+					articles[1].Title,
+					"https://en.wikipedia.org/?curid=",
+					articles[1].ID,
+				),
+				Inline: false,
+			},
+		},
+	})
 }
 
 // HandleHelp is called by whatever help command is in place when a user enters
@@ -118,9 +118,11 @@ func (c Wiki) HandleHelp(ctx *multiplexer.Context) bool {
 // associated with that command.
 func (c Wiki) Settings() *multiplexer.CommandSettings {
 	return &multiplexer.CommandSettings{
-		Command:      c.Command,
-		HelpText:     c.HelpText,
-		RateLimitMax: c.RateLimitMax,
-		RateLimitDB:  c.RateLimitDB,
+		Command:         c.Command,
+		HelpText:        c.HelpText,
+		RateLimitMax:    c.RateLimitMax,
+		RateLimitDB:     c.RateLimitDB,
+		RateLimitWindow: c.RateLimitWindow,
+		Category:        "Fun",
 	}
 }
@@ -4,7 +4,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
 	"github.com/PulseDevelopmentGroup/0x626f74/util"
@@ -13,13 +17,25 @@ import (
 )
 
 type (
-	// BotConfig defines the configuration container for the bot
+	// BotConfig defines the configuration container for the bot. Once the bot
+	// is running, it must be locked while it's read or changed, as it can be
+	// reloaded at any time.
 	BotConfig struct {
+		sync.RWMutex
+
 		Path string
 
 		ErrorChannel string
 
-		SimpleCommands map[string]string
+		// AuditChannel is where edits, deletes and membership changes are
+		// posted. Nothing is posted if it's empty.
+		AuditChannel string
+		// AuditCacheSize is how many recent messages are kept, so the content
+		// of deleted messages can be recovered
+		AuditCacheSize int
+
+		SimpleCommands map[string]multiplexer.SimpleCommand
+		AutoResponders []*multiplexer.AutoResponder
 		Permissions    map[string]*multiplexer.CommandPermissions
 	}
 
@@ -32,6 +48,10 @@ type (
 	}
 )
 
+// DefaultAuditCacheSize is how many recent messages the audit log keeps when
+// the config doesn't say
+const DefaultAuditCacheSize = 1000
+
 // Get loads the config from the json file at the path specified
 func Get(path string) (*BotConfig, error) {
 	json, err := getJSON(path)
@@ -44,16 +64,30 @@ func Get(path string) (*BotConfig, error) {
 		return &BotConfig{}, err
 	}
 
+	responders, err := getAutoResponders(json)
+	if err != nil {
+		return &BotConfig{}, err
+	}
+
 	perms := getPermissions(json)
 
+	cacheSize, err := getAuditCacheSize(json)
+	if err != nil {
+		return &BotConfig{}, err
+	}
+
 	return &BotConfig{
 		Path:           path,
 		ErrorChannel:   gjson.Get(json, "errorChannel").String(),
+		AuditChannel:   gjson.Get(json, "audit.channel").String(),
+		AuditCacheSize: cacheSize,
 		SimpleCommands: simpleCommands,
+		AutoResponders: responders,
 		Permissions:    perms,
 	}, nil
 }
 
+// Update reloads the config from its path. The config must be locked.
 func (c *BotConfig) Update() error {
 	new, err := Get(c.Path)
 	if err != nil {
@@ -61,7 +95,11 @@ func (c *BotConfig) Update() error {
 	}
 
 	c.Path = new.Path
+	c.ErrorChannel = new.ErrorChannel
+	c.AuditChannel = new.AuditChannel
+	c.AuditCacheSize = new.AuditCacheSize
 	c.SimpleCommands = new.SimpleCommands
+	c.AutoResponders = new.AutoResponders
 	c.Permissions = new.Permissions
 
 	return nil
@@ -96,25 +134,210 @@ func getJSON(path string) (string, error) {
 	return string(json), nil
 }
 
-func getSimpleCommands(json string) (map[string]string, error) {
-	out := make(map[string]string)
+// getSimpleCommands parses the simple commands from the config. Each simple
+// command is either a string, or an object describing the content, embed,
+// file and/or random responses of the command, and how it's listed in help.
+func getSimpleCommands(json string) (map[string]multiplexer.SimpleCommand, error) {
+	out := make(map[string]multiplexer.SimpleCommand)
 
-	m, ok := gjson.Parse(
-		gjson.Get(json, "simpleCommands").String(),
-	).Value().(map[string]interface{})
-	if !ok {
+	s := gjson.Get(json, "simpleCommands")
+	if !s.IsObject() {
 		return out,
 			fmt.Errorf("unable to get list of simple commands from config file")
 	}
 
-	/* TODO: Better error handling in the event of a non-string value */
-	for k, v := range m {
-		out[k] = v.(string)
+	var err error
+	s.ForEach(func(key, value gjson.Result) bool {
+		name := strings.ToLower(key.String())
+
+		if value.Type == gjson.String {
+			out[name] = multiplexer.SimpleCommand{
+				Command:  name,
+				Content:  value.String(),
+				HelpText: "This is a simple command",
+			}
+			return true
+		}
+
+		if !value.IsObject() {
+			err = fmt.Errorf(
+				"simple command %s must be either a string or an object", name,
+			)
+			return false
+		}
+
+		cmd := multiplexer.SimpleCommand{
+			Command:  name,
+			Content:  value.Get("content").String(),
+			HelpText: value.Get("help").String(),
+			File:     value.Get("file").String(),
+			Category: value.Get("category").String(),
+			Hidden:   value.Get("hidden").Bool(),
+		}
+
+		if len(cmd.HelpText) == 0 {
+			cmd.HelpText = "This is a simple command"
+		}
+
+		for _, r := range value.Get("responses").Array() {
+			cmd.Responses = append(cmd.Responses, r.String())
+		}
+
+		if e := value.Get("embed"); e.Exists() {
+			cmd.Embed, err = getEmbed(e)
+			if err != nil {
+				err = fmt.Errorf("simple command %s: %v", name, err)
+				return false
+			}
+		}
+
+		if len(cmd.Content) == 0 && len(cmd.Responses) == 0 &&
+			cmd.Embed == nil && len(cmd.File) == 0 {
+			err = fmt.Errorf("simple command %s has nothing to send", name)
+			return false
+		}
+
+		out[name] = cmd
+		return true
+	})
+
+	return out, err
+}
+
+func getEmbed(e gjson.Result) (*multiplexer.SimpleEmbed, error) {
+	embed := &multiplexer.SimpleEmbed{
+		Title:       e.Get("title").String(),
+		Description: e.Get("description").String(),
+		URL:         e.Get("url").String(),
+		Image:       e.Get("image").String(),
+		Thumbnail:   e.Get("thumbnail").String(),
+	}
+
+	/* Colors can be either a number or a "#rrggbb" string */
+	if c := e.Get("color"); c.Type == gjson.String {
+		color, err := strconv.ParseInt(strings.TrimPrefix(c.String(), "#"), 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid embed color %s", c.String())
+		}
+		embed.Color = int(color)
+	} else {
+		embed.Color = int(c.Int())
+	}
+
+	for _, f := range e.Get("fields").Array() {
+		embed.Fields = append(embed.Fields, multiplexer.SimpleEmbedField{
+			Name:   f.Get("name").String(),
+			Value:  f.Get("value").String(),
+			Inline: f.Get("inline").Bool(),
+		})
+	}
+
+	return embed, nil
+}
+
+// getAutoResponders parses the (optional) list of auto-responders from the
+// config.
+func getAutoResponders(json string) ([]*multiplexer.AutoResponder, error) {
+	var out []*multiplexer.AutoResponder
+
+	for i, value := range gjson.Get(json, "autoResponders").Array() {
+		r := &multiplexer.AutoResponder{
+			Name:     value.Get("name").String(),
+			Action:   multiplexer.ResponderAction(value.Get("action").String()),
+			Reply:    value.Get("reply").String(),
+			Reaction: value.Get("reaction").String(),
+			Chance:   100,
+		}
+
+		if len(r.Name) == 0 {
+			r.Name = fmt.Sprintf("responder %d", i)
+		}
+
+		if p := value.Get("pattern"); p.Exists() {
+			re, err := regexp.Compile(p.String())
+			if err != nil {
+				return out, fmt.Errorf("auto-responder %s: %v", r.Name, err)
+			}
+			r.Pattern = re
+		}
+
+		for _, k := range value.Get("keywords").Array() {
+			r.Keywords = append(r.Keywords, k.String())
+		}
+
+		if r.Pattern == nil && len(r.Keywords) == 0 {
+			return out, fmt.Errorf(
+				"auto-responder %s needs either a pattern or keywords", r.Name,
+			)
+		}
+
+		for _, c := range value.Get("channels").Array() {
+			r.ChanIDs = append(r.ChanIDs, c.String())
+		}
+		for _, c := range value.Get("roles").Array() {
+			r.RoleIDs = append(r.RoleIDs, c.String())
+		}
+
+		if c := value.Get("cooldown"); c.Exists() {
+			d, err := time.ParseDuration(c.String())
+			if err != nil {
+				return out, fmt.Errorf("auto-responder %s: %v", r.Name, err)
+			}
+			r.Cooldown = d
+		}
+
+		if c := value.Get("chance"); c.Exists() {
+			r.Chance = int(c.Int())
+			if r.Chance < 1 || r.Chance > 100 {
+				return out, fmt.Errorf(
+					"auto-responder %s: chance must be between 1 and 100", r.Name,
+				)
+			}
+		}
+
+		/* Default to whatever the responder has content for */
+		if len(r.Action) == 0 {
+			r.Action = multiplexer.ActionReply
+			if len(r.Reply) == 0 {
+				r.Action = multiplexer.ActionReact
+			}
+		}
+
+		switch r.Action {
+		case multiplexer.ActionReply, multiplexer.ActionReact, multiplexer.ActionBoth:
+		default:
+			return out, fmt.Errorf(
+				"auto-responder %s: unknown action %s", r.Name, r.Action,
+			)
+		}
+
+		if r.Action != multiplexer.ActionReact && len(r.Reply) == 0 {
+			return out, fmt.Errorf("auto-responder %s needs a reply", r.Name)
+		}
+		if r.Action != multiplexer.ActionReply && len(r.Reaction) == 0 {
+			return out, fmt.Errorf("auto-responder %s needs a reaction", r.Name)
+		}
+
+		out = append(out, r)
 	}
 
 	return out, nil
 }
 
+// getAuditCacheSize parses how many recent messages the audit log keeps, which
+// is DefaultAuditCacheSize unless it's set.
+func getAuditCacheSize(json string) (int, error) {
+	size := gjson.Get(json, "audit.cacheSize")
+	if !size.Exists() {
+		return DefaultAuditCacheSize, nil
+	}
+
+	if size.Type != gjson.Number || size.Int() < 0 {
+		return 0, fmt.Errorf("audit cacheSize must be a number of messages")
+	}
+	return int(size.Int()), nil
+}
+
 // TODO: Implement support for getting user ids and channel ids
 func getPermissions(json string) map[string]*multiplexer.CommandPermissions {
 	out := make(map[string]*multiplexer.CommandPermissions)
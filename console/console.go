@@ -0,0 +1,286 @@
+package console
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/bwmarrin/discordgo"
+)
+
+type (
+	// Console feeds lines of text through the multiplexer as messages from a
+	// fake user, and prints everything the bot does in response. Initialized
+	// with New().
+	Console struct {
+		Session *session.Fake
+
+		mux     *multiplexer.Mux
+		out     io.Writer
+		user    *discordgo.Member
+		guildID string
+		chanID  string
+
+		/* The last message sent by the user, and by the bot */
+		lastSent, lastReply string
+		mu                  sync.Mutex
+	}
+
+	// Options configure the fake user, and where they send messages
+	Options struct {
+		UserID, Username string
+		// Roles are given to the user, and created in the guild. Each role is
+		// either an ID, or an ID and name ("id=name").
+		Roles []string
+
+		GuildID, ChannelID, ChannelName string
+	}
+)
+
+/* IDs used when none are specified, big enough to be valid snowflakes */
+const (
+	defaultBotID     = "100000000000000001"
+	defaultUserID    = "100000000000000002"
+	defaultGuildID   = "100000000000000003"
+	defaultChannelID = "100000000000000004"
+)
+
+// Help describes the console's own commands
+const Help = `Lines are sent as messages from the fake user. Console commands:
+  /edit [content]            edit your last message
+  /delete                    delete your last message
+  /react [emoji] [msg ID]    react to a message (the bot's last, by default)
+  /unreact [emoji] [msg ID]  remove a reaction
+  /help                      show this message
+  /quit                      exit the console`
+
+// New creates a console with a fake guild containing the user, their roles and
+// a channel. Output is written to out.
+func New(
+	mux *multiplexer.Mux, opts Options, out io.Writer,
+) (*Console, error) {
+	def := func(s *string, d string) {
+		if len(*s) == 0 {
+			*s = d
+		}
+	}
+	def(&opts.UserID, defaultUserID)
+	def(&opts.Username, "user")
+	def(&opts.GuildID, defaultGuildID)
+	def(&opts.ChannelID, defaultChannelID)
+	def(&opts.ChannelName, "console")
+
+	bot := &discordgo.User{ID: defaultBotID, Username: "bot", Bot: true}
+	fake := session.NewFake(bot)
+
+	user := &discordgo.Member{
+		GuildID: opts.GuildID,
+		User:    &discordgo.User{ID: opts.UserID, Username: opts.Username},
+		Roles:   []string{},
+	}
+
+	/* The @everyone role shares the guild's ID */
+	roles := []*discordgo.Role{{
+		ID:          opts.GuildID,
+		Name:        "@everyone",
+		Permissions: discordgo.PermissionAllText,
+	}}
+	for _, r := range opts.Roles {
+		id, name := r, r
+		if i := strings.Index(r, "="); i != -1 {
+			id, name = r[:i], r[i+1:]
+		}
+
+		roles = append(roles, &discordgo.Role{ID: id, Name: name})
+		user.Roles = append(user.Roles, id)
+	}
+
+	err := fake.AddGuild(&discordgo.Guild{
+		ID:    opts.GuildID,
+		Name:  "Console",
+		Roles: roles,
+		Channels: []*discordgo.Channel{{
+			ID:   opts.ChannelID,
+			Name: opts.ChannelName,
+			Type: discordgo.ChannelTypeGuildText,
+		}},
+		Members: []*discordgo.Member{user, {User: bot}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Console{
+		Session: fake,
+		mux:     mux,
+		out:     out,
+		user:    user,
+		guildID: opts.GuildID,
+		chanID:  opts.ChannelID,
+	}
+	fake.OnAction = c.print
+	return c, nil
+}
+
+// Run reads lines from in until it's closed, or the user quits. The bot's
+// ready and guild create listeners are called first. Each line is handled
+// before the next is read, so output is never interleaved.
+func (c *Console) Run(in io.Reader) error {
+	guild, err := c.Session.Guild(c.guildID)
+	if err != nil {
+		return err
+	}
+
+	c.mux.HandleEvent(c.Session, &discordgo.Ready{
+		User: c.Session.State().User, Guilds: []*discordgo.Guild{guild},
+	})
+	c.mux.HandleEvent(c.Session, &discordgo.GuildCreate{Guild: guild})
+	c.mux.Wait()
+
+	fmt.Fprintln(c.out, Help)
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		args := fields[1:]
+		switch {
+		case !strings.HasPrefix(line, "/"):
+			c.send(line)
+		case fields[0] == "/quit", fields[0] == "/exit":
+			return nil
+		case fields[0] == "/help":
+			fmt.Fprintln(c.out, Help)
+		case fields[0] == "/edit":
+			c.edit(strings.TrimSpace(strings.TrimPrefix(line, fields[0])))
+		case fields[0] == "/delete":
+			c.delete()
+		case fields[0] == "/react", fields[0] == "/unreact":
+			if len(args) == 0 {
+				fmt.Fprintf(c.out, "Usage: %s [emoji] [message ID]\n", fields[0])
+				continue
+			}
+			c.react(fields[0] == "/react", args)
+		default:
+			fmt.Fprintf(c.out, "Unknown console command %s, try /help\n", fields[0])
+		}
+
+		c.mux.Wait()
+	}
+
+	return scanner.Err()
+}
+
+/* === Helper Functions === */
+
+// send sends a message from the user
+func (c *Console) send(content string) {
+	msg := c.Session.AddMessage(&discordgo.Message{
+		ChannelID: c.chanID,
+		GuildID:   c.guildID,
+		Author:    c.user.User,
+		Member:    c.user,
+		Content:   content,
+		Type:      discordgo.MessageTypeDefault,
+	})
+
+	c.mu.Lock()
+	c.lastSent = msg.ID
+	c.mu.Unlock()
+
+	c.mux.HandleMessage(c.Session, &discordgo.MessageCreate{Message: msg})
+}
+
+// edit edits the user's last message
+func (c *Console) edit(content string) {
+	c.mu.Lock()
+	id := c.lastSent
+	c.mu.Unlock()
+
+	msg, err := c.Session.UpdateMessage(c.chanID, id, content)
+	if err != nil {
+		fmt.Fprintln(c.out, "There's no message to edit")
+		return
+	}
+
+	c.mux.HandleEvent(c.Session, &discordgo.MessageUpdate{Message: msg})
+}
+
+// delete deletes the user's last message
+func (c *Console) delete() {
+	c.mu.Lock()
+	id := c.lastSent
+	c.lastSent = ""
+	c.mu.Unlock()
+
+	if err := c.Session.RemoveMessage(c.chanID, id); err != nil {
+		fmt.Fprintln(c.out, "There's no message to delete")
+		return
+	}
+
+	c.mux.HandleEvent(c.Session, &discordgo.MessageDelete{
+		Message: &discordgo.Message{
+			ID: id, ChannelID: c.chanID, GuildID: c.guildID,
+		},
+	})
+}
+
+// react adds or removes the user's reaction to a message
+func (c *Console) react(add bool, args []string) {
+	c.mu.Lock()
+	id := c.lastReply
+	c.mu.Unlock()
+
+	if len(args) > 1 {
+		id = args[1]
+	}
+	if len(id) == 0 {
+		fmt.Fprintln(c.out, "There's no message to react to")
+		return
+	}
+
+	reaction := &discordgo.MessageReaction{
+		UserID:    c.user.User.ID,
+		MessageID: id,
+		ChannelID: c.chanID,
+		GuildID:   c.guildID,
+		Emoji:     emoji(args[0]),
+	}
+
+	if add {
+		c.mux.HandleEvent(c.Session, &discordgo.MessageReactionAdd{
+			MessageReaction: reaction,
+		})
+		return
+	}
+	c.mux.HandleEvent(c.Session, &discordgo.MessageReactionRemove{
+		MessageReaction: reaction,
+	})
+}
+
+// print prints an action taken by the bot
+func (c *Console) print(a session.Action) {
+	if a.Type == session.ActionSend {
+		c.mu.Lock()
+		c.lastReply = a.MessageID
+		c.mu.Unlock()
+	}
+
+	fmt.Fprintln(c.out, Render(a))
+}
+
+// emoji parses an emoji as it's used in the API ("name:id" for custom emoji)
+func emoji(s string) discordgo.Emoji {
+	if i := strings.LastIndex(s, ":"); i != -1 {
+		return discordgo.Emoji{Name: s[:i], ID: s[i+1:]}
+	}
+	return discordgo.Emoji{Name: s}
+}
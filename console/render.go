@@ -0,0 +1,115 @@
+package console
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/bwmarrin/discordgo"
+)
+
+// Render describes an action taken by the bot as text. Messages are rendered
+// with their embeds and files, indented under a header line.
+func Render(a session.Action) string {
+	switch a.Type {
+	case session.ActionSend:
+		header := fmt.Sprintf("[%s] bot:", a.MessageID)
+		if ref := a.Message.MessageReference; ref != nil {
+			header = fmt.Sprintf(
+				"[%s] bot (replying to %s):", a.MessageID, ref.MessageID,
+			)
+		}
+		return header + renderMessage(a.Message)
+	case session.ActionEdit:
+		return fmt.Sprintf("[%s] bot edited:", a.MessageID) +
+			renderMessage(a.Message)
+	case session.ActionDelete:
+		return fmt.Sprintf("[%s] bot deleted the message", a.MessageID)
+	case session.ActionTyping:
+		return "bot is typing..."
+	case session.ActionReact:
+		return fmt.Sprintf("[%s] bot reacted with %s", a.MessageID, a.Emoji)
+	case session.ActionUnreact:
+		return fmt.Sprintf(
+			"[%s] bot removed %s's %s reaction", a.MessageID, a.UserID, a.Emoji,
+		)
+	case session.ActionUnreactAll:
+		return fmt.Sprintf("[%s] bot removed every reaction", a.MessageID)
+	case session.ActionRoleAdd:
+		return fmt.Sprintf("bot gave role %s to %s", a.RoleID, a.UserID)
+	case session.ActionRoleRemove:
+		return fmt.Sprintf("bot took role %s from %s", a.RoleID, a.UserID)
+	case session.ActionStatus:
+		if a.Status.Game != nil {
+			return fmt.Sprintf(
+				"bot set its status to %s (%s)", a.Status.Status, a.Status.Game.Name,
+			)
+		}
+		return "bot set its status to " + a.Status.Status
+	}
+
+	return fmt.Sprintf("bot did something unexpected: %s", a.Type)
+}
+
+// renderMessage renders a message's content, embeds and attachments on
+// indented lines.
+func renderMessage(m *discordgo.Message) string {
+	var lines []string
+	if len(m.Content) != 0 {
+		lines = append(lines, strings.Split(m.Content, "\n")...)
+	}
+
+	for _, e := range m.Embeds {
+		lines = append(lines, renderEmbed(e)...)
+	}
+
+	for _, a := range m.Attachments {
+		lines = append(lines, fmt.Sprintf("[file %s, %d bytes]", a.Filename, a.Size))
+	}
+
+	if len(lines) == 0 {
+		return " (empty)"
+	}
+	return "\n  " + strings.Join(lines, "\n  ")
+}
+
+// renderEmbed renders an embed in a box, one line at a time
+func renderEmbed(e *discordgo.MessageEmbed) []string {
+	lines := []string{"+--"}
+	add := func(prefix, text string) {
+		for _, l := range strings.Split(text, "\n") {
+			lines = append(lines, "| "+prefix+l)
+		}
+	}
+
+	if e.Author != nil && len(e.Author.Name) != 0 {
+		add("", e.Author.Name)
+	}
+	if len(e.Title) != 0 {
+		title := "# " + e.Title
+		if len(e.URL) != 0 {
+			title += " <" + e.URL + ">"
+		}
+		add("", title)
+	}
+	if len(e.Description) != 0 {
+		add("", e.Description)
+	}
+
+	for _, f := range e.Fields {
+		add("", "**"+f.Name+"**")
+		add("  ", f.Value)
+	}
+
+	if e.Image != nil {
+		add("", "[image "+e.Image.URL+"]")
+	}
+	if e.Thumbnail != nil {
+		add("", "[thumbnail "+e.Thumbnail.URL+"]")
+	}
+	if e.Footer != nil && len(e.Footer.Text) != 0 {
+		add("", "-- "+e.Footer.Text)
+	}
+
+	return append(lines, "+--")
+}
@@ -0,0 +1,79 @@
+package log
+
+import (
+	"time"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+	"github.com/PulseDevelopmentGroup/0x626f74/util"
+)
+
+// ErrorRecord is an error reported by a command, as posted to the error channel
+type ErrorRecord struct {
+	Time       time.Time `json:"time"`
+	Invocation string    `json:"invocation"`
+	Severity   string    `json:"severity"`
+	Command    string    `json:"command"`
+	Text       string    `json:"text"`
+	User       string    `json:"user"`
+	UserID     string    `json:"userID"`
+	GuildID    string    `json:"guildID"`
+	ChannelID  string    `json:"channelID"`
+	Channel    string    `json:"channel"`
+	MessageURL string    `json:"messageURL"`
+	Message    string    `json:"message"`
+	Error      string    `json:"error"`
+}
+
+// recentErrors is how many errors are kept for RecentErrors
+const recentErrors = 50
+
+// RecentErrors returns the errors most recently reported by commands, newest
+// first.
+func (l *Logs) RecentErrors() []ErrorRecord {
+	l.errMu.Lock()
+	defer l.errMu.Unlock()
+
+	out := make([]ErrorRecord, 0, len(l.errors))
+	for i := 1; i <= len(l.errors); i++ {
+		out = append(out, l.errors[(l.errNext-i+len(l.errors))%len(l.errors)])
+	}
+	return out
+}
+
+// recordError adds an error to the recent errors, replacing the oldest once
+// there are too many.
+func (l *Logs) recordError(
+	ctx *multiplexer.Context,
+	severity Severity,
+	channel, text string,
+	errMsg error,
+	msg string,
+) {
+	m := ctx.Message
+	r := ErrorRecord{
+		Time:       time.Now(),
+		Invocation: ctx.ID,
+		Severity:   severity.String(),
+		Command:    ctx.Prefix + ctx.Command,
+		Text:       text,
+		User:       m.Author.Username,
+		UserID:     m.Author.ID,
+		GuildID:    m.GuildID,
+		ChannelID:  m.ChannelID,
+		Channel:    channel,
+		MessageURL: util.GetMsgURL(m.GuildID, m.ChannelID, m.ID),
+		Message:    msg,
+		Error:      errText(errMsg),
+	}
+
+	l.errMu.Lock()
+	defer l.errMu.Unlock()
+
+	if len(l.errors) < recentErrors {
+		l.errors = append(l.errors, r)
+		l.errNext = len(l.errors) % recentErrors
+		return
+	}
+	l.errors[l.errNext] = r
+	l.errNext = (l.errNext + 1) % recentErrors
+}
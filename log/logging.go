@@ -2,8 +2,9 @@ package log
 
 import (
 	"fmt"
+	"io"
 	"os"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
@@ -12,56 +13,143 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-// Logs defines all the different loggers used within the bot
+// Logs defines all the different loggers used within the bot. Each subsystem
+// has its own logger, so their levels can be set separately.
 type Logs struct {
 	Primary     *logrus.Logger
 	Command     *logrus.Entry
 	Multiplexer *logrus.Entry
 
-	debug        bool
-	errorChannel string
+	// Reporter reports command errors to the error channel
+	Reporter *Reporter
+
+	debug   bool
+	metrics *botMetrics
+	privacy *Privacy
+	privMu  sync.RWMutex
+
+	/* Loggers for each subsystem, and the levels they've been given */
+	loggers   map[string]*logrus.Logger
+	levels    map[string]logrus.Level
+	level     logrus.Level
+	formatter logrus.Formatter
+	out       io.Writer
+	loggersMu sync.Mutex
+
+	/* Recently reported errors, oldest replaced first */
+	errors  []ErrorRecord
+	errNext int
+	errMu   sync.Mutex
 }
 
 // New creates a new Logs stuct. Accepts a boolean specifying whether
-// debug mode is enabled.
+// debug mode is enabled, which sets the default level and format.
 func New(debug bool, errorChannel string) *Logs {
-	logrus.SetOutput(os.Stdout)
-	primary := logrus.New()
+	l := &Logs{
+		debug:     debug,
+		loggers:   make(map[string]*logrus.Logger),
+		levels:    make(map[string]logrus.Level),
+		level:     logrus.InfoLevel,
+		formatter: formatter(FormatJSON),
+		out:       os.Stdout,
+
+		/* Until told otherwise, no chat is logged */
+		privacy: &Privacy{
+			Redaction:  Redaction{All: true},
+			SampleRate: 1,
+			optedOut:   make(map[string]bool),
+		},
+	}
 
 	if debug {
-		logrus.SetLevel(logrus.DebugLevel)
-		primary.SetFormatter(&logrus.TextFormatter{
-			ForceColors: true,
-		})
-	} else {
-		logrus.SetLevel(logrus.InfoLevel)
-		primary.SetFormatter(&logrus.JSONFormatter{})
-
+		l.level = logrus.DebugLevel
+		l.formatter = formatter(FormatText)
 	}
 
-	return &Logs{
-		Primary:      primary,
-		Command:      primary.WithField("type", "command"),
-		Multiplexer:  primary.WithField("type", "multiplexer"),
-		debug:        debug,
-		errorChannel: errorChannel,
-	}
+	l.Primary = l.logger(SubsystemPrimary)
+	l.Command = l.Subsystem(SubsystemCommand)
+	l.Multiplexer = l.Subsystem(SubsystemMux)
+	l.Reporter = NewReporter(errorChannel, l.Subsystem("reporter"))
+
+	return l
 }
 
 // MuxMiddleware is the middleware function attached to MuxLog. Accepts the context
-// from disgomux.
+// from disgomux. Messages are logged at debug level, following the privacy
+// settings (see UsePrivacy).
 func (l *Logs) MuxMiddleware(ctx *multiplexer.Context) {
-	if l.debug {
-		// Ignoring errors here since they're effectivly meaningless
-		ch, _ := ctx.Session.Channel(ctx.Message.ChannelID)
-		gu, _ := ctx.Session.Guild(ctx.Message.GuildID)
+	if !l.Multiplexer.Logger.IsLevelEnabled(logrus.DebugLevel) {
+		return
+	}
+
+	p := l.Privacy()
+	if !p.logs(ctx.Message.Author.ID) {
+		return
+	}
 
+	/* Names come from the state, as looking them up isn't worth a request */
+	guild, channel := "unknown", "unknown"
+	state := ctx.Session.State()
+	if ch, err := state.Channel(ctx.Message.ChannelID); err == nil {
+		channel = ch.Name
+	}
+	if gu, err := state.Guild(ctx.Message.GuildID); err == nil {
+		guild = gu.Name
+	}
+
+	/* Only the command is kept when everything is redacted */
+	content := p.Redaction.Apply(ctx.Message.Content)
+	if p.Redaction.All {
+		content = p.commandText(ctx)
+	}
+
+	fields := logrus.Fields{
+		"messageGuild":    guild,
+		"messageChannel":  channel,
+		"messageAuthorID": ctx.Message.Author.ID,
+		"messageContent":  content,
+		"invocation":      ctx.ID,
+	}
+	if p.Redaction == (Redaction{}) {
+		fields["messageAuthor"] = ctx.Message.Author.Username
+	}
+
+	l.Multiplexer.WithFields(fields).Debug("Message Recieved")
+}
+
+// EventMiddleware is the middleware function for event listeners. Accepts the
+// event context from the multiplexer.
+func (l *Logs) EventMiddleware(ctx *multiplexer.EventContext) {
+	if l.Multiplexer.Logger.IsLevelEnabled(logrus.DebugLevel) {
 		l.Multiplexer.WithFields(logrus.Fields{
-			"messageGuild":   gu.Name,
-			"messageChannel": ch.Name,
-			"messageAuthor":  ctx.Message.Author.Username,
-			"messageContent": ctx.Message.Content,
-		}).Info("Message Recieved")
+			"eventType":     ctx.Type,
+			"eventListener": ctx.Listener,
+			"eventGuild":    ctx.GuildID,
+			"eventChannel":  ctx.ChannelID,
+			"eventUser":     ctx.UserID,
+		}).Debug("Event Recieved")
+	}
+}
+
+// Panic is used as the multiplexer's panic handler. Panics within commands are
+// reported like any other command error, panics within event listeners are
+// only logged.
+func (l *Logs) Panic(
+	name string, ctx *multiplexer.Context, err interface{}, stack []byte,
+) {
+	entry := l.Multiplexer.WithFields(logrus.Fields{
+		"name":  name,
+		"stack": string(stack),
+	})
+	if ctx != nil {
+		entry = entry.WithField("invocation", ctx.ID)
+	}
+	entry.Errorf("Recovered from panic: %v", err)
+
+	if ctx != nil {
+		l.CmdReport(
+			ctx, SeverityCritical, fmt.Errorf("panic: %v", err), "The command crashed",
+		)
 	}
 }
 
@@ -69,34 +157,56 @@ func (l *Logs) MuxMiddleware(ctx *multiplexer.Context) {
 // to the user. Takes a multiplexer context, error message, and user-readable
 // message which are sent to the channel where the command was executed.
 func (l *Logs) CmdErr(ctx *multiplexer.Context, errMsg error, msg string) {
+	l.CmdReport(ctx, SeverityError, errMsg, msg)
+}
+
+// CmdWarn is like CmdErr, for errors which are expected now and then (ie. an
+// external API being unavailable).
+func (l *Logs) CmdWarn(ctx *multiplexer.Context, errMsg error, msg string) {
+	l.CmdReport(ctx, SeverityWarning, errMsg, msg)
+}
+
+// CmdReport reports an error within a command with the given severity. The
+// user is told about it, and it's reported to the error channel.
+func (l *Logs) CmdReport(
+	ctx *multiplexer.Context, severity Severity, errMsg error, msg string,
+) {
+	ctx.Fail()
+
 	// Inform the user of the issue (using a basic message string)
 	ctx.ChannelSendf("The bot seems to have encountered an issue: `%s`", msg)
 
 	// Inform the admins of the issue
 	msgTime, err := ctx.Message.Timestamp.Parse()
 	if err != nil {
-		msgTime = time.Time{}
+		msgTime = time.Now()
 	}
 
 	msgChannel := "unknown"
-	channel, err := ctx.Session.Channel(ctx.Message.ChannelID)
+	channel, err := ctx.Session.State().Channel(ctx.Message.ChannelID)
+	if err != nil {
+		channel, err = ctx.Session.Channel(ctx.Message.ChannelID)
+	}
 	if err == nil {
 		msgChannel = channel.Name
 	}
 
+	/* Reports follow the same privacy settings as the message logs */
+	text := l.Privacy().commandText(ctx)
+	l.recordError(ctx, severity, msgChannel, text, errMsg, msg)
+
 	if !l.debug {
-		ctx.Session.ChannelMessageSendEmbed(l.errorChannel, &discordgo.MessageEmbed{
-			Color: 0xff0000,
+		l.Reporter.Report(ctx.Session, Report{
+			Severity: severity,
+			Title:    fmt.Sprintf("Error with command `%s%s`", ctx.Prefix, ctx.Command),
+			URL: util.GetMsgURL(
+				ctx.Message.GuildID, ctx.Message.ChannelID, ctx.Message.ID,
+			),
 			Author: &discordgo.MessageEmbedAuthor{
 				IconURL: ctx.Message.Author.AvatarURL(""),
 				Name:    ctx.Message.Author.Username,
 			},
-			Title: fmt.Sprintf("🚧 Error with command `%s%s`", ctx.Prefix, ctx.Command),
-			URL: util.GetMsgURL(
-				ctx.Message.GuildID, ctx.Message.ChannelID, ctx.Message.ID,
-			),
-
-			Timestamp: msgTime.Format("2006-01-02T15:04:05.000Z"),
+			Time: msgTime,
 			Fields: []*discordgo.MessageEmbedField{
 				{
 					Name:   "🚶 User",
@@ -119,16 +229,25 @@ func (l *Logs) CmdErr(ctx *multiplexer.Context, errMsg error, msg string) {
 				},
 				{
 					Name:  "⚠️ Error Message",
-					Value: errMsg.Error(),
+					Value: errText(errMsg),
 				},
 				{
-					Name: "🖊️ Command Text",
-					Value: ctx.Prefix + ctx.Command +
-						" " + strings.Join(ctx.Arguments[:], " "),
+					Name:  "🖊️ Command Text",
+					Value: text,
 				},
 			},
+			Err: errMsg,
+			ID:  ctx.ID,
 		})
 	}
 
-	l.Command.Error(errMsg)
+	entry := l.CommandLog(ctx.Command).WithFields(logrus.Fields{
+		"severity":   severity.String(),
+		"invocation": ctx.ID,
+	})
+	if severity == SeverityWarning {
+		entry.Warn(errText(errMsg))
+	} else {
+		entry.Error(errText(errMsg))
+	}
 }
@@ -0,0 +1,109 @@
+package log
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/metrics"
+	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+	"github.com/PulseDevelopmentGroup/0x626f74/trace"
+	"github.com/sirupsen/logrus"
+)
+
+// botMetrics are the metrics updated as commands are handled, and requests fail
+type botMetrics struct {
+	commands    *metrics.Counter
+	latency     *metrics.Histogram
+	rateLimited *metrics.Counter
+	denied      *metrics.Counter
+	httpErrors  *metrics.Counter
+}
+
+// UseMetrics registers the bot's metrics, which are then updated by Observe and
+// HTTPError.
+func (l *Logs) UseMetrics(r *metrics.Registry) {
+	l.metrics = &botMetrics{
+		commands: r.Counter(
+			"bot_commands_total", "Commands handled, by command and result",
+			"command", "result",
+		),
+		latency: r.Histogram(
+			"bot_command_duration_seconds",
+			"Time taken to handle commands which were run", nil, "command",
+		),
+		rateLimited: r.Counter(
+			"bot_rate_limited_total", "Commands rejected by rate limits", "command",
+		),
+		denied: r.Counter(
+			"bot_permission_denied_total",
+			"Commands rejected because the user lacked permission", "command",
+		),
+		httpErrors: r.Counter(
+			"bot_http_errors_total",
+			"Outbound HTTP requests which failed, by host and status",
+			"host", "status",
+		),
+	}
+}
+
+// Observe is used as the multiplexer's observer. The outcome of each command is
+// logged at debug level, and counted if metrics are in use.
+func (l *Logs) Observe(
+	ctx *multiplexer.Context, outcome multiplexer.Outcome, d time.Duration,
+) {
+	/* Commands which weren't found could be anything, so aren't named */
+	command := ctx.Command
+	if outcome == multiplexer.OutcomeNotFound {
+		command = ""
+	}
+
+	l.Multiplexer.WithFields(logrus.Fields{
+		"command":    command,
+		"outcome":    outcome,
+		"duration":   d,
+		"invocation": ctx.ID,
+	}).Debug("Command handled")
+
+	m := l.metrics
+	if m == nil {
+		return
+	}
+
+	m.commands.Inc(command, string(outcome))
+	switch outcome {
+	case multiplexer.OutcomeRateLimited:
+		m.rateLimited.Inc(command)
+	case multiplexer.OutcomeDenied:
+		m.denied.Inc(command)
+	case multiplexer.OutcomeOK, multiplexer.OutcomeError,
+		multiplexer.OutcomePanic:
+		m.latency.Observe(d.Seconds(), command)
+	}
+}
+
+// HTTPError is used as the error handler of the bot's HTTP transport. Failed
+// requests are logged at debug level, and counted if metrics are in use.
+func (l *Logs) HTTPError(req *http.Request, resp *http.Response, err error) {
+	status := "error"
+	if resp != nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+
+	entry := l.Subsystem("http").WithFields(logrus.Fields{
+		"host":   req.URL.Host,
+		"path":   req.URL.Path,
+		"status": status,
+	})
+	if span := trace.FromContext(req.Context()); span != nil {
+		entry = entry.WithField("invocation", span.TraceID())
+	}
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+	entry.Debug("Outbound request failed")
+
+	if l.metrics != nil {
+		l.metrics.httpErrors.Inc(req.URL.Host, status)
+	}
+}
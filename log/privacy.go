@@ -0,0 +1,245 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+)
+
+type (
+	// Redaction is what's removed from message content before it's logged
+	Redaction struct {
+		// Mentions replaces user, role and channel mentions
+		Mentions bool
+		// URLs replaces links
+		URLs bool
+		// All leaves out everything but the command
+		All bool
+	}
+
+	// Privacy decides which messages are logged, and how much of them. Users
+	// who opt out are saved as JSON to the path it was opened with.
+	// Initialized with OpenPrivacy().
+	Privacy struct {
+		Redaction Redaction
+		// SampleRate is the fraction of messages logged, between 0 and 1
+		SampleRate float64
+
+		path     string
+		optedOut map[string]bool
+		mu       sync.RWMutex
+	}
+)
+
+var (
+	mentionRE = regexp.MustCompile(`<(@[!&]?|#)\d+>|@(everyone|here)`)
+	urlRE     = regexp.MustCompile(`(?i)\b(https?://|www\.)\S+`)
+)
+
+// ParseRedaction parses redaction rules, as used by LOG_REDACT. Rules are
+// separated by commas, and are "mentions", "urls", "all" or "none".
+func ParseRedaction(s string) (Redaction, error) {
+	var r Redaction
+	for _, rule := range strings.Split(s, ",") {
+		switch strings.ToLower(strings.TrimSpace(rule)) {
+		case "mentions":
+			r.Mentions = true
+		case "urls":
+			r.URLs = true
+		case "all":
+			r.All = true
+		case "none", "":
+		default:
+			return Redaction{}, fmt.Errorf(
+				"%q isn't a redaction rule, use mentions, urls, all or none",
+				rule,
+			)
+		}
+	}
+	return r, nil
+}
+
+// String describes the rules, in the format ParseRedaction accepts
+func (r Redaction) String() string {
+	var rules []string
+	if r.All {
+		rules = append(rules, "all")
+	}
+	if r.Mentions {
+		rules = append(rules, "mentions")
+	}
+	if r.URLs {
+		rules = append(rules, "urls")
+	}
+	if len(rules) == 0 {
+		return "none"
+	}
+	return strings.Join(rules, ",")
+}
+
+// Apply redacts message content. Only the length is kept if everything is
+// being redacted.
+func (r Redaction) Apply(content string) string {
+	if r.All {
+		return fmt.Sprintf("[redacted %d characters]", len(content))
+	}
+	if r.Mentions {
+		content = mentionRE.ReplaceAllString(content, "[mention]")
+	}
+	if r.URLs {
+		content = urlRE.ReplaceAllString(content, "[url]")
+	}
+	return content
+}
+
+// OpenPrivacy loads the users who've opted out from the path. If no file
+// exists at the path, nobody has opted out and the file is created when
+// someone does. If the path is empty, opt-outs are only kept in memory.
+func OpenPrivacy(path string, redaction Redaction, sampleRate float64) (
+	*Privacy, error,
+) {
+	p := &Privacy{
+		Redaction:  redaction,
+		SampleRate: sampleRate,
+		path:       path,
+		optedOut:   make(map[string]bool),
+	}
+
+	if len(path) == 0 {
+		return p, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return p, nil
+	}
+
+	var ids []string
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return nil, fmt.Errorf("unable to parse privacy file %s: %v", path, err)
+	}
+	for _, id := range ids {
+		p.optedOut[id] = true
+	}
+
+	return p, nil
+}
+
+// OptedOut checks if the user has opted out of having their messages logged
+func (p *Privacy) OptedOut(userID string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.optedOut[userID]
+}
+
+// SetOptedOut opts the user out of (or back into) having their messages
+// logged, and saves the change.
+func (p *Privacy) SetOptedOut(userID string, optedOut bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.optedOut[userID] == optedOut {
+		return nil
+	}
+
+	if optedOut {
+		p.optedOut[userID] = true
+	} else {
+		delete(p.optedOut, userID)
+	}
+
+	if err := p.save(); err != nil {
+		/* Undo the change, so it matches what's saved */
+		if optedOut {
+			delete(p.optedOut, userID)
+		} else {
+			p.optedOut[userID] = true
+		}
+		return err
+	}
+	return nil
+}
+
+// UsePrivacy sets which messages are logged, and how much of them. By default
+// everything but the command is redacted.
+func (l *Logs) UsePrivacy(p *Privacy) {
+	l.privMu.Lock()
+	defer l.privMu.Unlock()
+
+	l.privacy = p
+}
+
+// Privacy returns the privacy settings messages are logged with
+func (l *Logs) Privacy() *Privacy {
+	l.privMu.RLock()
+	defer l.privMu.RUnlock()
+
+	return l.privacy
+}
+
+/* === Helper Functions === */
+
+// logs checks if a message from the user should be logged
+func (p *Privacy) logs(userID string) bool {
+	if p.OptedOut(userID) {
+		return false
+	}
+	return p.SampleRate >= 1 || rand.Float64() < p.SampleRate
+}
+
+// commandText is the command's text as it may be logged. The arguments are
+// redacted, and left out completely for users who've opted out.
+func (p *Privacy) commandText(ctx *multiplexer.Context) string {
+	text := ctx.Prefix + ctx.Command
+	if len(ctx.Arguments) == 0 {
+		return text
+	}
+
+	if p.Redaction.All || p.OptedOut(ctx.Message.Author.ID) {
+		return text + fmt.Sprintf(" [%d arguments redacted]", len(ctx.Arguments))
+	}
+	return text + " " + p.Redaction.Apply(strings.Join(ctx.Arguments, " "))
+}
+
+// save writes the users who've opted out to disk. Must be called with the lock
+// held.
+func (p *Privacy) save() error {
+	if len(p.path) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(p.optedOut))
+	for id := range p.optedOut {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	data, err := json.MarshalIndent(ids, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	/* Write to a temporary file first so a failed write can't lose opt-outs */
+	tmp := p.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, p.path)
+}
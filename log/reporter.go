@@ -0,0 +1,333 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/PulseDevelopmentGroup/0x626f74/util"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// Severity is how serious a reported error is
+type Severity int
+
+const (
+	// SeverityWarning is for errors which are expected now and then, ie. an
+	// external API being unavailable
+	SeverityWarning Severity = iota
+	// SeverityError is for errors which shouldn't happen
+	SeverityError
+	// SeverityCritical is for errors which mean something is broken, ie. a
+	// command panicking
+	SeverityCritical
+)
+
+type (
+	// Reporter reports errors to the error channel. Identical errors within
+	// the window are grouped, so they're only posted once. When the channel
+	// can't be reached errors are sent to the webhook, or failing that written
+	// to the file. Initialized with NewReporter().
+	Reporter struct {
+		// WebhookURL is a Discord webhook errors are sent to when the error
+		// channel can't be reached, if set
+		WebhookURL string
+		// File is where errors are written (as JSON lines) when neither the
+		// error channel or webhook can be reached, if set
+		File string
+		// Window is how long identical errors are grouped for. Errors aren't
+		// grouped if it's 0.
+		Window time.Duration
+
+		channel string
+		logger  *logrus.Entry
+		client  *http.Client
+		groups  map[string]*errorGroup
+		mu      sync.Mutex
+	}
+
+	// Report is an error to be reported
+	Report struct {
+		Severity Severity
+		// Title describes where the error happened, ie. the command
+		Title  string
+		URL    string
+		Author *discordgo.MessageEmbedAuthor
+		Time   time.Time
+		Fields []*discordgo.MessageEmbedField
+		Err    error
+		// ID is the invocation the error happened in, if there was one
+		ID string
+	}
+
+	// errorGroup is an error which has been reported, and any identical errors
+	// reported after it within the window
+	errorGroup struct {
+		report  Report
+		session session.Session
+		count   int
+		last    time.Time
+
+		/* Where the error was posted, if it was posted to the channel */
+		channelID string
+		messageID string
+	}
+
+	// webhookPayload is the body of a Discord webhook request
+	webhookPayload struct {
+		Username string                    `json:"username,omitempty"`
+		Embeds   []*discordgo.MessageEmbed `json:"embeds"`
+	}
+
+	// fileEntry is a line of the error file
+	fileEntry struct {
+		Time     time.Time               `json:"time"`
+		Severity string                  `json:"severity"`
+		Embed    *discordgo.MessageEmbed `json:"embed"`
+	}
+)
+
+/* How long to wait for the webhook */
+const webhookTimeout = 10 * time.Second
+
+// DefaultWindow is how long identical errors are grouped for by default
+const DefaultWindow = time.Minute
+
+// NewReporter creates a reporter which posts errors to the channel
+func NewReporter(channel string, logger *logrus.Entry) *Reporter {
+	return &Reporter{
+		Window:  DefaultWindow,
+		channel: channel,
+		logger:  logger,
+		client:  &http.Client{Timeout: webhookTimeout},
+		groups:  make(map[string]*errorGroup),
+	}
+}
+
+// String returns the name of the severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "critical"
+	}
+	return "error"
+}
+
+// SetChannel changes the channel errors are posted to
+func (r *Reporter) SetChannel(channel string) {
+	r.mu.Lock()
+	r.channel = channel
+	r.mu.Unlock()
+}
+
+// Report reports an error. If an identical error has been reported within the
+// window it's only counted, and the original report is updated with how many
+// times it happened once the window ends.
+func (r *Reporter) Report(s session.Session, report Report) {
+	if report.Time.IsZero() {
+		report.Time = time.Now()
+	}
+
+	key := fmt.Sprintf(
+		"%d\x00%s\x00%s", report.Severity, report.Title, errText(report.Err),
+	)
+
+	r.mu.Lock()
+	if g, ok := r.groups[key]; ok {
+		g.count++
+		g.last = report.Time
+		r.mu.Unlock()
+		return
+	}
+
+	g := &errorGroup{
+		report:  report,
+		session: s,
+		count:   1,
+		last:    report.Time,
+	}
+	if r.Window > 0 {
+		r.groups[key] = g
+		time.AfterFunc(r.Window, func() { r.flush(key) })
+	}
+	embed := g.embed()
+	r.mu.Unlock()
+
+	channelID, messageID := r.deliver(s, report.Severity, embed)
+
+	r.mu.Lock()
+	g.channelID, g.messageID = channelID, messageID
+	r.mu.Unlock()
+}
+
+/* === Helper Functions === */
+
+// flush ends a group, updating the original report if there were duplicates
+func (r *Reporter) flush(key string) {
+	r.mu.Lock()
+	g, ok := r.groups[key]
+	delete(r.groups, key)
+	if !ok || g.count == 1 {
+		r.mu.Unlock()
+		return
+	}
+	embed := g.embed()
+	channelID, messageID := g.channelID, g.messageID
+	r.mu.Unlock()
+
+	if len(messageID) != 0 {
+		_, err := g.session.ChannelMessageEditEmbed(channelID, messageID, embed)
+		if err == nil {
+			return
+		}
+	}
+	r.deliver(g.session, g.report.Severity, embed)
+}
+
+// deliver sends an embed to the error channel, falling back to the webhook and
+// then the file. Returns where the embed was posted, if it was posted to the
+// channel.
+func (r *Reporter) deliver(
+	s session.Session, severity Severity, embed *discordgo.MessageEmbed,
+) (string, string) {
+	r.mu.Lock()
+	channel := r.channel
+	r.mu.Unlock()
+
+	if len(channel) != 0 && s != nil {
+		msg, err := s.ChannelMessageSendEmbed(channel, embed)
+		if err == nil {
+			return msg.ChannelID, msg.ID
+		}
+		r.logger.WithError(err).Warn("Unable to post error to the error channel")
+	}
+
+	if len(r.WebhookURL) != 0 {
+		err := r.sendWebhook(embed)
+		if err == nil {
+			return "", ""
+		}
+		r.logger.WithError(err).Warn("Unable to send error to the webhook")
+	}
+
+	if len(r.File) != 0 {
+		if err := r.writeFile(severity, embed); err != nil {
+			r.logger.WithError(err).Warn("Unable to write error to the file")
+		}
+	}
+	return "", ""
+}
+
+// sendWebhook sends an embed to the webhook
+func (r *Reporter) sendWebhook(embed *discordgo.MessageEmbed) error {
+	body, err := json.Marshal(webhookPayload{
+		Embeds: []*discordgo.MessageEmbed{embed},
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.client.Post(r.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// writeFile appends an embed to the file
+func (r *Reporter) writeFile(
+	severity Severity, embed *discordgo.MessageEmbed,
+) error {
+	line, err := json.Marshal(fileEntry{
+		Time:     time.Now(),
+		Severity: severity.String(),
+		Embed:    embed,
+	})
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, err := os.OpenFile(r.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// embed builds the embed for the group, which Discord will accept
+func (g *errorGroup) embed() *discordgo.MessageEmbed {
+	rep := g.report
+
+	color, icon := 0xff0000, "🚧"
+	switch rep.Severity {
+	case SeverityWarning:
+		color, icon = 0xffa500, "⚠️"
+	case SeverityCritical:
+		color, icon = 0x8b0000, "🔥"
+	}
+
+	title := icon + " " + rep.Title
+	if g.count > 1 {
+		title += fmt.Sprintf(" ×%d", g.count)
+	}
+
+	fields := make([]*discordgo.MessageEmbedField, 0, len(rep.Fields)+1)
+	for _, f := range rep.Fields {
+		copied := *f
+		fields = append(fields, &copied)
+	}
+	if g.count > 1 {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name: "🔁 Occurrences",
+			Value: fmt.Sprintf(
+				"%d times, last at %s", g.count,
+				g.last.UTC().Format("2006-01-02 15:04:05 MST"),
+			),
+		})
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Color:     color,
+		Author:    rep.Author,
+		Title:     title,
+		URL:       rep.URL,
+		Timestamp: rep.Time.UTC().Format("2006-01-02T15:04:05.000Z"),
+		Fields:    fields,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: "Severity: " + rep.Severity.String(),
+		},
+	}
+	if len(rep.ID) != 0 {
+		embed.Footer.Text += " • Invocation " + rep.ID
+	}
+	util.LimitEmbed(embed)
+	return embed
+}
+
+// errText describes an error, which might be nil
+func errText(err error) string {
+	if err == nil {
+		return "unknown error"
+	}
+	return err.Error()
+}
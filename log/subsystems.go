@@ -0,0 +1,162 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Format is how logs are written
+type Format string
+
+const (
+	// FormatJSON writes each entry as a JSON object
+	FormatJSON Format = "json"
+	// FormatText writes entries for people to read, with colors
+	FormatText Format = "text"
+	// FormatLogfmt writes entries as key=value pairs
+	FormatLogfmt Format = "logfmt"
+)
+
+/* Subsystems which always have a logger */
+const (
+	// SubsystemPrimary is the primary logger, for the bot itself
+	SubsystemPrimary = "primary"
+	// SubsystemMux is the multiplexer's logger
+	SubsystemMux = "mux"
+	// SubsystemCommand is the parent of every command's logger. Setting its
+	// level sets the level of commands which haven't had theirs set.
+	SubsystemCommand = "command"
+)
+
+// ParseFormat parses a log format, as used by LOG_FORMAT
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatJSON, FormatText, FormatLogfmt:
+		return f, nil
+	}
+	return "", fmt.Errorf("%q isn't a log format, use json, text or logfmt", s)
+}
+
+// Subsystem returns the logger for a part of the bot (ie. "reactor"), creating
+// it if it doesn't exist. Its level can be changed with SetLevel.
+func (l *Logs) Subsystem(name string) *logrus.Entry {
+	name = strings.ToLower(name)
+
+	l.loggersMu.Lock()
+	defer l.loggersMu.Unlock()
+
+	return logrus.NewEntry(l.logger(name)).WithField("type", name)
+}
+
+// CommandLog returns the logger for a command. Its subsystem is
+// "command.<name>".
+func (l *Logs) CommandLog(command string) *logrus.Entry {
+	command = strings.ToLower(command)
+
+	l.loggersMu.Lock()
+	defer l.loggersMu.Unlock()
+
+	return logrus.NewEntry(
+		l.logger(SubsystemCommand + "." + command),
+	).WithFields(logrus.Fields{
+		"type":    SubsystemCommand,
+		"command": command,
+	})
+}
+
+// SetLevel sets the level of a subsystem, and of any subsystems below it (ie.
+// "command" and "command.tag") which haven't had their own level set.
+func (l *Logs) SetLevel(subsystem string, level logrus.Level) {
+	subsystem = strings.ToLower(subsystem)
+
+	l.loggersMu.Lock()
+	defer l.loggersMu.Unlock()
+
+	l.levels[subsystem] = level
+	l.logger(subsystem)
+
+	for name, logger := range l.loggers {
+		logger.SetLevel(l.levelOf(name))
+	}
+}
+
+// Level returns the level of a subsystem
+func (l *Logs) Level(subsystem string) logrus.Level {
+	l.loggersMu.Lock()
+	defer l.loggersMu.Unlock()
+
+	return l.levelOf(strings.ToLower(subsystem))
+}
+
+// Subsystems returns the name of every subsystem which has logged (or been
+// given a level), sorted.
+func (l *Logs) Subsystems() []string {
+	l.loggersMu.Lock()
+	defer l.loggersMu.Unlock()
+
+	names := make([]string, 0, len(l.loggers))
+	for name := range l.loggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetFormat changes how every subsystem's logs are written
+func (l *Logs) SetFormat(format Format) {
+	l.loggersMu.Lock()
+	defer l.loggersMu.Unlock()
+
+	l.formatter = formatter(format)
+	for _, logger := range l.loggers {
+		logger.SetFormatter(l.formatter)
+	}
+}
+
+/* === Helper Functions === */
+
+// logger returns the subsystem's logger, creating it if it doesn't exist.
+// loggersMu must be held.
+func (l *Logs) logger(name string) *logrus.Logger {
+	if logger, ok := l.loggers[name]; ok {
+		return logger
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(l.out)
+	logger.SetFormatter(l.formatter)
+	logger.SetLevel(l.levelOf(name))
+
+	l.loggers[name] = logger
+	return logger
+}
+
+// levelOf finds the level of a subsystem, from its own level or the closest
+// subsystem above it with one. loggersMu must be held.
+func (l *Logs) levelOf(name string) logrus.Level {
+	for {
+		if level, ok := l.levels[name]; ok {
+			return level
+		}
+
+		i := strings.LastIndex(name, ".")
+		if i == -1 {
+			return l.level
+		}
+		name = name[:i]
+	}
+}
+
+// formatter creates the formatter for a log format
+func formatter(format Format) logrus.Formatter {
+	switch format {
+	case FormatText:
+		return &logrus.TextFormatter{ForceColors: true, FullTimestamp: true}
+	case FormatLogfmt:
+		return &logrus.TextFormatter{DisableColors: true, FullTimestamp: true}
+	}
+	return &logrus.JSONFormatter{}
+}
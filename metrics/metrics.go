@@ -0,0 +1,306 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type (
+	// Registry holds metrics, and writes them in the Prometheus text format.
+	// Initialized with NewRegistry().
+	Registry struct {
+		metrics []metric
+		mu      sync.Mutex
+	}
+
+	// Counter is a value which only goes up, with a series for each
+	// combination of label values
+	Counter struct {
+		desc
+		values map[string]float64
+		mu     sync.Mutex
+	}
+
+	// Histogram counts observations (ie. durations in seconds) into buckets,
+	// with a series for each combination of label values
+	Histogram struct {
+		desc
+		buckets []float64
+		series  map[string]*histogramSeries
+		mu      sync.Mutex
+	}
+
+	// desc is the name, help text and label names shared by every metric
+	desc struct {
+		name, help, kind string
+		labels           []string
+	}
+
+	// metric is anything which can be written by the registry
+	metric interface {
+		write(w io.Writer) error
+	}
+
+	// gaugeFunc is a gauge whose value is read when it's written
+	gaugeFunc struct {
+		desc
+		fn func() float64
+	}
+
+	histogramSeries struct {
+		counts []uint64 // Per bucket, not cumulative
+		count  uint64
+		sum    float64
+	}
+)
+
+// DefaultBuckets are the upper bounds of histogram buckets for durations in
+// seconds, from 5ms to 30s.
+var DefaultBuckets = []float64{
+	.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30,
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Counter creates a counter, and registers it. Values for the labels are
+// supplied in the same order when it's incremented.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	c := &Counter{
+		desc:   desc{name: name, help: help, kind: "counter", labels: labels},
+		values: make(map[string]float64),
+	}
+	r.add(c)
+	return c
+}
+
+// Histogram creates a histogram with the supplied buckets (DefaultBuckets if
+// nil), and registers it.
+func (r *Registry) Histogram(
+	name, help string, buckets []float64, labels ...string,
+) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	h := &Histogram{
+		desc:    desc{name: name, help: help, kind: "histogram", labels: labels},
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+	}
+	r.add(h)
+	return h
+}
+
+// GaugeFunc registers a gauge whose value is read from the function whenever
+// metrics are written, such as the number of items in a pool.
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	r.add(&gaugeFunc{desc: desc{name: name, help: help, kind: "gauge"}, fn: fn})
+}
+
+// Write writes every metric in the Prometheus text format
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	metrics := make([]metric, len(r.metrics))
+	copy(metrics, r.metrics)
+	r.mu.Unlock()
+
+	for _, m := range metrics {
+		if err := m.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServeHTTP serves the metrics, for Prometheus to scrape
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.Write(w)
+}
+
+// Inc adds one to the series with the supplied label values
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds to the series with the supplied label values. Negative values are
+// ignored, since counters can't go down.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+
+	key := c.labelString(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.values[key] += v
+}
+
+// Observe adds an observation to the series with the supplied label values
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	key := h.labelString(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+
+	s.count++
+	s.sum += v
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+}
+
+/* === Helper Functions === */
+
+func (r *Registry) add(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.metrics = append(r.metrics, m)
+}
+
+func (c *Counter) write(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.header(w); err != nil {
+		return err
+	}
+
+	for _, key := range sortedKeys(c.values) {
+		_, err := fmt.Fprintf(
+			w, "%s%s %s\n", c.name, braces(key), formatFloat(c.values[key]),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Histogram) write(w io.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.header(w); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := h.series[key]
+
+		/* Prometheus buckets are cumulative */
+		var total uint64
+		for i, bound := range h.buckets {
+			total += s.counts[i]
+			_, err := fmt.Fprintf(
+				w, "%s_bucket%s %d\n",
+				h.name, braces(join(key, `le="`+formatFloat(bound)+`"`)), total,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := fmt.Fprintf(
+			w, "%s_bucket%s %d\n%s_sum%s %s\n%s_count%s %d\n",
+			h.name, braces(join(key, `le="+Inf"`)), s.count,
+			h.name, braces(key), formatFloat(s.sum),
+			h.name, braces(key), s.count,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *gaugeFunc) write(w io.Writer) error {
+	if err := g.header(w); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.fn()))
+	return err
+}
+
+// header writes the HELP and TYPE lines for a metric
+func (d *desc) header(w io.Writer) error {
+	_, err := fmt.Fprintf(
+		w, "# HELP %s %s\n# TYPE %s %s\n",
+		d.name, strings.Replace(d.help, "\n", " ", -1), d.name, d.kind,
+	)
+	return err
+}
+
+// labelString formats label values as they appear between the braces of a
+// series ("name=\"value\",..."). Missing values are left empty.
+func (d *desc) labelString(values []string) string {
+	pairs := make([]string, len(d.labels))
+	for i, label := range d.labels {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs[i] = label + "=" + strconv.Quote(value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func join(a, b string) string {
+	if len(a) == 0 {
+		return b
+	}
+	return a + "," + b
+}
+
+func braces(labels string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
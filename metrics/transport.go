@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"net/http"
+)
+
+// Transport is an HTTP transport which reports failed requests: ones which
+// couldn't be made, and ones which got an error status (4xx or 5xx).
+type Transport struct {
+	// Base makes the requests, http.DefaultTransport if nil
+	Base http.RoundTripper
+	// OnError is called with each failed request, and either its response or
+	// the error which stopped it being made
+	OnError func(req *http.Request, resp *http.Response, err error)
+}
+
+// RoundTrip makes a request using the base transport
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	resp, err := base.RoundTrip(req)
+	if t.OnError != nil && (err != nil || resp.StatusCode >= 400) {
+		t.OnError(req, resp, err)
+	}
+	return resp, err
+}
@@ -0,0 +1,100 @@
+package multiplexer
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/util"
+	"github.com/bwmarrin/discordgo"
+)
+
+// ChannelSend is a helper function for easily sending a message to the current
+// channel.
+func (ctx *Context) ChannelSend(message string) (*discordgo.Message, error) {
+	return ctx.ChannelSendComplex(&discordgo.MessageSend{Content: message})
+}
+
+// ChannelSendf is a helper function like ChannelSend for sending a formatted
+// message to the current channel.
+func (ctx *Context) ChannelSendf(
+	format string,
+	a ...interface{},
+) (*discordgo.Message, error) {
+	return ctx.ChannelSend(fmt.Sprintf(format, a...))
+}
+
+// ChannelSendLong is a helper function like ChannelSend for sending messages
+// which may be longer than Discord allows. The message is split into as many
+// messages as needed.
+func (ctx *Context) ChannelSendLong(message string) ([]*discordgo.Message, error) {
+	var sent []*discordgo.Message
+	for _, chunk := range util.SplitMessage(message, util.MaxMessageLength) {
+		msg, err := ctx.ChannelSend(chunk)
+		if err != nil {
+			return sent, err
+		}
+		sent = append(sent, msg)
+	}
+
+	return sent, nil
+}
+
+// Reply is a helper function for sending a message to the current channel as
+// a reply to the message which invoked the command.
+func (ctx *Context) Reply(message string) (*discordgo.Message, error) {
+	return ctx.send(&discordgo.MessageSend{Content: message}, ctx.reference())
+}
+
+// Replyf is a helper function like Reply for replying with a formatted
+// message.
+func (ctx *Context) Replyf(
+	format string,
+	a ...interface{},
+) (*discordgo.Message, error) {
+	return ctx.Reply(fmt.Sprintf(format, a...))
+}
+
+// EmbedSend is a helper function for sending an embed to the current channel,
+// styled like the rest of the bot's embeds.
+func (ctx *Context) EmbedSend(
+	embed *discordgo.MessageEmbed,
+) (*discordgo.Message, error) {
+	return ctx.ChannelSendComplex(&discordgo.MessageSend{
+		Embed: util.StyleEmbed(embed),
+	})
+}
+
+// FileSend is a helper function for sending a file to the current channel
+func (ctx *Context) FileSend(
+	name string,
+	r io.Reader,
+) (*discordgo.Message, error) {
+	return ctx.ChannelSendComplex(&discordgo.MessageSend{
+		Files: []*discordgo.File{{Name: name, Reader: r}},
+	})
+}
+
+// DMSend is a helper function for sending a direct message to the user who
+// invoked the command. If they don't accept direct messages, they're told to
+// allow them (without the message being shown), and the error is returned.
+func (ctx *Context) DMSend(message string) (*discordgo.Message, error) {
+	sent, err := util.SendDM(
+		ctx.Session, ctx.Message.Author.ID,
+		&discordgo.MessageSend{Content: message},
+	)
+	if err != nil {
+		notice, _ := ctx.Reply(util.DMFallback)
+		return notice, err
+	}
+
+	return sent, nil
+}
+
+// reference returns a reference to the message which invoked the command
+func (ctx *Context) reference() *discordgo.MessageReference {
+	return &discordgo.MessageReference{
+		MessageID: ctx.Message.ID,
+		ChannelID: ctx.Message.ChannelID,
+		GuildID:   ctx.Message.GuildID,
+	}
+}
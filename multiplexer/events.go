@@ -0,0 +1,267 @@
+package multiplexer
+
+import (
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/bwmarrin/discordgo"
+)
+
+type (
+	// EventType identifies a gateway event listeners can be registered for
+	EventType string
+
+	// EventContext is the contextual values supplied to event middlewares
+	EventContext struct {
+		Type     EventType
+		Listener string
+
+		GuildID, ChannelID, UserID string
+
+		Session session.Session
+		Event   interface{}
+	}
+
+	// EventMiddleware specifies a special middleware function that is called
+	// anytime an event listener is called.
+	EventMiddleware func(*EventContext)
+
+	// listener is a named event handler. Handlers are wrapped so every event
+	// type can be stored the same way.
+	listener struct {
+		name    string
+		handler func(s session.Session, event interface{})
+	}
+)
+
+const (
+	// EventMemberJoin is fired when a member joins a guild
+	EventMemberJoin EventType = "memberJoin"
+	// EventMemberLeave is fired when a member leaves (or is removed from) a
+	// guild
+	EventMemberLeave EventType = "memberLeave"
+	// EventMemberUpdate is fired when a member's roles or nickname change
+	EventMemberUpdate EventType = "memberUpdate"
+	// EventMessageCreate is fired when a message is sent, before it's handled
+	// as a command
+	EventMessageCreate EventType = "messageCreate"
+	// EventMessageUpdate is fired when a message is edited
+	EventMessageUpdate EventType = "messageUpdate"
+	// EventMessageDelete is fired when a message is deleted
+	EventMessageDelete EventType = "messageDelete"
+	// EventMessageDeleteBulk is fired when many messages are deleted at once
+	EventMessageDeleteBulk EventType = "messageDeleteBulk"
+	// EventReactionAdd is fired when a reaction is added to a message
+	EventReactionAdd EventType = "reactionAdd"
+	// EventReactionRemove is fired when a reaction is removed from a message
+	EventReactionRemove EventType = "reactionRemove"
+	// EventGuildCreate is fired when a guild becomes available to the bot
+	EventGuildCreate EventType = "guildCreate"
+	// EventReady is fired when the bot connects to the gateway
+	EventReady EventType = "ready"
+)
+
+// UseEventMiddleware adds a middleware to the event listeners. Middlewares are
+// called before a listener is called.
+func (m *Mux) UseEventMiddleware(mw EventMiddleware) {
+	m.eventMiddleware = append(m.eventMiddleware, mw)
+}
+
+// OnMemberJoin registers a listener for members joining a guild
+func (m *Mux) OnMemberJoin(
+	name string, fn func(session.Session, *discordgo.GuildMemberAdd),
+) {
+	m.listen(EventMemberJoin, name, func(s session.Session, e interface{}) {
+		fn(s, e.(*discordgo.GuildMemberAdd))
+	})
+}
+
+// OnMemberLeave registers a listener for members leaving a guild
+func (m *Mux) OnMemberLeave(
+	name string, fn func(session.Session, *discordgo.GuildMemberRemove),
+) {
+	m.listen(EventMemberLeave, name, func(s session.Session, e interface{}) {
+		fn(s, e.(*discordgo.GuildMemberRemove))
+	})
+}
+
+// OnMemberUpdate registers a listener for members' roles or nicknames changing
+func (m *Mux) OnMemberUpdate(
+	name string, fn func(session.Session, *discordgo.GuildMemberUpdate),
+) {
+	m.listen(EventMemberUpdate, name, func(s session.Session, e interface{}) {
+		fn(s, e.(*discordgo.GuildMemberUpdate))
+	})
+}
+
+// OnMessageCreate registers a listener for messages being sent. Listeners are
+// called for every message the multiplexer handles, including the bot's own.
+func (m *Mux) OnMessageCreate(
+	name string, fn func(session.Session, *discordgo.MessageCreate),
+) {
+	m.listen(EventMessageCreate, name, func(s session.Session, e interface{}) {
+		fn(s, e.(*discordgo.MessageCreate))
+	})
+}
+
+// OnMessageUpdate registers a listener for messages being edited
+func (m *Mux) OnMessageUpdate(
+	name string, fn func(session.Session, *discordgo.MessageUpdate),
+) {
+	m.listen(EventMessageUpdate, name, func(s session.Session, e interface{}) {
+		fn(s, e.(*discordgo.MessageUpdate))
+	})
+}
+
+// OnMessageDelete registers a listener for messages being deleted
+func (m *Mux) OnMessageDelete(
+	name string, fn func(session.Session, *discordgo.MessageDelete),
+) {
+	m.listen(EventMessageDelete, name, func(s session.Session, e interface{}) {
+		fn(s, e.(*discordgo.MessageDelete))
+	})
+}
+
+// OnMessageDeleteBulk registers a listener for messages being bulk deleted
+func (m *Mux) OnMessageDeleteBulk(
+	name string, fn func(session.Session, *discordgo.MessageDeleteBulk),
+) {
+	m.listen(EventMessageDeleteBulk, name, func(s session.Session, e interface{}) {
+		fn(s, e.(*discordgo.MessageDeleteBulk))
+	})
+}
+
+// OnReactionAdd registers a listener for reactions being added to a message
+func (m *Mux) OnReactionAdd(
+	name string, fn func(session.Session, *discordgo.MessageReactionAdd),
+) {
+	m.listen(EventReactionAdd, name, func(s session.Session, e interface{}) {
+		fn(s, e.(*discordgo.MessageReactionAdd))
+	})
+}
+
+// OnReactionRemove registers a listener for reactions being removed from a
+// message
+func (m *Mux) OnReactionRemove(
+	name string, fn func(session.Session, *discordgo.MessageReactionRemove),
+) {
+	m.listen(EventReactionRemove, name, func(s session.Session, e interface{}) {
+		fn(s, e.(*discordgo.MessageReactionRemove))
+	})
+}
+
+// OnGuildCreate registers a listener for guilds becoming available
+func (m *Mux) OnGuildCreate(
+	name string, fn func(session.Session, *discordgo.GuildCreate),
+) {
+	m.listen(EventGuildCreate, name, func(s session.Session, e interface{}) {
+		fn(s, e.(*discordgo.GuildCreate))
+	})
+}
+
+// OnReady registers a listener for the bot connecting to the gateway
+func (m *Mux) OnReady(
+	name string, fn func(session.Session, *discordgo.Ready),
+) {
+	m.listen(EventReady, name, func(s session.Session, e interface{}) {
+		fn(s, e.(*discordgo.Ready))
+	})
+}
+
+// AttachEvents adds the DiscordGo handler which feeds events to the registered
+// listeners.
+func (m *Mux) AttachEvents(s *discordgo.Session) {
+	s.AddHandler(func(s *discordgo.Session, e interface{}) {
+		m.HandleEvent(session.Wrap(s), e)
+	})
+}
+
+// HandleEvent passes an event received through any session (such as a fake
+// one) to the registered listeners. Events no listener can handle are ignored.
+func (m *Mux) HandleEvent(s session.Session, event interface{}) {
+	switch e := event.(type) {
+	case *discordgo.GuildMemberAdd:
+		m.dispatch(s, &EventContext{
+			Type: EventMemberJoin, GuildID: e.GuildID, UserID: e.User.ID,
+		}, e)
+	case *discordgo.GuildMemberRemove:
+		m.dispatch(s, &EventContext{
+			Type: EventMemberLeave, GuildID: e.GuildID, UserID: e.User.ID,
+		}, e)
+	case *discordgo.GuildMemberUpdate:
+		m.dispatch(s, &EventContext{
+			Type: EventMemberUpdate, GuildID: e.GuildID, UserID: e.User.ID,
+		}, e)
+	case *discordgo.MessageUpdate:
+		ctx := &EventContext{
+			Type: EventMessageUpdate, GuildID: e.GuildID, ChannelID: e.ChannelID,
+		}
+		if e.Author != nil {
+			ctx.UserID = e.Author.ID
+		}
+		m.dispatch(s, ctx, e)
+	case *discordgo.MessageDelete:
+		m.dispatch(s, &EventContext{
+			Type: EventMessageDelete, GuildID: e.GuildID, ChannelID: e.ChannelID,
+		}, e)
+	case *discordgo.MessageDeleteBulk:
+		m.dispatch(s, &EventContext{
+			Type: EventMessageDeleteBulk, GuildID: e.GuildID, ChannelID: e.ChannelID,
+		}, e)
+	case *discordgo.MessageReactionAdd:
+		m.dispatch(s, &EventContext{
+			Type: EventReactionAdd, GuildID: e.GuildID, ChannelID: e.ChannelID,
+			UserID: e.UserID,
+		}, e)
+	case *discordgo.MessageReactionRemove:
+		m.dispatch(s, &EventContext{
+			Type: EventReactionRemove, GuildID: e.GuildID, ChannelID: e.ChannelID,
+			UserID: e.UserID,
+		}, e)
+	case *discordgo.GuildCreate:
+		m.dispatch(s, &EventContext{Type: EventGuildCreate, GuildID: e.ID}, e)
+	case *discordgo.Ready:
+		m.dispatch(s, &EventContext{Type: EventReady}, e)
+	}
+}
+
+/* === Helper Functions === */
+
+func (m *Mux) listen(
+	t EventType, name string, fn func(session.Session, interface{}),
+) {
+	m.listenerMu.Lock()
+	defer m.listenerMu.Unlock()
+
+	m.listeners[t] = append(m.listeners[t], &listener{name: name, handler: fn})
+}
+
+// dispatch calls every enabled listener registered for the event type. Each
+// listener gets its own copy of the context.
+func (m *Mux) dispatch(
+	s session.Session, base *EventContext, event interface{},
+) {
+	m.listenerMu.RLock()
+	listeners := m.listeners[base.Type]
+	m.listenerMu.RUnlock()
+
+	for _, l := range listeners {
+		if !m.IsEnabled(l.name, base.GuildID) {
+			continue
+		}
+
+		ctx := *base
+		ctx.Listener = l.name
+		ctx.Session = s
+		ctx.Event = event
+
+		for _, mw := range m.eventMiddleware {
+			go mw(&ctx)
+		}
+
+		l := l
+		m.running.Add(1)
+		go func() {
+			defer m.running.Done()
+			m.protect(l.name, nil, func() { l.handler(s, event) })
+		}()
+	}
+}
@@ -2,8 +2,14 @@ package multiplexer
 
 import (
 	"fmt"
+	"runtime/debug"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/PulseDevelopmentGroup/0x626f74/trace"
 	"github.com/PulseDevelopmentGroup/0x626f74/util"
 	"github.com/bwmarrin/discordgo"
 	"github.com/patrickmn/go-cache"
@@ -22,6 +28,29 @@ type (
 		commandNames   []string
 		errorTexts     *ErrorTexts
 		permissions    map[string]*CommandPermissions
+		dataDir        string
+		responders     []*AutoResponder
+
+		invocations     *cache.Cache
+		listeners       map[EventType][]*listener
+		eventMiddleware []EventMiddleware
+		panicHandler    PanicHandler
+		observers       []Observer
+
+		/* Command handlers, auto-responders and listeners which are running */
+		running sync.WaitGroup
+
+		/* Names of disabled commands and listeners, mapped to the IDs of the
+		guilds they're disabled in ("" meaning everywhere) */
+		disabled map[string]map[string]bool
+
+		/* Guards SimpleCommands, which can change while handling messages */
+		simpleMu    sync.RWMutex
+		responderMu sync.RWMutex
+		listenerMu  sync.RWMutex
+		disabledMu  sync.RWMutex
+		observerMu  sync.RWMutex
+		permsMu     sync.RWMutex
 	}
 
 	// Command specifies the functions for a multiplexed command
@@ -36,9 +65,9 @@ type (
 	// format. UserID takes priority over all other permissions. RoleID takes
 	// priority over ChanID.
 	CommandPermissions struct {
-		UserIDs []string
-		RoleIDs []string
-		ChanIDs []string
+		UserIDs []string `json:"userIDs,omitempty"`
+		RoleIDs []string `json:"roleIDs,omitempty"`
+		ChanIDs []string `json:"chanIDs,omitempty"`
 	}
 
 	// CommandSettings contain command-specific settings the multiplexer should
@@ -48,25 +77,69 @@ type (
 
 		RateLimitMax int
 		RateLimitDB  *cache.Cache
-	}
-
-	// SimpleCommand contains the content and helptext of a logic-less command.
-	// Simple commands have no support for permissions.
-	SimpleCommand struct {
-		Command, Content, HelpText string
+		// RateLimitWindow is how long uses are counted for, which should be
+		// the default expiration RateLimitDB was created with
+		RateLimitWindow time.Duration
+
+		// IgnoreEdits stops the command from being re-run when the message
+		// which invoked it is edited. Should be set for commands with side
+		// effects.
+		IgnoreEdits bool
+		// DeleteReplies deletes the command's replies when the message which
+		// invoked it is deleted.
+		DeleteReplies bool
+
+		// Category groups the command with similar commands in help
+		Category string
+		// Usage describes the command's arguments, ie. "[give|take] [role]"
+		Usage string
+		// Examples are arguments the command could be used with
+		Examples []string
+		// Hidden leaves the command out of the list of commands in help
+		Hidden bool
+		// Restricted commands can only be used by those given permission in
+		// the config. Nobody can use them until then.
+		Restricted bool
 	}
 
 	// ErrorTexts holds strings used when an error occurs
 	ErrorTexts struct {
-		CommandNotFound, NoPermissions, RateLimited string
+		CommandNotFound, NoPermissions, RateLimited, Disabled string
 	}
 
+	// PanicHandler is called when a command or event listener panics. The
+	// context is nil for event listeners.
+	PanicHandler func(name string, ctx *Context, err interface{}, stack []byte)
+
 	// Context is the contexual values supplied to middlewares and handlers
 	Context struct {
 		Prefix, Command string
 		Arguments       []string
-		Session         *discordgo.Session
+		Session         session.Session
 		Message         *discordgo.MessageCreate
+
+		// ID identifies the invocation in logs, error reports and outbound
+		// requests. It's the ID of its trace.
+		ID string
+		// Trace records each stage of handling the invocation
+		Trace *trace.Trace
+
+		/* Replies are recorded to the invocation (if it's being tracked), and
+		replies from a previous run are re-used */
+		invocation *invocation
+		previous   *replySet
+
+		/* The member who sent the message, fetched when first needed */
+		member   *discordgo.Member
+		memberMu sync.Mutex
+
+		/* Set when the command reports an error */
+		failed bool
+		failMu sync.Mutex
+
+		/* The span of the stage which is running */
+		span   *trace.Span
+		spanMu sync.Mutex
 	}
 
 	// Middleware specifies a special middleware function that is called anytime
@@ -89,7 +162,7 @@ func New(prefix string) (*Mux, error) {
 		return &Mux{}, fmt.Errorf("prefix %s greater than 1 character", prefix)
 	}
 
-	return &Mux{
+	m := &Mux{
 		Prefix:         prefix,
 		Commands:       make(map[string]Command),
 		SimpleCommands: make(map[string]SimpleCommand),
@@ -97,11 +170,23 @@ func New(prefix string) (*Mux, error) {
 		errorTexts: &ErrorTexts{
 			CommandNotFound: "Command not found.",
 			NoPermissions:   "You do not have permission to use that command.",
+			Disabled:        "That command is currently disabled.",
 		},
 		options:     &Options{true, true, true, true},
 		permissions: make(map[string]*CommandPermissions),
 		fuzzyMatch:  false,
-	}, nil
+		invocations: cache.New(DefaultEditWindow, DefaultEditWindow),
+		listeners:   make(map[EventType][]*listener),
+		disabled:    make(map[string]map[string]bool),
+	}
+
+	/* Re-run commands when the message which invoked them is edited, and
+	clean up after them when it's deleted */
+	m.OnMessageUpdate("mux:rerun", m.handleEdit)
+	m.OnMessageDelete("mux:cleanup", m.handleDelete)
+	m.OnMessageDeleteBulk("mux:cleanup", m.handleDeleteBulk)
+
+	return m, nil
 }
 
 // SetOptions allows configuration of the multiplexer. Must be called before
@@ -110,12 +195,21 @@ func (m *Mux) SetOptions(opt *Options) {
 	m.options = opt
 }
 
-// SetPermissions allows defining permissions for each command. Must be called
-// before Initialize()
+// SetPermissions allows defining permissions for each command. May be called
+// again while handling messages, ie. when the config is reloaded.
 func (m *Mux) SetPermissions(perms map[string]*CommandPermissions) {
+	m.permsMu.Lock()
+	defer m.permsMu.Unlock()
+
 	m.permissions = perms
 }
 
+// SetDataDir sets the directory files attached to simple commands are loaded
+// from.
+func (m *Mux) SetDataDir(dir string) {
+	m.dataDir = dir
+}
+
 // UseMiddleware adds a middleware to the multiplexer. Middlewares are called
 // before a command is handled.
 func (m *Mux) UseMiddleware(mw Middleware) {
@@ -137,20 +231,138 @@ func (m *Mux) Register(commands ...Command) {
 	}
 }
 
-// RegisterSimple registers one or more simple commands to the multiplexer
+// RegisterSimple registers one or more simple commands to the multiplexer.
+// Simple commands may be registered while the multiplexer is handling
+// messages.
 func (m *Mux) RegisterSimple(simpleCommands ...SimpleCommand) {
+	m.simpleMu.Lock()
+	defer m.simpleMu.Unlock()
+
 	for _, c := range simpleCommands {
-		cString := c.Command
-		if len(cString) != 0 {
-			m.SimpleCommands[cString] = c
+		cString := strings.ToLower(c.Command)
+		if len(cString) == 0 {
+			continue
 		}
+
+		/* Invalid templates are sent as-is, check with CheckSimple() first */
+		c.compile()
+
+		m.SimpleCommands[cString] = c
+	}
+}
+
+// RemoveSimple unregisters one or more simple commands from the multiplexer
+func (m *Mux) RemoveSimple(commands ...string) {
+	m.simpleMu.Lock()
+	defer m.simpleMu.Unlock()
+
+	for _, c := range commands {
+		delete(m.SimpleCommands, strings.ToLower(c))
 	}
 }
 
+// ClearSimple unregisters all simple commands from the multiplexer
 func (m *Mux) ClearSimple() {
+	m.simpleMu.Lock()
+	defer m.simpleMu.Unlock()
+
 	m.SimpleCommands = make(map[string]SimpleCommand)
 }
 
+// GetSimple returns the simple command registered under the supplied name
+func (m *Mux) GetSimple(command string) (SimpleCommand, bool) {
+	m.simpleMu.RLock()
+	defer m.simpleMu.RUnlock()
+
+	c, ok := m.SimpleCommands[strings.ToLower(command)]
+	return c, ok
+}
+
+// ListSimple returns every simple command, sorted by name
+func (m *Mux) ListSimple() []SimpleCommand {
+	m.simpleMu.RLock()
+	defer m.simpleMu.RUnlock()
+
+	list := make([]SimpleCommand, 0, len(m.SimpleCommands))
+	for _, c := range m.SimpleCommands {
+		list = append(list, c)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return strings.ToLower(list[i].Command) <
+			strings.ToLower(list[j].Command)
+	})
+	return list
+}
+
+// IsCommand checks if the supplied name is taken by either a command or a
+// simple command.
+func (m *Mux) IsCommand(command string) bool {
+	if _, ok := m.Commands[strings.ToLower(command)]; ok {
+		return true
+	}
+
+	_, ok := m.GetSimple(command)
+	return ok
+}
+
+// GetPermissions returns the permissions specified for the supplied name, if
+// there are any. Names don't need to belong to a registered command, allowing
+// commands to define their own finer grained permissions (ie. "tag.create").
+func (m *Mux) GetPermissions(name string) (*CommandPermissions, bool) {
+	m.permsMu.RLock()
+	defer m.permsMu.RUnlock()
+
+	p, ok := m.permissions[strings.ToLower(name)]
+	return p, ok
+}
+
+// SetPanicHandler sets the function called when a command or event listener
+// panics. Panics are always recovered, even when no handler is set.
+func (m *Mux) SetPanicHandler(handler PanicHandler) {
+	m.panicHandler = handler
+}
+
+// Disable disables a command or event listener in the supplied guild. An empty
+// guild ID disables it everywhere.
+func (m *Mux) Disable(name, guildID string) {
+	m.disabledMu.Lock()
+	defer m.disabledMu.Unlock()
+
+	name = strings.ToLower(name)
+	if m.disabled[name] == nil {
+		m.disabled[name] = make(map[string]bool)
+	}
+	m.disabled[name][guildID] = true
+}
+
+// Enable re-enables a command or event listener in the supplied guild. An
+// empty guild ID enables it everywhere it was disabled.
+func (m *Mux) Enable(name, guildID string) {
+	m.disabledMu.Lock()
+	defer m.disabledMu.Unlock()
+
+	name = strings.ToLower(name)
+	if guildID == "" {
+		delete(m.disabled, name)
+		return
+	}
+	delete(m.disabled[name], guildID)
+}
+
+// IsEnabled checks if a command or event listener is enabled in the supplied
+// guild.
+func (m *Mux) IsEnabled(name, guildID string) bool {
+	m.disabledMu.RLock()
+	defer m.disabledMu.RUnlock()
+
+	guilds, ok := m.disabled[strings.ToLower(name)]
+	if !ok {
+		return true
+	}
+	return !guilds[""] && !guilds[guildID]
+}
+
 // UseFuzzy both enables and builds a list of commands to fuzzy match
 // against. May result in a small performance hit
 func (m *Mux) UseFuzzy() {
@@ -177,11 +389,48 @@ func (m *Mux) Initialize() {
 
 // Handle is passed to DiscordGo to handle actions
 func (m *Mux) Handle(
-	session *discordgo.Session,
+	s *discordgo.Session,
+	message *discordgo.MessageCreate,
+) {
+	m.HandleMessage(session.Wrap(s), message)
+}
+
+// HandleMessage handles a message sent through any session, such as a fake
+// one. Message listeners are called before it's handled.
+func (m *Mux) HandleMessage(
+	session session.Session,
 	message *discordgo.MessageCreate,
 ) {
+	m.dispatch(session, &EventContext{
+		Type:      EventMessageCreate,
+		GuildID:   message.GuildID,
+		ChannelID: message.ChannelID,
+		UserID:    message.Author.ID,
+	}, message)
+	m.handle(session, message, nil)
+}
+
+// handle runs a message through the multiplexer. When re-running an edited
+// message, the replies of the previous run are supplied so they can be re-used.
+// Any which aren't re-used are deleted.
+func (m *Mux) handle(
+	session session.Session,
+	message *discordgo.MessageCreate,
+	previous *replySet,
+) {
+	/* Unless the handler is started (which cleans up itself), remove any
+	left over replies once the message is handled */
+	started := false
+	if previous != nil {
+		defer func() {
+			if !started {
+				previous.discard(session)
+			}
+		}()
+	}
+
 	/* Ignore if the message being handled originated from the bot */
-	if message.Author.ID == session.State.User.ID {
+	if message.Author.ID == session.State().User.ID {
 		return
 	}
 
@@ -190,9 +439,10 @@ func (m *Mux) Handle(
 		return
 	}
 
-	/* Ignore if the message is not default */
+	/* Ignore if the message is not default (replies count as default) */
 	if m.options.IgnoreNonDefault &&
-		message.Type != discordgo.MessageTypeDefault {
+		message.Type != discordgo.MessageTypeDefault &&
+		message.Type != messageTypeReply {
 		return
 	}
 
@@ -206,11 +456,22 @@ func (m *Mux) Handle(
 		return
 	}
 
-	/* Ignore if the message doesn't have the prefix */
+	/* Messages without the prefix can only trigger auto-responders */
 	if !strings.HasPrefix(message.Content, m.Prefix) {
+		if previous == nil {
+			m.running.Add(1)
+			go func() {
+				defer m.running.Done()
+				m.handleResponders(session, message)
+			}()
+		}
 		return
 	}
 
+	/* Every invocation is traced, from being parsed onwards */
+	tr := trace.New("command")
+	parse := tr.Root.Child("parse")
+
 	/* Split the message on the space */
 	var args []string
 	for _, arg := range strings.Split(message.Content, " ") {
@@ -222,16 +483,36 @@ func (m *Mux) Handle(
 	}
 
 	command := strings.ToLower(args[0][1:])
+	start := time.Now()
+	parse.End()
 
-	simple, ok := m.SimpleCommands[command]
+	/* Form context */
+	ctx := &Context{
+		Prefix:    m.Prefix,
+		Command:   command,
+		Arguments: args[1:],
+		Session:   session,
+		Message:   message,
+		previous:  previous,
+	}
+	ctx.startTrace(tr)
+
+	simple, ok := m.GetSimple(command)
 	if ok {
-		session.ChannelMessageSend(message.ChannelID, simple.Content)
+		m.track(ctx, true, false)
+		span := ctx.stage("simple")
+		m.handleSimple(ctx, simple)
+		span.End()
+		m.observe(ctx, ctx.outcome(false), start)
 		return
 	}
 
 	handler, ok := m.Commands[command]
 	/* If command does not exist, attempt to fuzzy match it */
 	if !ok {
+		/* Fixing the typo re-runs the command, replacing this reply */
+		m.track(ctx, true, true)
+
 		if m.fuzzyMatch {
 			var sb strings.Builder
 
@@ -240,37 +521,39 @@ func (m *Mux) Handle(
 			}
 
 			if sb.Len() != 0 {
-				session.ChannelMessageSend(
-					message.ChannelID,
-					fmt.Sprintf(
-						"Command not found. Did you mean: \n%s", sb.String(),
-					),
+				ctx.ChannelSendf(
+					"Command not found. Did you mean: \n%s", sb.String(),
 				)
+				m.observe(ctx, OutcomeNotFound, start)
 				return
 			}
 
 		}
 
-		session.ChannelMessageSend(
-			message.ChannelID,
-			m.errorTexts.CommandNotFound,
-		)
-
+		ctx.ChannelSend(m.errorTexts.CommandNotFound)
+		m.observe(ctx, OutcomeNotFound, start)
 		return
 	}
 
-	/* Form context */
 	settings := handler.Settings()
-	ctx := &Context{
-		Prefix:    m.Prefix,
-		Command:   command,
-		Arguments: args[1:],
-		Session:   session,
-		Message:   message,
+
+	/* Commands which ignore edits shouldn't be started by one either */
+	if previous != nil && settings.IgnoreEdits {
+		return
 	}
 
-	if !settings.checkLimit(message.Author.ID) {
+	if !m.IsEnabled(command, message.GuildID) {
+		ctx.ChannelSend(m.errorTexts.Disabled)
+		m.observe(ctx, OutcomeDisabled, start)
+		return
+	}
+
+	span := ctx.stage("rate_limit")
+	limited := !settings.checkLimit(message.Author.ID)
+	span.End()
+	if limited {
 		ctx.ChannelSend(m.errorTexts.RateLimited)
+		m.observe(ctx, OutcomeRateLimited, start)
 		return
 	}
 
@@ -283,30 +566,142 @@ func (m *Mux) Handle(
 	}
 
 	/* If permissions have been specified, check them */
-	p, ok := m.permissions[command]
-	if ok {
-		member, err := session.GuildMember(message.GuildID, message.Author.ID)
-		if err != nil {
-			ctx.ChannelSend("There was a weird issue.")
-			return
-		}
-
-		/* Check the permissions struct against the context */
-		if !CheckPermissions(
-			p, member.User.ID, member.Roles, message.ChannelID,
-		) {
-			/* The user doesn't have the correct permissions */
-			ctx.ChannelSend(m.errorTexts.NoPermissions)
-			return
-		}
+	span = ctx.stage("permissions")
+	allowed, err := m.permitted(ctx, command)
+	span.Fail(err)
+	span.End()
+	if err != nil {
+		ctx.ChannelSend("There was a weird issue.")
+		m.observe(ctx, OutcomeError, start)
+		return
 	}
+	if !allowed {
+		/* The user doesn't have the correct permissions */
+		ctx.ChannelSend(m.errorTexts.NoPermissions)
+		m.observe(ctx, OutcomeDenied, start)
+		return
+	}
+
+	m.track(ctx, !settings.IgnoreEdits, settings.DeleteReplies)
 
 	/* User has permissions or it doesnt require them? Run it */
-	go handler.Handle(ctx)
+	started = true
+	span = ctx.stage("handler")
+	m.running.Add(1)
+	go func() {
+		defer m.running.Done()
+		panicked := m.protect(command, ctx, func() { handler.Handle(ctx) })
+		span.End()
+		ctx.previous.discard(session)
+		m.observe(ctx, ctx.outcome(panicked), start)
+	}()
+}
+
+// Wait waits for every running command handler, auto-responder and event
+// listener to finish.
+func (m *Mux) Wait() {
+	m.running.Wait()
 }
 
 /* === Helper Functions === */
 
+// CanRun checks if the user who sent the context's message can run the named
+// command in the current channel. Simple commands can be run by anyone.
+func (m *Mux) CanRun(ctx *Context, name string) bool {
+	name = strings.ToLower(name)
+	if _, ok := m.GetSimple(name); ok {
+		return true
+	}
+
+	if _, ok := m.Commands[name]; !ok {
+		return false
+	}
+
+	if !m.IsEnabled(name, ctx.Message.GuildID) {
+		return false
+	}
+
+	allowed, err := m.permitted(ctx, name)
+	return err == nil && allowed
+}
+
+// permitted checks the context against the permissions specified for the
+// named command. Commands without permissions can be used by anyone, unless
+// they're restricted.
+func (m *Mux) permitted(ctx *Context, name string) (bool, error) {
+	p, ok := m.GetPermissions(name)
+	if !ok {
+		cmd, ok := m.Commands[name]
+		return !ok || !cmd.Settings().Restricted, nil
+	}
+
+	member, err := ctx.Member()
+	if err != nil {
+		return false, err
+	}
+
+	/* Check the permissions struct against the context */
+	return CheckPermissions(
+		p, member.User.ID, member.Roles, ctx.Message.ChannelID,
+	), nil
+}
+
+// Member returns the guild member who sent the context's message. The member
+// is fetched once, and re-used by later calls.
+func (ctx *Context) Member() (*discordgo.Member, error) {
+	ctx.memberMu.Lock()
+	defer ctx.memberMu.Unlock()
+
+	if ctx.member != nil {
+		return ctx.member, nil
+	}
+
+	member, err := ctx.Session.State().Member(
+		ctx.Message.GuildID, ctx.Message.Author.ID,
+	)
+	if err != nil {
+		member, err = ctx.Session.GuildMember(
+			ctx.Message.GuildID, ctx.Message.Author.ID,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctx.member = member
+	return member, nil
+}
+
+// UsageText returns how the command is used, ie. "!role [give|take] [role]"
+func (cs *CommandSettings) UsageText(prefix string) string {
+	return strings.TrimSpace(prefix + cs.Command + " " + cs.Usage)
+}
+
+// ExampleTexts returns the command's examples, prefixed with the command
+func (cs *CommandSettings) ExampleTexts(prefix string) []string {
+	examples := make([]string, len(cs.Examples))
+	for i, e := range cs.Examples {
+		examples[i] = strings.TrimSpace(prefix + cs.Command + " " + e)
+	}
+	return examples
+}
+
+// protect calls the supplied function, recovering from (and reporting) any
+// panic which occurs. Returns true if the function panicked.
+func (m *Mux) protect(name string, ctx *Context, fn func()) (panicked bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			panicked = true
+			if m.panicHandler != nil {
+				m.panicHandler(name, ctx, err, debug.Stack())
+			}
+		}
+	}()
+
+	fn()
+	return false
+}
+
 // checkLimit checks the supplied command settings' rate limiter to see if
 // the user is allowed to run the command.
 func (cs *CommandSettings) checkLimit(id string) bool {
@@ -333,23 +728,6 @@ func (cs *CommandSettings) checkLimit(id string) bool {
 	return false
 }
 
-// ChannelSend is a helper function for easily sending a message to the current
-// channel.
-func (ctx *Context) ChannelSend(message string) (*discordgo.Message, error) {
-	return ctx.Session.ChannelMessageSend(ctx.Message.ChannelID, message)
-}
-
-// ChannelSendf is a helper function like ChannelSend for sending a formatted
-// message to the current channel.
-func (ctx *Context) ChannelSendf(
-	format string,
-	a ...interface{},
-) (*discordgo.Message, error) {
-	return ctx.Session.ChannelMessageSend(
-		ctx.Message.ChannelID, fmt.Sprintf(format, a...),
-	)
-}
-
 // CheckPermissions takes the user, role(s), and channel IDs and checks them
 // against the supplied permissions struct.
 func CheckPermissions(
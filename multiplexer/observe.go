@@ -0,0 +1,82 @@
+package multiplexer
+
+import (
+	"time"
+)
+
+type (
+	// Outcome is how handling a command ended
+	Outcome string
+
+	// Observer is called once a command has been handled, with how it ended and
+	// how long it took. Used to collect metrics.
+	Observer func(ctx *Context, outcome Outcome, duration time.Duration)
+)
+
+const (
+	// OutcomeOK is a command which ran without reporting an error
+	OutcomeOK Outcome = "ok"
+	// OutcomeError is a command which reported an error (see Context.Fail)
+	OutcomeError Outcome = "error"
+	// OutcomePanic is a command which panicked
+	OutcomePanic Outcome = "panic"
+	// OutcomeNotFound is a message with the prefix, but no matching command
+	OutcomeNotFound Outcome = "not_found"
+	// OutcomeDisabled is a command which is disabled in the guild
+	OutcomeDisabled Outcome = "disabled"
+	// OutcomeRateLimited is a command the user has run too many times
+	OutcomeRateLimited Outcome = "rate_limited"
+	// OutcomeDenied is a command the user doesn't have permission to run
+	OutcomeDenied Outcome = "denied"
+)
+
+// UseObserver adds an observer, which is called whenever a command has been
+// handled.
+func (m *Mux) UseObserver(o Observer) {
+	m.observerMu.Lock()
+	defer m.observerMu.Unlock()
+
+	m.observers = append(m.observers, o)
+}
+
+// Fail marks the command as having failed, so observers see it ended with an
+// error. Called when an error is reported to the user.
+func (ctx *Context) Fail() {
+	ctx.failMu.Lock()
+	defer ctx.failMu.Unlock()
+
+	ctx.failed = true
+}
+
+// Failed checks if the command has been marked as failed
+func (ctx *Context) Failed() bool {
+	ctx.failMu.Lock()
+	defer ctx.failMu.Unlock()
+
+	return ctx.failed
+}
+
+// outcome returns how a command which was run ended
+func (ctx *Context) outcome(panicked bool) Outcome {
+	switch {
+	case panicked:
+		return OutcomePanic
+	case ctx.Failed():
+		return OutcomeError
+	}
+	return OutcomeOK
+}
+
+// observe ends the command's trace, and calls each observer with the outcome
+// of the command and the time since it started being handled.
+func (m *Mux) observe(ctx *Context, outcome Outcome, start time.Time) {
+	duration := time.Since(start)
+	ctx.endTrace(outcome)
+
+	m.observerMu.RLock()
+	defer m.observerMu.RUnlock()
+
+	for _, o := range m.observers {
+		o(ctx, outcome, duration)
+	}
+}
@@ -0,0 +1,91 @@
+package multiplexer
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+type (
+	// RateLimitState is the live state of a command's rate limiter
+	RateLimitState struct {
+		Command string         `json:"command"`
+		Max     int            `json:"max"`
+		Window  string         `json:"window"`
+		Users   []RateLimitUse `json:"users"`
+	}
+
+	// RateLimitUse is how many times a user has tried to use a command within
+	// the current window
+	RateLimitUse struct {
+		UserID  string    `json:"userID"`
+		Uses    int       `json:"uses"`
+		Limited bool      `json:"limited"`
+		Resets  time.Time `json:"resets"`
+	}
+)
+
+// RateLimits returns the state of every command's rate limiter, sorted by
+// command name. Users are sorted by their number of uses, most first.
+func (m *Mux) RateLimits() []RateLimitState {
+	var states []RateLimitState
+	for name, cmd := range m.Commands {
+		s := cmd.Settings()
+		if s.RateLimitDB == nil {
+			continue
+		}
+
+		state := RateLimitState{
+			Command: name,
+			Max:     s.RateLimitMax,
+			Window:  s.RateLimitWindow.String(),
+			Users:   []RateLimitUse{},
+		}
+
+		for id, item := range s.RateLimitDB.Items() {
+			uses, ok := item.Object.(int)
+			if !ok {
+				continue
+			}
+
+			use := RateLimitUse{
+				UserID: id, Uses: uses, Limited: uses > s.RateLimitMax,
+			}
+			if item.Expiration != 0 {
+				use.Resets = time.Unix(0, item.Expiration)
+			}
+			state.Users = append(state.Users, use)
+		}
+
+		sort.Slice(state.Users, func(i, j int) bool {
+			if state.Users[i].Uses != state.Users[j].Uses {
+				return state.Users[i].Uses > state.Users[j].Uses
+			}
+			return state.Users[i].UserID < state.Users[j].UserID
+		})
+		states = append(states, state)
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Command < states[j].Command
+	})
+	return states
+}
+
+// ResetRateLimit forgets a user's uses of a command, so they can use it again
+// straight away. Returns false if the command doesn't exist, or has no rate
+// limit.
+func (m *Mux) ResetRateLimit(command, userID string) bool {
+	cmd, ok := m.Commands[strings.ToLower(command)]
+	if !ok {
+		return false
+	}
+
+	s := cmd.Settings()
+	if s.RateLimitDB == nil {
+		return false
+	}
+
+	s.RateLimitDB.Delete(userID)
+	return true
+}
@@ -0,0 +1,217 @@
+package multiplexer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type (
+	// CommandReference describes a registered command, for documentation
+	CommandReference struct {
+		Name     string   `json:"name"`
+		Simple   bool     `json:"simple"`
+		Category string   `json:"category,omitempty"`
+		HelpText string   `json:"helpText,omitempty"`
+		Usage    string   `json:"usage"`
+		Examples []string `json:"examples,omitempty"`
+		Hidden   bool     `json:"hidden,omitempty"`
+		// Restricted commands can't be used by anyone without permissions
+		Restricted bool `json:"restricted,omitempty"`
+
+		// Permissions are keyed by name, which is either the command's name
+		// or a finer grained permission (ie. "tag.create")
+		Permissions map[string]*CommandPermissions `json:"permissions,omitempty"`
+		RateLimit   *RateLimitReference            `json:"rateLimit,omitempty"`
+	}
+
+	// RateLimitReference describes a command's rate limit
+	RateLimitReference struct {
+		Max    int    `json:"max"`
+		Window string `json:"window"`
+	}
+)
+
+// Reference describes every registered command and simple command, sorted by
+// name.
+func (m *Mux) Reference() []CommandReference {
+	var refs []CommandReference
+
+	for name, cmd := range m.Commands {
+		s := cmd.Settings()
+		ref := CommandReference{
+			Name:        name,
+			Category:    s.Category,
+			HelpText:    s.HelpText,
+			Usage:       s.UsageText(m.Prefix),
+			Examples:    s.ExampleTexts(m.Prefix),
+			Hidden:      s.Hidden,
+			Restricted:  s.Restricted,
+			Permissions: m.permissionsFor(name),
+		}
+
+		if s.RateLimitDB != nil {
+			ref.RateLimit = &RateLimitReference{
+				Max:    s.RateLimitMax,
+				Window: s.RateLimitWindow.String(),
+			}
+		}
+
+		refs = append(refs, ref)
+	}
+
+	for _, s := range m.ListSimple() {
+		name := strings.ToLower(s.Command)
+		refs = append(refs, CommandReference{
+			Name:     name,
+			Simple:   true,
+			Category: s.Category,
+			HelpText: s.HelpText,
+			Usage:    m.Prefix + name,
+			Hidden:   s.Hidden,
+		})
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Name < refs[j].Name
+	})
+	return refs
+}
+
+// WriteReference writes the command reference to the directory, as both
+// Markdown (commands.md) and JSON (commands.json).
+func (m *Mux) WriteReference(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	refs := m.Reference()
+
+	data, err := json.MarshalIndent(refs, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(
+		filepath.Join(dir, "commands.json"), append(data, '\n'), 0644,
+	)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(filepath.Join(dir, "commands.md"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return writeMarkdown(f, refs)
+}
+
+// writeMarkdown writes the reference as Markdown, with a table of contents
+// followed by a section for each command.
+func writeMarkdown(w io.Writer, refs []CommandReference) error {
+	var sb strings.Builder
+	sb.WriteString("# Commands\n\n")
+	sb.WriteString("_Generated with `--dump-commands`, do not edit by hand._\n\n")
+
+	for _, r := range refs {
+		fmt.Fprintf(&sb, "- [`%s`](#%s)", r.Usage, r.Name)
+		if len(r.HelpText) != 0 {
+			sb.WriteString(" - " + r.HelpText)
+		}
+		sb.WriteString("\n")
+	}
+
+	for _, r := range refs {
+		fmt.Fprintf(&sb, "\n## %s\n\n", r.Name)
+		if len(r.HelpText) != 0 {
+			sb.WriteString(r.HelpText + "\n\n")
+		}
+
+		kind := "Command"
+		if r.Simple {
+			kind = "Simple command"
+		}
+		category := r.Category
+		if len(category) == 0 {
+			category = "None"
+		}
+		fmt.Fprintf(&sb, "- **Type:** %s\n", kind)
+		fmt.Fprintf(&sb, "- **Category:** %s\n", category)
+		fmt.Fprintf(&sb, "- **Usage:** `%s`\n", r.Usage)
+		if r.Hidden {
+			sb.WriteString("- **Hidden** from help\n")
+		}
+
+		if r.RateLimit != nil {
+			fmt.Fprintf(
+				&sb, "- **Rate limit:** %d uses per %s\n",
+				r.RateLimit.Max, r.RateLimit.Window,
+			)
+		}
+
+		switch {
+		case len(r.Permissions) == 0 && r.Restricted:
+			sb.WriteString("- **Permissions:** Nobody, until given in the config\n")
+		case len(r.Permissions) == 0:
+			sb.WriteString("- **Permissions:** Anyone\n")
+		default:
+			sb.WriteString("- **Permissions:**\n")
+			names := make([]string, 0, len(r.Permissions))
+			for name := range r.Permissions {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				p := r.Permissions[name]
+				fmt.Fprintf(
+					&sb, "  - `%s`: users %s, roles %s, channels %s\n",
+					name, idList(p.UserIDs), idList(p.RoleIDs),
+					idList(p.ChanIDs),
+				)
+			}
+		}
+
+		if len(r.Examples) != 0 {
+			sb.WriteString("\n**Examples:**\n\n")
+			for _, e := range r.Examples {
+				fmt.Fprintf(&sb, "- `%s`\n", e)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// permissionsFor returns the permissions for the command, along with any
+// finer grained permissions the command defines (ie. "tag.create").
+func (m *Mux) permissionsFor(name string) map[string]*CommandPermissions {
+	m.permsMu.RLock()
+	defer m.permsMu.RUnlock()
+
+	out := make(map[string]*CommandPermissions)
+	for k, p := range m.permissions {
+		if k == name || strings.HasPrefix(k, name+".") {
+			out[k] = p
+		}
+	}
+
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func idList(ids []string) string {
+	if len(ids) == 0 {
+		return "none"
+	}
+	return "`" + strings.Join(ids, "`, `") + "`"
+}
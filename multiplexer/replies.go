@@ -0,0 +1,238 @@
+package multiplexer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/PulseDevelopmentGroup/0x626f74/util"
+	"github.com/bwmarrin/discordgo"
+	"github.com/patrickmn/go-cache"
+)
+
+type (
+	// invocation tracks a command message and the replies the bot sent in
+	// response to it, so the command can be re-run when the message is edited,
+	// and its replies deleted when the message is deleted.
+	invocation struct {
+		message *discordgo.MessageCreate
+		replies *replySet
+		rerun   bool
+		cleanup bool
+	}
+
+	// replySet is an ordered set of replies sent to a single channel. Once
+	// closed, no more replies can be added.
+	replySet struct {
+		channelID string
+		replies   []reply
+		closed    bool
+		mu        sync.Mutex
+	}
+
+	reply struct {
+		id          string
+		embed, file bool
+	}
+)
+
+// DefaultEditWindow is how long after a command is sent that editing it will
+// re-run the command, or deleting it will delete its replies.
+const DefaultEditWindow = 5 * time.Minute
+
+// SetEditWindow sets how long after a command is sent that editing it will
+// re-run the command, or deleting it will delete its replies. Must be called
+// before Initialize()
+func (m *Mux) SetEditWindow(window time.Duration) {
+	m.invocations = cache.New(window, window)
+}
+
+// handleEdit re-runs a tracked command when the message which invoked it is
+// edited. Replies from the previous run are edited or replaced.
+func (m *Mux) handleEdit(
+	session session.Session, update *discordgo.MessageUpdate,
+) {
+	/* Embeds being unfurled also trigger updates, but without content */
+	if len(update.Content) == 0 {
+		return
+	}
+
+	v, ok := m.invocations.Get(update.ID)
+	if !ok {
+		return
+	}
+	inv := v.(*invocation)
+
+	if !inv.rerun || inv.message.Content == update.Content {
+		return
+	}
+	m.invocations.Delete(update.ID)
+
+	/* Re-use the original message, since updates may be missing the author */
+	msg := *inv.message.Message
+	msg.Content = update.Content
+	msg.EditedTimestamp = update.EditedTimestamp
+
+	m.handle(session, &discordgo.MessageCreate{Message: &msg}, inv.replies)
+}
+
+// handleDelete deletes the replies of a tracked command when the message which
+// invoked it is deleted.
+func (m *Mux) handleDelete(
+	session session.Session, del *discordgo.MessageDelete,
+) {
+	m.cleanup(session, del.ID)
+}
+
+// handleDeleteBulk is like handleDelete, but for bulk deletes
+func (m *Mux) handleDeleteBulk(
+	session session.Session, del *discordgo.MessageDeleteBulk,
+) {
+	for _, id := range del.Messages {
+		m.cleanup(session, id)
+	}
+}
+
+func (m *Mux) cleanup(session session.Session, messageID string) {
+	v, ok := m.invocations.Get(messageID)
+	if !ok {
+		return
+	}
+	m.invocations.Delete(messageID)
+
+	inv := v.(*invocation)
+	if !inv.cleanup {
+		return
+	}
+
+	/* Replies sent after this point (by a handler that's still running) are
+	deleted as soon as they're sent */
+	inv.replies.close()
+	inv.replies.discard(session)
+}
+
+// track starts tracking the replies to the context's message
+func (m *Mux) track(ctx *Context, rerun, cleanup bool) {
+	if !rerun && !cleanup {
+		return
+	}
+
+	ctx.invocation = &invocation{
+		message: ctx.Message,
+		replies: &replySet{channelID: ctx.Message.ChannelID},
+		rerun:   rerun,
+		cleanup: cleanup,
+	}
+	m.invocations.SetDefault(ctx.Message.ID, ctx.invocation)
+}
+
+// ChannelSendComplex is a helper function for sending a complex message (with
+// embeds, files, etc.) to the current channel. All of the other send helpers
+// use it, and so should commands, so replies can be edited if the command is
+// re-run.
+func (ctx *Context) ChannelSendComplex(
+	msg *discordgo.MessageSend,
+) (*discordgo.Message, error) {
+	return ctx.send(msg, nil)
+}
+
+// send sends a message to the current channel, optionally as a reply to the
+// referenced message, and records it as a reply to the command.
+func (ctx *Context) send(
+	msg *discordgo.MessageSend, ref *discordgo.MessageReference,
+) (*discordgo.Message, error) {
+	channelID := ctx.Message.ChannelID
+	r := reply{embed: msg.Embed != nil, file: len(msg.Files) != 0}
+
+	/* When re-running a command, edit the previous replies where possible */
+	if prev, ok := ctx.previous.pop(); ok {
+		if !prev.file && !r.file && (r.embed || !prev.embed) {
+			edited, err := ctx.Session.ChannelMessageEditComplex(
+				&discordgo.MessageEdit{
+					ID:              prev.id,
+					Channel:         channelID,
+					Content:         &msg.Content,
+					Embed:           msg.Embed,
+					AllowedMentions: msg.AllowedMentions,
+				},
+			)
+			if err == nil {
+				r.id = edited.ID
+				ctx.record(r)
+				return edited, nil
+			}
+		}
+
+		ctx.Session.ChannelMessageDelete(channelID, prev.id)
+	}
+
+	sent, err := util.SendReply(ctx.Session, channelID, msg, ref)
+	if err != nil {
+		return sent, err
+	}
+
+	r.id = sent.ID
+	ctx.record(r)
+	return sent, nil
+}
+
+// record adds a reply to the context's invocation, if it's being tracked. If
+// the invoking message has already been deleted, so is the reply.
+func (ctx *Context) record(r reply) {
+	if ctx.invocation == nil {
+		return
+	}
+
+	if !ctx.invocation.replies.add(r) {
+		ctx.Session.ChannelMessageDelete(ctx.Message.ChannelID, r.id)
+	}
+}
+
+// add adds a reply to the set, returning false if the set is closed
+func (rs *replySet) add(r reply) bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if rs.closed {
+		return false
+	}
+
+	rs.replies = append(rs.replies, r)
+	return true
+}
+
+func (rs *replySet) close() {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.closed = true
+}
+
+// pop removes and returns the oldest reply in the set
+func (rs *replySet) pop() (reply, bool) {
+	if rs == nil {
+		return reply{}, false
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if len(rs.replies) == 0 {
+		return reply{}, false
+	}
+
+	r := rs.replies[0]
+	rs.replies = rs.replies[1:]
+	return r, true
+}
+
+// discard deletes every reply left in the set
+func (rs *replySet) discard(session session.Session) {
+	for {
+		r, ok := rs.pop()
+		if !ok {
+			return
+		}
+		session.ChannelMessageDelete(rs.channelID, r.id)
+	}
+}
@@ -0,0 +1,94 @@
+package multiplexer
+
+import (
+	"testing"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/bwmarrin/discordgo"
+)
+
+type pingCommand struct{}
+
+func (pingCommand) Init(m *Mux)                  {}
+func (pingCommand) Handle(ctx *Context)          { ctx.ChannelSend("pong") }
+func (pingCommand) HandleHelp(ctx *Context) bool { return false }
+func (pingCommand) Settings() *CommandSettings {
+	return &CommandSettings{Command: "ping", HelpText: "Pong"}
+}
+
+// newTestMux creates a multiplexer with the ping command, and a fake session
+// with a guild and channel for it to be used in
+func newTestMux(t *testing.T) (*Mux, *session.Fake) {
+	m, err := New("!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Register(pingCommand{})
+	m.Initialize()
+
+	f := session.NewFake(&discordgo.User{ID: "1", Username: "bot", Bot: true})
+	f.AddGuild(&discordgo.Guild{
+		ID:       "g",
+		Channels: []*discordgo.Channel{{ID: "c", GuildID: "g"}},
+	})
+	f.AddUser(&discordgo.User{ID: "2", Username: "user"})
+	return m, f
+}
+
+// send sends a message from the user, and waits for it to be handled
+func send(m *Mux, f *session.Fake, content string) *discordgo.Message {
+	msg := f.AddMessage(&discordgo.Message{
+		ChannelID: "c", GuildID: "g", Content: content,
+		Author: &discordgo.User{ID: "2", Username: "user"},
+	})
+	/* Gateway events are copies, which edits to the message don't change */
+	copied := *msg
+	m.HandleMessage(f, &discordgo.MessageCreate{Message: &copied})
+	m.Wait()
+	return msg
+}
+
+// edit edits a message from the user, and waits for it to be handled
+func edit(m *Mux, f *session.Fake, msg *discordgo.Message, content string) {
+	edited, _ := f.UpdateMessage(msg.ChannelID, msg.ID, content)
+	m.HandleEvent(f, &discordgo.MessageUpdate{Message: edited})
+	m.Wait()
+}
+
+func TestEditUnknownCommand(t *testing.T) {
+	m, f := newTestMux(t)
+
+	msg := send(m, f, "!pnig")
+	actions := f.Actions()
+	if len(actions) != 1 || actions[0].Message.Content != "Command not found." {
+		t.Fatalf("unknown command sent %+v", actions)
+	}
+	notFound := actions[0].MessageID
+
+	f.ClearActions()
+	edit(m, f, msg, "!ping")
+
+	actions = f.Actions()
+	if len(actions) != 1 {
+		t.Fatalf("fixing the command made %d actions, want 1: %+v", len(actions), actions)
+	}
+	if actions[0].Type != session.ActionEdit || actions[0].MessageID != notFound ||
+		actions[0].Message.Content != "pong" {
+		t.Errorf("fixing the command didn't replace the reply: %+v", actions[0])
+	}
+}
+
+func TestDeleteUnknownCommand(t *testing.T) {
+	m, f := newTestMux(t)
+
+	msg := send(m, f, "!pnig")
+	f.ClearActions()
+
+	m.HandleEvent(f, &discordgo.MessageDelete{Message: msg})
+	m.Wait()
+
+	actions := f.Actions()
+	if len(actions) != 1 || actions[0].Type != session.ActionDelete {
+		t.Errorf("deleting the command didn't delete the reply: %+v", actions)
+	}
+}
@@ -0,0 +1,206 @@
+package multiplexer
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/util"
+	"github.com/bwmarrin/discordgo"
+)
+
+var (
+	// ErrMessageNotFound is returned when a target message doesn't exist
+	ErrMessageNotFound = errors.New("that message doesn't exist")
+	// ErrMessageForbidden is returned when the bot can't see a target message
+	ErrMessageForbidden = errors.New("I don't have access to that message")
+	// ErrNoTargetMessage is returned when there is no message to target
+	ErrNoTargetMessage = errors.New("there's no message to use")
+)
+
+// messageTypeReply is the type of messages sent as replies, which DiscordGo
+// doesn't define yet.
+const messageTypeReply discordgo.MessageType = 19
+
+// historyLimit is how many messages back to look for the previous message
+const historyLimit = 10
+
+// TargetMessage resolves the message a command should act on. In order of
+// priority, this is:
+//
+//  1. The message the command is replying to
+//  2. The message linked in the argument (in any channel of the guild)
+//  3. The message with the ID in the argument (in the current channel)
+//  4. The previous message sent by someone other than a bot
+//
+// An empty argument skips straight to the previous message. Errors returned
+// are safe to show to the user.
+func (ctx *Context) TargetMessage(arg string) (*discordgo.Message, error) {
+	if ref := ctx.Message.MessageReference; ref != nil &&
+		len(ref.MessageID) != 0 && ctx.Message.Type == messageTypeReply {
+		return ctx.fetchMessage(ref.ChannelID, ref.MessageID)
+	}
+
+	if guildID, channelID, messageID, ok := util.ParseMsgURL(arg); ok {
+		/* Don't let links leak messages from other guilds, or channels the
+		user can't see */
+		if guildID != ctx.Message.GuildID || !ctx.canRead(channelID) {
+			return nil, ErrMessageForbidden
+		}
+		return ctx.fetchMessage(channelID, messageID)
+	}
+
+	if util.IsID(arg) {
+		return ctx.fetchMessage(ctx.Message.ChannelID, arg)
+	}
+
+	if len(arg) != 0 {
+		return nil, fmt.Errorf(
+			"'%s' doesn't look like a message ID or link", arg,
+		)
+	}
+
+	messages, err := ctx.Session.ChannelMessages(
+		ctx.Message.ChannelID, historyLimit, ctx.Message.ID, "", "",
+	)
+	if err != nil {
+		return nil, messageError(err)
+	}
+
+	for _, msg := range messages {
+		if !msg.Author.Bot {
+			return msg, nil
+		}
+	}
+
+	return nil, ErrNoTargetMessage
+}
+
+// IsTargeting checks if the argument (or the message itself, if it's a reply)
+// refers to a target message which can be resolved with TargetMessage().
+func (ctx *Context) IsTargeting(arg string) bool {
+	if ctx.Message.Type == messageTypeReply &&
+		ctx.Message.MessageReference != nil {
+		return true
+	}
+
+	_, _, _, ok := util.ParseMsgURL(arg)
+	return ok || util.IsID(arg)
+}
+
+// canRead checks that the channel is in the current guild, and that the user
+// can read its history. Anything which can't be looked up is unreadable.
+func (ctx *Context) canRead(channelID string) bool {
+	if channelID == ctx.Message.ChannelID {
+		return true
+	}
+
+	ch, err := ctx.Session.State().Channel(channelID)
+	if err != nil {
+		if ch, err = ctx.Session.Channel(channelID); err != nil {
+			return false
+		}
+	}
+
+	if ch.GuildID != ctx.Message.GuildID {
+		return false
+	}
+
+	guild, err := ctx.Session.State().Guild(ch.GuildID)
+	if err != nil {
+		if guild, err = ctx.Session.Guild(ch.GuildID); err != nil {
+			return false
+		}
+	}
+
+	if ctx.Message.Author.ID == guild.OwnerID {
+		return true
+	}
+
+	member, err := ctx.Member()
+	if err != nil {
+		return false
+	}
+
+	need := discordgo.PermissionViewChannel | discordgo.PermissionReadMessageHistory
+	return channelPermissions(guild, ch, member)&need == need
+}
+
+// channelPermissions works out the member's permissions in the channel, from
+// their roles and the channel's overwrites. This is what DiscordGo's state
+// does, but it only works for members which are cached.
+func channelPermissions(
+	guild *discordgo.Guild, ch *discordgo.Channel, member *discordgo.Member,
+) int {
+	roles := make(map[string]bool, len(member.Roles))
+	for _, id := range member.Roles {
+		roles[id] = true
+	}
+
+	/* The @everyone role has the same ID as the guild */
+	perms := 0
+	for _, role := range guild.Roles {
+		if role.ID == guild.ID || roles[role.ID] {
+			perms |= role.Permissions
+		}
+	}
+
+	if perms&discordgo.PermissionAdministrator != 0 {
+		return discordgo.PermissionAll
+	}
+
+	for _, o := range ch.PermissionOverwrites {
+		if o.ID == guild.ID {
+			perms = perms&^o.Deny | o.Allow
+		}
+	}
+
+	/* Role overwrites are combined, then member ones take priority */
+	deny, allow := 0, 0
+	for _, o := range ch.PermissionOverwrites {
+		if o.Type == "role" && roles[o.ID] {
+			deny |= o.Deny
+			allow |= o.Allow
+		}
+	}
+	perms = perms&^deny | allow
+
+	for _, o := range ch.PermissionOverwrites {
+		if o.Type == "member" && member.User != nil && o.ID == member.User.ID {
+			perms = perms&^o.Deny | o.Allow
+		}
+	}
+
+	return perms
+}
+
+func (ctx *Context) fetchMessage(
+	channelID, messageID string,
+) (*discordgo.Message, error) {
+	msg, err := ctx.Session.ChannelMessage(channelID, messageID)
+	if err != nil {
+		return nil, messageError(err)
+	}
+
+	/* Messages fetched over REST don't include the guild */
+	msg.GuildID = ctx.Message.GuildID
+	return msg, nil
+}
+
+// messageError converts errors from Discord into ones which are safe to show
+// to the user.
+func messageError(err error) error {
+	restErr, ok := err.(*discordgo.RESTError)
+	if !ok || restErr.Response == nil {
+		return err
+	}
+
+	switch restErr.Response.StatusCode {
+	case http.StatusNotFound:
+		return ErrMessageNotFound
+	case http.StatusForbidden:
+		return ErrMessageForbidden
+	}
+
+	return err
+}
@@ -0,0 +1,94 @@
+package multiplexer
+
+import (
+	"testing"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/bwmarrin/discordgo"
+)
+
+/* Message links only match snowflake IDs */
+const (
+	linkGuild     = "80351110224678912"
+	linkOther     = "80351110224678913"
+	linkPublic    = "81384788765712384"
+	linkHidden    = "81384788765712385"
+	linkElsewhere = "81384788765712386"
+	linkMissing   = "81384788765712387"
+	linkStaff     = "81384788765712388"
+	linkMessage   = "90000000000000001"
+)
+
+// link creates a link to the message in the channel
+func link(guildID, channelID string) string {
+	return "https://discord.com/channels/" + guildID + "/" + channelID + "/" +
+		linkMessage
+}
+
+// newLinkSession creates a guild with a public channel, a channel only the
+// "staff" role can see, and a channel in another guild
+func newLinkSession() *session.Fake {
+	f := session.NewFake(&discordgo.User{ID: "1", Username: "bot", Bot: true})
+	f.AddGuild(&discordgo.Guild{
+		ID: linkGuild, OwnerID: "9",
+		Roles: []*discordgo.Role{
+			{ID: linkGuild, Permissions: discordgo.PermissionViewChannel |
+				discordgo.PermissionReadMessageHistory},
+			{ID: linkStaff},
+		},
+		Channels: []*discordgo.Channel{
+			{ID: "c"},
+			{ID: linkPublic},
+			{ID: linkHidden, PermissionOverwrites: []*discordgo.PermissionOverwrite{
+				{ID: linkGuild, Type: "role", Deny: discordgo.PermissionViewChannel},
+				{ID: linkStaff, Type: "role", Allow: discordgo.PermissionViewChannel},
+			}},
+		},
+		Members: []*discordgo.Member{
+			{User: &discordgo.User{ID: "2", Username: "user"}},
+			{User: &discordgo.User{ID: "3", Username: "staff"}, Roles: []string{linkStaff}},
+		},
+	})
+	f.AddGuild(&discordgo.Guild{
+		ID: linkOther, Channels: []*discordgo.Channel{{ID: linkElsewhere}},
+	})
+
+	for _, ch := range []string{linkPublic, linkHidden, linkElsewhere} {
+		f.AddMessage(&discordgo.Message{
+			ID: linkMessage, ChannelID: ch, Content: "hi",
+			Author: &discordgo.User{ID: "9", Username: "owner"},
+		})
+	}
+	return f
+}
+
+func TestTargetMessageLinks(t *testing.T) {
+	f := newLinkSession()
+
+	tests := []struct {
+		userID, link string
+		err          error
+	}{
+		{"2", link(linkGuild, linkPublic), nil},
+		{"2", link(linkGuild, linkHidden), ErrMessageForbidden},
+		{"3", link(linkGuild, linkHidden), nil},
+		{"9", link(linkGuild, linkHidden), nil},
+		{"2", link(linkOther, linkElsewhere), ErrMessageForbidden},
+		{"2", link(linkGuild, linkMissing), ErrMessageForbidden},
+		/* Someone who isn't in the guild can't read anything */
+		{"4", link(linkGuild, linkPublic), ErrMessageForbidden},
+	}
+
+	for _, tt := range tests {
+		ctx := &Context{Session: f, Message: &discordgo.MessageCreate{
+			Message: &discordgo.Message{
+				ChannelID: "c", GuildID: linkGuild,
+				Author: &discordgo.User{ID: tt.userID},
+			},
+		}}
+
+		if _, err := ctx.TargetMessage(tt.link); err != tt.err {
+			t.Errorf("user %s linking %s got %v, want %v", tt.userID, tt.link, err, tt.err)
+		}
+	}
+}
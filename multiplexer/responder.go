@@ -0,0 +1,189 @@
+package multiplexer
+
+import (
+	"math/rand"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/PulseDevelopmentGroup/0x626f74/util"
+	"github.com/bwmarrin/discordgo"
+)
+
+type (
+	// AutoResponder is a trigger which is matched against ordinary
+	// (non-command) messages. Either Pattern or Keywords must be set.
+	AutoResponder struct {
+		Name string
+
+		// Pattern is matched against the content of the message
+		Pattern *regexp.Regexp
+		// Keywords are matched as whole words, ignoring case
+		Keywords []string
+
+		// ChanIDs and RoleIDs limit the responder to the specified channels
+		// and roles. Empty means everywhere and everyone.
+		ChanIDs, RoleIDs []string
+
+		// Cooldown is the minimum amount of time between two responses
+		Cooldown time.Duration
+		// Chance is the percent chance (1-100) of responding to a match
+		Chance int
+
+		Action ResponderAction
+		// Reply is sent when the action is ActionReply or ActionBoth. It can be
+		// a template, just like the content of a simple command.
+		Reply string
+		// Reaction is added when the action is ActionReact or ActionBoth
+		Reaction string
+
+		keywordRE *regexp.Regexp
+		tmpl      *template.Template
+		last      time.Time
+		mu        sync.Mutex
+	}
+
+	// ResponderAction specifies what an auto-responder does when triggered
+	ResponderAction string
+)
+
+const (
+	// ActionReply replies to the message
+	ActionReply ResponderAction = "reply"
+	// ActionReact reacts to the message
+	ActionReact ResponderAction = "react"
+	// ActionBoth replies and reacts to the message
+	ActionBoth ResponderAction = "both"
+)
+
+// RegisterResponder registers one or more auto-responders to the multiplexer
+func (m *Mux) RegisterResponder(responders ...*AutoResponder) {
+	m.responderMu.Lock()
+	defer m.responderMu.Unlock()
+
+	for _, r := range responders {
+		if len(r.Keywords) != 0 {
+			words := make([]string, len(r.Keywords))
+			for i, w := range r.Keywords {
+				words[i] = regexp.QuoteMeta(w)
+			}
+			r.keywordRE = regexp.MustCompile(
+				`(?i)\b(` + strings.Join(words, "|") + `)\b`,
+			)
+		}
+
+		if IsTemplate(r.Reply) {
+			r.tmpl, _ = ParseTemplate(r.Name, r.Reply)
+		}
+
+		m.responders = append(m.responders, r)
+	}
+}
+
+// ClearResponders unregisters all auto-responders from the multiplexer
+func (m *Mux) ClearResponders() {
+	m.responderMu.Lock()
+	defer m.responderMu.Unlock()
+
+	m.responders = nil
+}
+
+// handleResponders runs a non-command message through the auto-responders.
+// Every matching responder fires (subject to its cooldown and chance).
+func (m *Mux) handleResponders(
+	session session.Session,
+	message *discordgo.MessageCreate,
+) {
+	m.responderMu.RLock()
+	responders := m.responders
+	m.responderMu.RUnlock()
+
+	var roles []string
+	if message.Member != nil {
+		roles = message.Member.Roles
+	}
+
+	for _, r := range responders {
+		if !r.matches(message.Content, message.ChannelID, roles) ||
+			!r.ready() {
+			continue
+		}
+
+		if r.Action == ActionReact || r.Action == ActionBoth {
+			session.MessageReactionAdd(
+				message.ChannelID, message.ID, r.Reaction,
+			)
+		}
+
+		if r.Action == ActionReply || r.Action == ActionBoth {
+			reply := r.Reply
+			ctx := &Context{
+				Prefix:    m.Prefix,
+				Arguments: strings.Fields(message.Content),
+				Session:   session,
+				Message:   message,
+			}
+
+			if r.tmpl != nil {
+				var err error
+				reply, err = ExecuteTemplate(r.tmpl, ctx.templateData())
+				if err != nil {
+					continue
+				}
+			}
+
+			if len(strings.TrimSpace(reply)) != 0 {
+				ctx.ChannelSendComplex(&discordgo.MessageSend{
+					Content: reply, AllowedMentions: userMentions(),
+				})
+			}
+		}
+	}
+}
+
+// matches checks the responder's trigger and scoping against a message
+func (r *AutoResponder) matches(content, chanID string, roleIDs []string) bool {
+	if len(r.ChanIDs) != 0 && !util.ArrayContains(r.ChanIDs, chanID, false) {
+		return false
+	}
+
+	if len(r.RoleIDs) != 0 {
+		found := false
+		for _, id := range roleIDs {
+			if util.ArrayContains(r.RoleIDs, id, false) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if r.Pattern != nil && r.Pattern.MatchString(content) {
+		return true
+	}
+
+	return r.keywordRE != nil && r.keywordRE.MatchString(content)
+}
+
+// ready checks the cooldown and chance of the responder, starting the
+// cooldown if it's going to fire.
+func (r *AutoResponder) ready() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Cooldown > 0 && time.Since(r.last) < r.Cooldown {
+		return false
+	}
+
+	if r.Chance > 0 && r.Chance < 100 && rand.Intn(100) >= r.Chance {
+		return false
+	}
+
+	r.last = time.Now()
+	return true
+}
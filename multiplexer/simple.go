@@ -0,0 +1,248 @@
+package multiplexer
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type (
+	// SimpleCommand contains the content and helptext of a logic-less command.
+	// Simple commands have no support for permissions, and can only mention
+	// users. Content (and each of the Responses) containing template actions
+	// (ie. "{{.User.Mention}}") is executed as a template, see TemplateData for
+	// what's available.
+	SimpleCommand struct {
+		Command, Content, HelpText string
+
+		// Responses is a list of contents to pick from at random. If set,
+		// Content is ignored.
+		Responses []string
+		// Embed is sent along with the content, if set
+		Embed *SimpleEmbed
+		// File is the path of a file (relative to the data directory) which is
+		// uploaded along with the content, if set
+		File string
+
+		// Category groups the command with similar commands in help
+		Category string
+		// Hidden leaves the command out of the list of commands in help
+		Hidden bool
+
+		tmpl      *template.Template
+		responses []*template.Template
+	}
+
+	// SimpleEmbed describes an embed sent by a simple command
+	SimpleEmbed struct {
+		Title       string             `json:"title"`
+		Description string             `json:"description"`
+		URL         string             `json:"url"`
+		Color       int                `json:"color"`
+		Image       string             `json:"image"`
+		Thumbnail   string             `json:"thumbnail"`
+		Fields      []SimpleEmbedField `json:"fields"`
+	}
+
+	// SimpleEmbedField is a single field of a SimpleEmbed
+	SimpleEmbedField struct {
+		Name   string `json:"name"`
+		Value  string `json:"value"`
+		Inline bool   `json:"inline"`
+	}
+)
+
+// CheckSimple checks every registered simple command for problems, such as
+// invalid templates or files which don't exist. Problems don't stop a simple
+// command from being used, but should be reported on startup.
+func (m *Mux) CheckSimple() []error {
+	m.simpleMu.RLock()
+	defer m.simpleMu.RUnlock()
+
+	var (
+		names []string
+		errs  []error
+	)
+	for k := range m.SimpleCommands {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, k := range names {
+		errs = append(errs, m.CheckSimpleCommand(m.SimpleCommands[k])...)
+	}
+
+	return errs
+}
+
+// CheckSimpleCommand checks a simple command for problems, like CheckSimple.
+// The command doesn't need to be registered, so it can be checked first.
+func (m *Mux) CheckSimpleCommand(c SimpleCommand) []error {
+	var errs []error
+	k := strings.ToLower(c.Command)
+
+	for _, content := range append([]string{c.Content}, c.Responses...) {
+		if !IsTemplate(content) {
+			continue
+		}
+		if _, err := ParseTemplate(k, content); err != nil {
+			errs = append(errs, fmt.Errorf("simple command %s: %v", k, err))
+		}
+	}
+
+	if len(c.File) == 0 {
+		return errs
+	}
+
+	path, err := m.simpleFilePath(c.File)
+	if err != nil {
+		return append(errs, fmt.Errorf("simple command %s: %v", k, err))
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return append(errs, fmt.Errorf(
+			"simple command %s: file %s does not exist", k, path,
+		))
+	}
+	if info.IsDir() {
+		errs = append(errs, fmt.Errorf(
+			"simple command %s: %s is a directory", k, path,
+		))
+	}
+
+	return errs
+}
+
+// compile parses the templates of the simple command, if there are any
+func (c *SimpleCommand) compile() {
+	if IsTemplate(c.Content) {
+		c.tmpl, _ = ParseTemplate(c.Command, c.Content)
+	}
+
+	c.responses = make([]*template.Template, len(c.Responses))
+	for i, r := range c.Responses {
+		if IsTemplate(r) {
+			c.responses[i], _ = ParseTemplate(c.Command, r)
+		}
+	}
+}
+
+// handleSimple sends the content, embed and file of a simple command,
+// executing the content as a template if needed.
+func (m *Mux) handleSimple(ctx *Context, simple SimpleCommand) {
+	content, tmpl := simple.Content, simple.tmpl
+	if len(simple.Responses) != 0 {
+		i := rand.Intn(len(simple.Responses))
+		content, tmpl = simple.Responses[i], simple.responses[i]
+	}
+
+	if tmpl != nil {
+		var err error
+		content, err = ExecuteTemplate(tmpl, ctx.templateData())
+		if err != nil {
+			ctx.ChannelSendf(
+				"Unable to run `%s%s`: %s", ctx.Prefix, ctx.Command, err,
+			)
+			return
+		}
+	}
+
+	msg := &discordgo.MessageSend{
+		Content:         strings.TrimSpace(content),
+		Embed:           simple.Embed.build(),
+		AllowedMentions: userMentions(),
+	}
+
+	if len(simple.File) != 0 {
+		path, err := m.simpleFilePath(simple.File)
+		if err != nil {
+			ctx.ChannelSendf("Unable to run `%s%s`: %s", ctx.Prefix, ctx.Command, err)
+			return
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			ctx.ChannelSendf(
+				"Unable to run `%s%s`: the file for this command is missing",
+				ctx.Prefix, ctx.Command,
+			)
+			return
+		}
+		defer file.Close()
+
+		name := filepath.Base(path)
+		msg.Files = []*discordgo.File{{Name: name, Reader: file}}
+
+		/* Show the uploaded file inside the embed instead of above it */
+		if msg.Embed != nil && msg.Embed.Image == nil {
+			msg.Embed.Image = &discordgo.MessageEmbedImage{
+				URL: "attachment://" + name,
+			}
+		}
+	}
+
+	if len(msg.Content) == 0 && msg.Embed == nil && len(msg.Files) == 0 {
+		return
+	}
+
+	ctx.ChannelSendComplex(msg)
+}
+
+// userMentions only allows users to be mentioned, for messages whose content
+// comes from users (ie. tags, and templates using the arguments). Everyone and
+// roles can't be pinged through the bot.
+func userMentions() *discordgo.MessageAllowedMentions {
+	return &discordgo.MessageAllowedMentions{
+		Parse: []discordgo.AllowedMentionType{discordgo.AllowedMentionTypeUsers},
+	}
+}
+
+// simpleFilePath resolves the path of a simple command's file, making sure it
+// doesn't point outside of the data directory.
+func (m *Mux) simpleFilePath(file string) (string, error) {
+	clean := filepath.Clean(file)
+	if filepath.IsAbs(clean) || strings.HasPrefix(clean, "..") {
+		return "", fmt.Errorf("file %s is outside of the data directory", file)
+	}
+
+	return filepath.Join(m.dataDir, clean), nil
+}
+
+// build converts the simple embed into a Discord embed
+func (e *SimpleEmbed) build() *discordgo.MessageEmbed {
+	if e == nil {
+		return nil
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       e.Title,
+		Description: e.Description,
+		URL:         e.URL,
+		Color:       e.Color,
+	}
+
+	if len(e.Image) != 0 {
+		embed.Image = &discordgo.MessageEmbedImage{URL: e.Image}
+	}
+
+	if len(e.Thumbnail) != 0 {
+		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: e.Thumbnail}
+	}
+
+	for _, f := range e.Fields {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   f.Name,
+			Value:  f.Value,
+			Inline: f.Inline,
+		})
+	}
+
+	return embed
+}
@@ -0,0 +1,301 @@
+package multiplexer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+type (
+	// TemplateData is the data simple command templates are executed against.
+	// Only plain values are exposed so templates can't reach the session, the
+	// filesystem or the network.
+	TemplateData struct {
+		User     TemplateUser
+		Mentions []TemplateUser
+		Args     []string
+		Channel  TemplateChannel
+		Guild    TemplateGuild
+	}
+
+	// TemplateUser is a user as seen by a simple command template
+	TemplateUser struct {
+		ID, Username, Mention string
+	}
+
+	// TemplateChannel is a channel as seen by a simple command template
+	TemplateChannel struct {
+		ID, Name, Mention string
+	}
+
+	// TemplateGuild is a guild as seen by a simple command template
+	TemplateGuild struct {
+		ID, Name string
+	}
+
+	/* limitedBuffer errors once more than max bytes are written to it */
+	limitedBuffer struct {
+		bytes.Buffer
+		max int
+	}
+)
+
+const (
+	templateMaxOutput = 2000
+	templateTimeout   = 500 * time.Millisecond
+
+	/* Loop iterations a template can run, across all of its ranges */
+	templateMaxIterations = 10000
+	/* Called at the start of every iteration, see countIterations */
+	templateTickFunc = "tick"
+)
+
+var (
+	errTemplateOutput     = errors.New("template output is too long")
+	errTemplateTimeout    = errors.New("template took too long to execute")
+	errTemplateIterations = errors.New("template loops too many times")
+
+	templateFuncs = template.FuncMap{
+		/* Picks one of the supplied values at random */
+		"choose": func(options ...string) string {
+			if len(options) == 0 {
+				return ""
+			}
+			return options[rand.Intn(len(options))]
+		},
+		/* Returns the nth argument or an empty string if there isn't one */
+		"arg": func(n int, args []string) string {
+			if n < 0 || n >= len(args) {
+				return ""
+			}
+			return args[n]
+		},
+		"now":   time.Now,
+		"join":  strings.Join,
+		"lower": strings.ToLower,
+		"upper": strings.ToUpper,
+	}
+)
+
+// ParseTemplate parses the supplied simple command content into a template.
+// Only a safe subset of the template language is allowed: templates can't
+// define or call other templates, and can only range over the arguments and
+// mentions, one range at a time.
+func ParseTemplate(name, content string) (*template.Template, error) {
+	tmpl, err := template.New(name).
+		Option("missingkey=zero").
+		Funcs(templateFuncs).
+		Parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tmpl.Templates()) > 1 {
+		return nil, fmt.Errorf("templates can't define other templates")
+	}
+
+	if err := checkTemplateNode(tmpl.Tree.Root, false); err != nil {
+		return nil, err
+	}
+	countIterations(tmpl.Tree, tmpl.Tree.Root)
+
+	return tmpl, nil
+}
+
+// IsTemplate checks if the supplied content contains template actions
+func IsTemplate(content string) bool {
+	return strings.Contains(content, "{{")
+}
+
+// ExecuteTemplate runs the supplied template against the data, limiting how
+// long it can run, how many times it can loop and how much it can output.
+// Templates can't be cancelled, so once one times out it's stopped at the
+// start of its next loop iteration.
+func ExecuteTemplate(
+	tmpl *template.Template, data *TemplateData,
+) (string, error) {
+	var iterations, stopped int32
+	tmpl, err := tmpl.Clone()
+	if err != nil {
+		return "", err
+	}
+	tmpl.Funcs(template.FuncMap{
+		templateTickFunc: func() (string, error) {
+			if atomic.LoadInt32(&stopped) != 0 {
+				return "", errTemplateTimeout
+			}
+			if atomic.AddInt32(&iterations, 1) > templateMaxIterations {
+				return "", errTemplateIterations
+			}
+			return "", nil
+		},
+	})
+
+	buf := &limitedBuffer{max: templateMaxOutput}
+	done := make(chan error, 1)
+
+	go func() {
+		done <- tmpl.Execute(buf, data)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	case <-time.After(templateTimeout):
+		atomic.StoreInt32(&stopped, 1)
+		return "", errTemplateTimeout
+	}
+}
+
+// templateData builds the data templates are executed against from the
+// context.
+func (ctx *Context) templateData() *TemplateData {
+	data := &TemplateData{
+		User: TemplateUser{
+			ID:       ctx.Message.Author.ID,
+			Username: ctx.Message.Author.Username,
+			Mention:  ctx.Message.Author.Mention(),
+		},
+		Args: ctx.Arguments,
+		Channel: TemplateChannel{
+			ID:      ctx.Message.ChannelID,
+			Mention: "<#" + ctx.Message.ChannelID + ">",
+		},
+		Guild: TemplateGuild{
+			ID: ctx.Message.GuildID,
+		},
+	}
+
+	for _, u := range ctx.Message.Mentions {
+		data.Mentions = append(data.Mentions, TemplateUser{
+			ID:       u.ID,
+			Username: u.Username,
+			Mention:  u.Mention(),
+		})
+	}
+
+	/* Names come from the state cache, no need for extra API calls */
+	if ch, err := ctx.Session.State().Channel(ctx.Message.ChannelID); err == nil {
+		data.Channel.Name = ch.Name
+	}
+	if g, err := ctx.Session.State().Guild(ctx.Message.GuildID); err == nil {
+		data.Guild.Name = g.Name
+	}
+
+	return data
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.max {
+		return 0, errTemplateOutput
+	}
+	return b.Buffer.Write(p)
+}
+
+/* === Helper Functions === */
+
+// checkTemplateNode walks the template tree rejecting anything which could be
+// used to make a template run forever. Ranges can't be nested, even through
+// "with $", so a template loops at most once per argument for each range.
+func checkTemplateNode(node parse.Node, inRange bool) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, c := range n.Nodes {
+			if err := checkTemplateNode(c, inRange); err != nil {
+				return err
+			}
+		}
+	case *parse.TemplateNode:
+		return fmt.Errorf("templates can't call other templates")
+	case *parse.RangeNode:
+		if inRange {
+			return fmt.Errorf("templates can't range inside a range")
+		}
+		if !isFieldPipe(n.Pipe) {
+			return fmt.Errorf(
+				"templates can only range over .Args or .Mentions",
+			)
+		}
+		return checkBranch(&n.BranchNode, true)
+	case *parse.IfNode:
+		return checkBranch(&n.BranchNode, inRange)
+	case *parse.WithNode:
+		return checkBranch(&n.BranchNode, inRange)
+	}
+
+	return nil
+}
+
+func checkBranch(n *parse.BranchNode, inRange bool) error {
+	if err := checkTemplateNode(n.List, inRange); err != nil {
+		return err
+	}
+	return checkTemplateNode(n.ElseList, inRange)
+}
+
+// countIterations starts the body of every range with a call to the tick
+// function, which ExecuteTemplate uses to count iterations. Templates can't
+// call it themselves, since it isn't defined when they're parsed.
+func countIterations(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			countIterations(tree, c)
+		}
+	case *parse.RangeNode:
+		tick := &parse.ActionNode{
+			NodeType: parse.NodeAction,
+			Pos:      n.Pos,
+			Line:     n.Line,
+			Pipe: &parse.PipeNode{
+				NodeType: parse.NodePipe,
+				Pos:      n.Pos,
+				Line:     n.Line,
+				Cmds: []*parse.CommandNode{{
+					NodeType: parse.NodeCommand,
+					Pos:      n.Pos,
+					Args: []parse.Node{
+						parse.NewIdentifier(templateTickFunc).SetTree(tree).SetPos(n.Pos),
+					},
+				}},
+			},
+		}
+		n.List.Nodes = append([]parse.Node{tick}, n.List.Nodes...)
+		countIterations(tree, n.ElseList)
+	case *parse.IfNode:
+		countIterations(tree, n.List)
+		countIterations(tree, n.ElseList)
+	case *parse.WithNode:
+		countIterations(tree, n.List)
+		countIterations(tree, n.ElseList)
+	}
+}
+
+// isFieldPipe checks that a pipeline is just a single .Args or .Mentions field
+func isFieldPipe(pipe *parse.PipeNode) bool {
+	if pipe == nil || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return false
+	}
+
+	field, ok := pipe.Cmds[0].Args[0].(*parse.FieldNode)
+	if !ok || len(field.Ident) != 1 {
+		return false
+	}
+
+	return field.Ident[0] == "Args" || field.Ident[0] == "Mentions"
+}
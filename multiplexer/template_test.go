@@ -0,0 +1,73 @@
+package multiplexer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTemplate(t *testing.T) {
+	tests := []struct {
+		content string
+		ok      bool
+	}{
+		{`Hi {{.User.Mention}}`, true},
+		{`{{range .Args}}{{.}} {{end}}`, true},
+		{`{{range .Args}}{{end}}{{range .Mentions}}{{end}}`, true},
+		{`{{range .Args}}{{if .}}{{upper .}}{{end}}{{end}}`, true},
+		{`{{with .User}}{{range $.Args}}{{end}}{{end}}`, false},
+		{`{{range .Args}}{{range $.Args}}{{end}}{{end}}`, false},
+		{`{{range .Args}}{{with $}}{{range .Args}}{{end}}{{end}}{{end}}`, false},
+		{`{{range .Args}}{{if .}}{{with $}}{{range .Args}}{{end}}{{end}}{{end}}{{end}}`, false},
+		{`{{range .Args}}{{else}}{{range .Args}}{{end}}{{end}}`, false},
+		{`{{range .User.ID}}{{end}}`, false},
+		{`{{define "x"}}{{end}}`, false},
+		{`{{template "x"}}`, false},
+		{`{{tick}}`, false},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseTemplate("test", tt.content)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseTemplate(%q) = %v, want ok %v", tt.content, err, tt.ok)
+		}
+	}
+}
+
+func TestExecuteTemplate(t *testing.T) {
+	tmpl, err := ParseTemplate("test", `{{range .Args}}<{{.}}>{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ExecuteTemplate(tmpl, &TemplateData{Args: []string{"a", "b"}})
+	if err != nil || got != "<a><b>" {
+		t.Errorf("ExecuteTemplate() = %q, %v", got, err)
+	}
+
+	/* Each execution has its own count */
+	loop, err := ParseTemplate("loop", `{{range .Args}}{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := make([]string, templateMaxIterations/2)
+	for i := 0; i < 3; i++ {
+		if _, err := ExecuteTemplate(loop, &TemplateData{Args: args}); err != nil {
+			t.Errorf("run %d: %v", i, err)
+		}
+	}
+}
+
+func TestExecuteTemplateIterations(t *testing.T) {
+	tmpl, err := ParseTemplate(
+		"test", strings.Repeat(`{{range .Args}}{{end}}`, 11),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := make([]string, 1000)
+	_, err = ExecuteTemplate(tmpl, &TemplateData{Args: args})
+	if err == nil || !strings.Contains(err.Error(), errTemplateIterations.Error()) {
+		t.Errorf("looping too much returned %v", err)
+	}
+}
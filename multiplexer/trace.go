@@ -0,0 +1,111 @@
+package multiplexer
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/trace"
+)
+
+// StartSpan starts a span below the stage of the invocation which is running,
+// ie. for a slow part of a command. It must be ended.
+func (ctx *Context) StartSpan(name string) *trace.Span {
+	span := ctx.currentSpan()
+	if span == nil {
+		/* Contexts which aren't traced still need somewhere to record to */
+		return trace.New(name).Root
+	}
+	return span.Child(name)
+}
+
+// HTTPContext returns a context carrying the invocation's trace, so HTTP
+// requests made with it are recorded and carry the invocation ID.
+func (ctx *Context) HTTPContext() context.Context {
+	span := ctx.currentSpan()
+	if span == nil {
+		return context.Background()
+	}
+	return trace.WithSpan(context.Background(), span)
+}
+
+// NewRequest creates an HTTP request which is part of the invocation (see
+// HTTPContext).
+func (ctx *Context) NewRequest(
+	method, url string, body io.Reader,
+) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx.HTTPContext(), method, url, body)
+}
+
+// Get makes a GET request which is part of the invocation, with the default
+// HTTP client.
+func (ctx *Context) Get(url string) (*http.Response, error) {
+	req, err := ctx.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return http.DefaultClient.Do(req)
+}
+
+/* === Helper Functions === */
+
+// startTrace starts tracing the invocation, giving it an ID. Must be called
+// before the context is shared.
+func (ctx *Context) startTrace(t *trace.Trace) {
+	ctx.Trace = t
+	ctx.ID = t.ID
+	ctx.span = t.Root
+	ctx.Session = trace.WrapSession(ctx.Session, ctx.currentSpan)
+}
+
+// stage starts a span for a stage of handling the invocation. API calls made
+// through the context's session are recorded below it until the next stage.
+func (ctx *Context) stage(name string) *trace.Span {
+	if ctx.Trace == nil {
+		return trace.New(name).Root
+	}
+
+	span := ctx.Trace.Root.Child(name)
+
+	ctx.spanMu.Lock()
+	ctx.span = span
+	ctx.spanMu.Unlock()
+	return span
+}
+
+// currentSpan returns the span of the stage which is running
+func (ctx *Context) currentSpan() *trace.Span {
+	ctx.spanMu.Lock()
+	defer ctx.spanMu.Unlock()
+
+	return ctx.span
+}
+
+// endTrace ends the invocation's trace with its outcome
+func (ctx *Context) endTrace(outcome Outcome) {
+	if ctx.Trace == nil {
+		return
+	}
+
+	root := ctx.Trace.Root
+	if outcome != OutcomeNotFound {
+		root.SetName("command " + ctx.Command)
+		root.SetAttribute("command", ctx.Command)
+	}
+	root.SetAttribute("outcome", string(outcome))
+	root.SetAttribute("discord.guild", ctx.Message.GuildID)
+	root.SetAttribute("discord.channel", ctx.Message.ChannelID)
+	root.SetAttribute("discord.user", ctx.Message.Author.ID)
+	switch outcome {
+	case OutcomeError, OutcomePanic:
+		root.Fail(errOutcome(outcome))
+	}
+	root.End()
+}
+
+// errOutcome is an outcome which means the invocation failed
+type errOutcome Outcome
+
+func (e errOutcome) Error() string {
+	return "command ended with " + string(e)
+}
@@ -0,0 +1,211 @@
+package reactor
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/bwmarrin/discordgo"
+)
+
+type (
+	// Paginator flips an embed between pages when users react to it.
+	// Initialized with NewPaginator().
+	Paginator struct {
+		Pages  []*discordgo.MessageEmbed
+		Expiry time.Duration
+
+		current int
+		mu      sync.Mutex
+	}
+)
+
+const (
+	// PreviousPage is the reaction used to go back a page
+	PreviousPage = "⬅️"
+	// NextPage is the reaction used to go forward a page
+	NextPage = "➡️"
+
+	// DefaultPageExpiry is how long pages can be flipped through by default
+	DefaultPageExpiry = 5 * time.Minute
+
+	/* Discord's limits on embeds, with some room to spare for footers */
+	maxFields      = 25
+	maxEmbedLength = 5500
+	maxDescription = 2000
+)
+
+// NewPaginator creates a paginator for the supplied pages, numbering each
+// page in its footer.
+func NewPaginator(
+	pages []*discordgo.MessageEmbed, expiry time.Duration,
+) *Paginator {
+	if expiry == 0 {
+		expiry = DefaultPageExpiry
+	}
+
+	if len(pages) > 1 {
+		for i, p := range pages {
+			text := fmt.Sprintf("Page %d/%d", i+1, len(pages))
+			if p.Footer != nil && len(p.Footer.Text) != 0 {
+				text = p.Footer.Text + " | " + text
+			}
+
+			if p.Footer == nil {
+				p.Footer = &discordgo.MessageEmbedFooter{}
+			}
+			p.Footer.Text = text
+		}
+	}
+
+	return &Paginator{Pages: pages, Expiry: expiry}
+}
+
+// First returns the first page, which should be sent before calling Attach()
+func (p *Paginator) First() *discordgo.MessageEmbed {
+	if len(p.Pages) == 0 {
+		return &discordgo.MessageEmbed{}
+	}
+	return p.Pages[0]
+}
+
+// Attach adds the page flipping reactions to the (already sent) message and
+// watches them with the reactor. Once the pages expire, the reactions are
+// removed. A message which is edited in place (ie. when the command is re-run)
+// only keeps the paginator attached last.
+func (p *Paginator) Attach(
+	r *Reactor, session session.Session, msg *discordgo.Message,
+) {
+	if len(p.Pages) < 2 {
+		/* The message may have had pages before it was edited */
+		r.Unwatch(msg.ID)
+		return
+	}
+
+	expires := time.Now().Add(p.Expiry)
+	r.Replace(msg.ID,
+		Watcher{Trigger: PreviousPage, Handler: p.flip(-1), Time: expires},
+		Watcher{Trigger: NextPage, Handler: p.flip(1), Time: expires},
+	)
+
+	session.MessageReactionAdd(msg.ChannelID, msg.ID, PreviousPage)
+	session.MessageReactionAdd(msg.ChannelID, msg.ID, NextPage)
+
+	r.UnwatchAfter(msg.ID, p.Expiry, func() {
+		session.MessageReactionsRemoveAll(msg.ChannelID, msg.ID)
+	})
+}
+
+// flip returns a handler which moves the supplied number of pages, wrapping
+// around at either end.
+func (p *Paginator) flip(by int) func(ctx *Context) {
+	return func(ctx *Context) {
+		p.mu.Lock()
+		p.current = (p.current + by + len(p.Pages)) % len(p.Pages)
+		page := p.Pages[p.current]
+		p.mu.Unlock()
+
+		ctx.Session.ChannelMessageEditEmbed(
+			ctx.Reaction.ChannelID, ctx.Reaction.MessageID, page,
+		)
+
+		/* Remove the user's reaction so they can flip again (needs manage
+		messages, so errors are ignored) */
+		ctx.Session.MessageReactionRemove(
+			ctx.Reaction.ChannelID, ctx.Reaction.MessageID,
+			ctx.Reaction.Emoji.APIName(), ctx.Reaction.UserID,
+		)
+	}
+}
+
+// PageFields splits fields into pages based on the template embed, with no
+// more than perPage fields on each page. Discord's limits on the number of
+// fields and length of embeds are always respected.
+func PageFields(
+	template discordgo.MessageEmbed,
+	fields []*discordgo.MessageEmbedField,
+	perPage int,
+) []*discordgo.MessageEmbed {
+	if perPage <= 0 || perPage > maxFields {
+		perPage = maxFields
+	}
+
+	var (
+		pages  []*discordgo.MessageEmbed
+		page   = newPage(template)
+		length = embedLength(page)
+	)
+
+	for _, f := range fields {
+		l := len(f.Name) + len(f.Value)
+		if len(page.Fields) == perPage ||
+			(len(page.Fields) != 0 && length+l > maxEmbedLength) {
+			pages = append(pages, page)
+			page = newPage(template)
+			length = embedLength(page)
+		}
+
+		page.Fields = append(page.Fields, f)
+		length += l
+	}
+
+	return append(pages, page)
+}
+
+// PageLines splits lines of text into pages based on the template embed, with
+// no more than perPage lines in each page's description.
+func PageLines(
+	template discordgo.MessageEmbed, lines []string, perPage int,
+) []*discordgo.MessageEmbed {
+	var (
+		pages []*discordgo.MessageEmbed
+		page  []string
+		size  = len(template.Description)
+	)
+
+	flush := func() {
+		p := newPage(template)
+		p.Description = strings.TrimSpace(
+			template.Description + "\n" + strings.Join(page, "\n"),
+		)
+		pages = append(pages, p)
+		page, size = nil, len(template.Description)
+	}
+
+	for _, l := range lines {
+		if len(page) != 0 &&
+			((perPage > 0 && len(page) == perPage) ||
+				size+len(l)+1 > maxDescription) {
+			flush()
+		}
+
+		page = append(page, l)
+		size += len(l) + 1
+	}
+	flush()
+
+	return pages
+}
+
+func newPage(template discordgo.MessageEmbed) *discordgo.MessageEmbed {
+	page := template
+	page.Fields = nil
+	if template.Footer != nil {
+		footer := *template.Footer
+		page.Footer = &footer
+	}
+	return &page
+}
+
+func embedLength(e *discordgo.MessageEmbed) int {
+	l := len(e.Title) + len(e.Description)
+	if e.Footer != nil {
+		l += len(e.Footer.Text)
+	}
+	if e.Author != nil {
+		l += len(e.Author.Name)
+	}
+	return l
+}
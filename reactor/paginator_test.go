@@ -0,0 +1,77 @@
+package reactor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/bwmarrin/discordgo"
+)
+
+func pages(titles ...string) []*discordgo.MessageEmbed {
+	var out []*discordgo.MessageEmbed
+	for _, t := range titles {
+		out = append(out, &discordgo.MessageEmbed{Title: t})
+	}
+	return out
+}
+
+func TestAttachReplaces(t *testing.T) {
+	f := session.NewFake(&discordgo.User{ID: "1", Username: "bot", Bot: true})
+	f.AddChannel(&discordgo.Channel{ID: "c", GuildID: "g"})
+	msg := f.AddMessage(&discordgo.Message{
+		ChannelID: "c", Author: &discordgo.User{ID: "1"},
+	})
+
+	r := New(nil)
+
+	/* The first paginator would expire first, if it wasn't replaced */
+	NewPaginator(pages("a1", "a2"), 50*time.Millisecond).Attach(r, f, msg)
+	NewPaginator(pages("b1", "b2", "b3"), time.Hour).Attach(r, f, msg)
+
+	f.ClearActions()
+	r.Handle(f, &discordgo.MessageReactionAdd{MessageReaction: &discordgo.MessageReaction{
+		UserID: "2", MessageID: msg.ID, ChannelID: "c",
+		Emoji: discordgo.Emoji{Name: NextPage},
+	}})
+
+	var edits []string
+	for _, a := range f.Actions() {
+		if a.Type == session.ActionEdit {
+			edits = append(edits, a.Message.Embeds[0].Title)
+		}
+	}
+	if len(edits) != 1 || edits[0] != "b2" {
+		t.Fatalf("flipping edited the message to %v, want [b2]", edits)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if r.Size() != 1 {
+		t.Error("the replaced paginator's expiry unwatched the message")
+	}
+
+	/* Pages which no longer need flipping stop being watched */
+	NewPaginator(pages("c1"), time.Hour).Attach(r, f, msg)
+	if r.Size() != 0 {
+		t.Error("a single page is still being watched")
+	}
+}
+
+func TestAttachExpires(t *testing.T) {
+	f := session.NewFake(&discordgo.User{ID: "1", Username: "bot", Bot: true})
+	f.AddChannel(&discordgo.Channel{ID: "c", GuildID: "g"})
+	msg := f.AddMessage(&discordgo.Message{
+		ChannelID: "c", Author: &discordgo.User{ID: "1"},
+	})
+
+	r := New(nil)
+	NewPaginator(pages("a1", "a2"), 20*time.Millisecond).Attach(r, f, msg)
+	if r.Size() != 1 {
+		t.Fatal("the message isn't being watched")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if r.Size() != 0 {
+		t.Error("the message is still watched after the pages expired")
+	}
+}
@@ -2,9 +2,14 @@ package reactor
 
 import (
 	"fmt"
+	"io"
+	"sync"
 	"time"
 
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/PulseDevelopmentGroup/0x626f74/util"
 	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
 )
 
 type (
@@ -12,12 +17,18 @@ type (
 	// Reactor defines an instance of a reaction watcher.
 	Reactor struct {
 		DefaultExpiration *time.Time
+		// Logger logs watchers being added and triggered, if set
+		Logger *logrus.Entry
 
 		watchPool map[string][]Watcher
+		timers    map[string]*time.Timer // Scheduled by UnwatchAfter
+		mu        sync.Mutex
 	}
 
 	// Watcher defines the properties to be watched for a specific message,
-	// and is what makes up the watchPool.
+	// and is what makes up the watchPool. Trigger is the emoji (or
+	// "name:id" for custom emoji) which calls the handler, and Time is when
+	// the watcher expires.
 	Watcher struct {
 		Trigger string
 		Handler func(ctx *Context)
@@ -27,7 +38,7 @@ type (
 	// Context defines the Reactor context, including the emoji used, channelID,
 	// userID, and more.
 	Context struct {
-		Session  *discordgo.Session
+		Session  session.Session
 		Reaction *discordgo.MessageReactionAdd
 	}
 )
@@ -38,19 +49,59 @@ func New(defaultExpiration *time.Time) *Reactor {
 	return &Reactor{
 		DefaultExpiration: defaultExpiration,
 		watchPool:         make(map[string][]Watcher),
+		timers:            make(map[string]*time.Timer),
 	}
 }
 
 // Handle is passed to DiscordGo to handle reaction add events.
 func (r *Reactor) Handle(
-	session *discordgo.Session, reaction *discordgo.MessageReactionAdd,
+	session session.Session, reaction *discordgo.MessageReactionAdd,
 ) {
+	/* Ignore the bot's own reactions */
+	if reaction.UserID == session.State().User.ID {
+		return
+	}
+
+	r.mu.Lock()
+	r.expire(reaction.MessageID)
+	watchers := r.watchPool[reaction.MessageID]
+	r.mu.Unlock()
 
+	ctx := &Context{Session: session, Reaction: reaction}
+	for _, w := range watchers {
+		if w.Trigger == reaction.Emoji.Name ||
+			w.Trigger == reaction.Emoji.APIName() {
+			if r.Logger != nil {
+				r.Logger.WithFields(logrus.Fields{
+					"message": reaction.MessageID,
+					"trigger": w.Trigger,
+					"user":    reaction.UserID,
+				}).Debug("Watcher triggered")
+			}
+			w.Handler(ctx)
+		}
+	}
 }
 
 // Watch is used by commands or other parts of the bot to request a given
 // message be watched for reactions being added to it.
 func (r *Reactor) Watch(messageID string, watchers ...Watcher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Logger != nil {
+		r.Logger.WithFields(logrus.Fields{
+			"message":  messageID,
+			"watchers": len(watchers),
+		}).Debug("Watching message")
+	}
+
+	for i := range watchers {
+		if watchers[i].Time.IsZero() && r.DefaultExpiration != nil {
+			watchers[i].Time = *r.DefaultExpiration
+		}
+	}
+
 	if len(r.watchPool[messageID]) == 0 {
 		r.watchPool[messageID] = watchers
 		return
@@ -59,12 +110,86 @@ func (r *Reactor) Watch(messageID string, watchers ...Watcher) {
 	r.watchPool[messageID] = append(r.watchPool[messageID], watchers...)
 }
 
+// Replace is like Watch, but removes any watchers the message was already
+// being watched with (and cancels any UnwatchAfter) first.
+func (r *Reactor) Replace(messageID string, watchers ...Watcher) {
+	r.Unwatch(messageID)
+	r.Watch(messageID, watchers...)
+}
+
 // Unwatch is used by commands or other parts of the bot to unwatch a specific
-// message or messages.
+// message or messages. Any UnwatchAfter for them is cancelled.
 func (r *Reactor) Unwatch(messageID ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	for _, id := range messageID {
 		delete(r.watchPool, id)
+		if t, ok := r.timers[id]; ok {
+			t.Stop()
+			delete(r.timers, id)
+		}
+	}
+}
+
+// UnwatchAfter unwatches the message once the duration has passed, then calls
+// the function (if there is one). It replaces any UnwatchAfter already
+// scheduled for the message.
+func (r *Reactor) UnwatchAfter(
+	messageID string, d time.Duration, then func(),
+) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if t, ok := r.timers[messageID]; ok {
+		t.Stop()
+	}
+
+	var t *time.Timer
+	t = time.AfterFunc(d, func() {
+		r.mu.Lock()
+		/* A timer which was replaced as it fired mustn't unwatch anything */
+		if r.timers[messageID] != t {
+			r.mu.Unlock()
+			return
+		}
+		delete(r.timers, messageID)
+		delete(r.watchPool, messageID)
+		r.mu.Unlock()
+
+		if then != nil {
+			then()
+		}
+	})
+	r.timers[messageID] = t
+}
+
+// Size returns the number of messages currently being watched
+func (r *Reactor) Size() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id := range r.watchPool {
+		r.expire(id)
+	}
+	return len(r.watchPool)
+}
+
+// expire removes the expired watchers of a message. Must be called with the
+// lock held.
+func (r *Reactor) expire(messageID string) {
+	var active []Watcher
+	for _, w := range r.watchPool[messageID] {
+		if w.Time.IsZero() || time.Now().Before(w.Time) {
+			active = append(active, w)
+		}
+	}
+
+	if len(active) == 0 {
+		delete(r.watchPool, messageID)
+		return
 	}
+	r.watchPool[messageID] = active
 }
 
 // ChannelSend is a helper function for easily sending a message to the current
@@ -84,3 +209,71 @@ func (ctx *Context) ChannelSendf(
 		ctx.Reaction.ChannelID, fmt.Sprintf(format, a...),
 	)
 }
+
+// ChannelSendLong is a helper function like ChannelSend for sending messages
+// which may be longer than Discord allows (this is a duplicate of the
+// ChannelSendLong function in the multiplexer).
+func (ctx *Context) ChannelSendLong(message string) ([]*discordgo.Message, error) {
+	var sent []*discordgo.Message
+	for _, chunk := range util.SplitMessage(message, util.MaxMessageLength) {
+		msg, err := ctx.ChannelSend(chunk)
+		if err != nil {
+			return sent, err
+		}
+		sent = append(sent, msg)
+	}
+
+	return sent, nil
+}
+
+// Reply is a helper function for sending a message to the current channel as
+// a reply to the message which was reacted to.
+func (ctx *Context) Reply(message string) (*discordgo.Message, error) {
+	return util.SendReply(
+		ctx.Session, ctx.Reaction.ChannelID,
+		&discordgo.MessageSend{Content: message},
+		&discordgo.MessageReference{
+			MessageID: ctx.Reaction.MessageID,
+			ChannelID: ctx.Reaction.ChannelID,
+			GuildID:   ctx.Reaction.GuildID,
+		},
+	)
+}
+
+// EmbedSend is a helper function for sending an embed to the current channel,
+// styled like the rest of the bot's embeds (this is a duplicate of the
+// EmbedSend function in the multiplexer).
+func (ctx *Context) EmbedSend(
+	embed *discordgo.MessageEmbed,
+) (*discordgo.Message, error) {
+	return ctx.Session.ChannelMessageSendEmbed(
+		ctx.Reaction.ChannelID, util.StyleEmbed(embed),
+	)
+}
+
+// FileSend is a helper function for sending a file to the current channel
+// (this is a duplicate of the FileSend function in the multiplexer).
+func (ctx *Context) FileSend(
+	name string,
+	r io.Reader,
+) (*discordgo.Message, error) {
+	return ctx.Session.ChannelFileSend(ctx.Reaction.ChannelID, name, r)
+}
+
+// DMSend is a helper function for sending a direct message to the user who
+// reacted. If they don't accept direct messages, they're told to allow them
+// (without the message being shown), and the error is returned.
+func (ctx *Context) DMSend(message string) (*discordgo.Message, error) {
+	sent, err := util.SendDM(
+		ctx.Session, ctx.Reaction.UserID,
+		&discordgo.MessageSend{Content: message},
+	)
+	if err != nil {
+		notice, _ := ctx.ChannelSendf(
+			"<@%s>, %s", ctx.Reaction.UserID, util.DMFallback,
+		)
+		return notice, err
+	}
+
+	return sent, nil
+}
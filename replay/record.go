@@ -0,0 +1,489 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/bwmarrin/discordgo"
+)
+
+type (
+	// Recorder writes events, and the bot's actions in response to them, to a
+	// JSONL transcript. IDs are replaced with stable fake IDs, usernames with
+	// pseudonyms, and the content of messages which aren't commands is
+	// removed. Initialized with NewRecorder().
+	Recorder struct {
+		// KeepContent keeps the content of messages which aren't commands, so
+		// auto-responders can be replayed.
+		KeepContent bool
+
+		w      io.Writer
+		prefix string
+		ids    map[string]string
+		names  map[string]string
+		mu     sync.Mutex
+	}
+
+	// recordingSession records the actions taken through a session
+	recordingSession struct {
+		session.Session
+		r *Recorder
+	}
+)
+
+/* Fake IDs start here, so they're still valid snowflakes */
+const firstID = 100000000000000000
+
+var idRE = regexp.MustCompile(`\b\d{17,20}\b`)
+
+// NewRecorder creates a recorder which writes to w. Messages starting with the
+// prefix are commands, and keep their content.
+func NewRecorder(w io.Writer, prefix string) *Recorder {
+	return &Recorder{
+		w:      w,
+		prefix: prefix,
+		ids:    make(map[string]string),
+		names:  make(map[string]string),
+	}
+}
+
+// Event records an event received from Discord. Events which don't affect the
+// bot are ignored.
+func (r *Recorder) Event(event interface{}) error {
+	e, ok := entryFor(event)
+	if !ok {
+		return nil
+	}
+	return r.record(e)
+}
+
+// Session wraps a session, so that everything the bot does through it is
+// recorded.
+func (r *Recorder) Session(s session.Session) session.Session {
+	return recordingSession{s, r}
+}
+
+/* === Recording Session === */
+
+func (s recordingSession) UpdateStatusComplex(
+	usd discordgo.UpdateStatusData,
+) error {
+	err := s.Session.UpdateStatusComplex(usd)
+	if err == nil {
+		s.r.action(session.Action{Type: session.ActionStatus, Status: &usd})
+	}
+	return err
+}
+
+func (s recordingSession) ChannelTyping(channelID string) error {
+	err := s.Session.ChannelTyping(channelID)
+	if err == nil {
+		s.r.action(session.Action{
+			Type: session.ActionTyping, ChannelID: channelID,
+		})
+	}
+	return err
+}
+
+func (s recordingSession) ChannelMessageSend(
+	channelID string, content string,
+) (*discordgo.Message, error) {
+	return s.ChannelMessageSendComplex(
+		channelID, &discordgo.MessageSend{Content: content},
+	)
+}
+
+func (s recordingSession) ChannelMessageSendEmbed(
+	channelID string, embed *discordgo.MessageEmbed,
+) (*discordgo.Message, error) {
+	return s.ChannelMessageSendComplex(
+		channelID, &discordgo.MessageSend{Embed: embed},
+	)
+}
+
+func (s recordingSession) ChannelMessageSendComplex(
+	channelID string, data *discordgo.MessageSend,
+) (*discordgo.Message, error) {
+	data, files, err := readFiles(data)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := s.Session.ChannelMessageSendComplex(channelID, data)
+	s.r.sent(m, files, err)
+	return m, err
+}
+
+func (s recordingSession) ChannelMessageSendReply(
+	channelID string,
+	data *discordgo.MessageSend,
+	ref *discordgo.MessageReference,
+) (*discordgo.Message, error) {
+	m, err := s.Session.ChannelMessageSendReply(channelID, data, ref)
+	s.r.sent(m, nil, err)
+	return m, err
+}
+
+func (s recordingSession) ChannelMessageEditComplex(
+	edit *discordgo.MessageEdit,
+) (*discordgo.Message, error) {
+	m, err := s.Session.ChannelMessageEditComplex(edit)
+	if err == nil {
+		s.r.action(session.Action{
+			Type:      session.ActionEdit,
+			GuildID:   m.GuildID,
+			ChannelID: m.ChannelID,
+			MessageID: m.ID,
+			Message:   m,
+		})
+	}
+	return m, err
+}
+
+func (s recordingSession) ChannelMessageEditEmbed(
+	channelID, messageID string, embed *discordgo.MessageEmbed,
+) (*discordgo.Message, error) {
+	return s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID: messageID, Channel: channelID, Embed: embed,
+	})
+}
+
+func (s recordingSession) ChannelMessageDelete(
+	channelID, messageID string,
+) error {
+	err := s.Session.ChannelMessageDelete(channelID, messageID)
+	if err == nil {
+		s.r.action(session.Action{
+			Type: session.ActionDelete, ChannelID: channelID, MessageID: messageID,
+		})
+	}
+	return err
+}
+
+func (s recordingSession) ChannelFileSend(
+	channelID, name string, r io.Reader,
+) (*discordgo.Message, error) {
+	return s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Files: []*discordgo.File{{Name: name, Reader: r}},
+	})
+}
+
+func (s recordingSession) GuildMemberRoleAdd(
+	guildID, userID, roleID string,
+) error {
+	err := s.Session.GuildMemberRoleAdd(guildID, userID, roleID)
+	if err == nil {
+		s.r.action(session.Action{
+			Type: session.ActionRoleAdd, GuildID: guildID, UserID: userID,
+			RoleID: roleID,
+		})
+	}
+	return err
+}
+
+func (s recordingSession) GuildMemberRoleRemove(
+	guildID, userID, roleID string,
+) error {
+	err := s.Session.GuildMemberRoleRemove(guildID, userID, roleID)
+	if err == nil {
+		s.r.action(session.Action{
+			Type: session.ActionRoleRemove, GuildID: guildID, UserID: userID,
+			RoleID: roleID,
+		})
+	}
+	return err
+}
+
+func (s recordingSession) MessageReactionAdd(
+	channelID, messageID, emojiID string,
+) error {
+	err := s.Session.MessageReactionAdd(channelID, messageID, emojiID)
+	if err == nil {
+		s.r.action(session.Action{
+			Type: session.ActionReact, ChannelID: channelID,
+			MessageID: messageID, UserID: s.State().User.ID, Emoji: emojiID,
+		})
+	}
+	return err
+}
+
+func (s recordingSession) MessageReactionRemove(
+	channelID, messageID, emojiID, userID string,
+) error {
+	err := s.Session.MessageReactionRemove(
+		channelID, messageID, emojiID, userID,
+	)
+	if err == nil {
+		s.r.action(session.Action{
+			Type: session.ActionUnreact, ChannelID: channelID,
+			MessageID: messageID, UserID: userID, Emoji: emojiID,
+		})
+	}
+	return err
+}
+
+func (s recordingSession) MessageReactionsRemoveAll(
+	channelID, messageID string,
+) error {
+	err := s.Session.MessageReactionsRemoveAll(channelID, messageID)
+	if err == nil {
+		s.r.action(session.Action{
+			Type: session.ActionUnreactAll, ChannelID: channelID,
+			MessageID: messageID,
+		})
+	}
+	return err
+}
+
+/* === Helper Functions === */
+
+// sent records a message sent by the bot, if it was sent successfully
+func (r *Recorder) sent(m *discordgo.Message, files []session.File, err error) {
+	if err != nil {
+		return
+	}
+
+	r.action(session.Action{
+		Type:      session.ActionSend,
+		GuildID:   m.GuildID,
+		ChannelID: m.ChannelID,
+		MessageID: m.ID,
+		Message:   m,
+		Files:     files,
+	})
+}
+
+// action records an action. Actions can't fail to be recorded, so errors
+// writing the transcript are dropped.
+func (r *Recorder) action(a session.Action) {
+	r.record(Entry{Type: EntryAction, Action: &a})
+}
+
+// record scrubs an entry and writes it to the transcript
+func (r *Recorder) record(e Entry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	/* Scrub a copy, since events are shared with the rest of the bot. Only
+	its fields are scrubbed, so the JSON around them is left alone. */
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	var scrubbed Entry
+	if err := json.Unmarshal(b, &scrubbed); err != nil {
+		return err
+	}
+	r.scrub(&scrubbed)
+
+	b, err = json.Marshal(scrubbed)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.w.Write(append(r.scrubIDs(b), '\n'))
+	return err
+}
+
+// scrub removes personal information from an entry
+func (r *Recorder) scrub(e *Entry) {
+	r.scrubUser(e.User)
+	r.scrubMember(e.Member)
+	r.scrubMessage(e.Message)
+
+	if g := e.Guild; g != nil {
+		g.Name = "guild"
+		g.Icon, g.Splash = "", ""
+		g.Members, g.Presences, g.VoiceStates, g.Emojis = nil, nil, nil, nil
+	}
+
+	if e.Action != nil {
+		r.scrubMessage(e.Action.Message)
+	}
+}
+
+func (r *Recorder) scrubMessage(m *discordgo.Message) {
+	if m == nil {
+		return
+	}
+
+	r.scrubUser(m.Author)
+	r.scrubMember(m.Member)
+	for _, u := range m.Mentions {
+		r.scrubUser(u)
+	}
+
+	for _, a := range m.Attachments {
+		a.URL = "https://cdn.invalid/attachments/" + a.Filename
+		a.ProxyURL = a.URL
+	}
+
+	/* Names are scrubbed from what the bot sends, and from what's kept of
+	what everyone else sends */
+	if m.Author == nil || m.Author.Bot {
+		m.Content = r.scrubNames(m.Content)
+		for _, e := range m.Embeds {
+			r.scrubEmbed(e)
+		}
+		return
+	}
+
+	m.Embeds = nil
+	if !r.KeepContent && !strings.HasPrefix(m.Content, r.prefix) &&
+		len(m.Content) != 0 {
+		m.Content = "[scrubbed]"
+	}
+	m.Content = r.scrubNames(m.Content)
+}
+
+func (r *Recorder) scrubEmbed(e *discordgo.MessageEmbed) {
+	e.Title = r.scrubNames(e.Title)
+	e.Description = r.scrubNames(e.Description)
+	for _, f := range e.Fields {
+		f.Name = r.scrubNames(f.Name)
+		f.Value = r.scrubNames(f.Value)
+	}
+	if e.Author != nil {
+		e.Author.Name = r.scrubNames(e.Author.Name)
+		e.Author.IconURL, e.Author.ProxyIconURL = "", ""
+	}
+	if e.Footer != nil {
+		e.Footer.Text = r.scrubNames(e.Footer.Text)
+	}
+}
+
+func (r *Recorder) scrubMember(m *discordgo.Member) {
+	if m == nil {
+		return
+	}
+
+	m.Nick = ""
+	r.scrubUser(m.User)
+}
+
+func (r *Recorder) scrubUser(u *discordgo.User) {
+	if u == nil {
+		return
+	}
+
+	u.Email, u.Avatar, u.Token = "", "", ""
+	if u.Bot {
+		return
+	}
+
+	u.Discriminator = "0000"
+	if len(u.Username) == 0 {
+		return
+	}
+
+	if _, ok := r.names[u.Username]; !ok {
+		r.names[u.Username] = fmt.Sprintf("user-%d", len(r.names)+1)
+	}
+	u.Username = r.names[u.Username]
+}
+
+// scrubNames replaces the usernames which have been scrubbed wherever they
+// appear as whole words in text, ie. the content of messages
+func (r *Recorder) scrubNames(text string) string {
+	if len(text) == 0 {
+		return text
+	}
+
+	/* Replace longer names first, in case one contains another */
+	names := make([]string, 0, len(r.names))
+	for name := range r.names {
+		if len(name) >= 3 {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+
+	for _, name := range names {
+		text = replaceWord(text, name, r.names[name])
+	}
+	return text
+}
+
+// scrubIDs replaces IDs wherever they appear, including in the content of
+// messages
+func (r *Recorder) scrubIDs(b []byte) []byte {
+	return idRE.ReplaceAllFunc(b, func(id []byte) []byte {
+		fake, ok := r.ids[string(id)]
+		if !ok {
+			fake = strconv.Itoa(firstID + len(r.ids))
+			r.ids[string(id)] = fake
+		}
+		return []byte(fake)
+	})
+}
+
+// replaceWord replaces old with new wherever it isn't part of a longer word
+func replaceWord(s, old, new string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, old)
+		if i == -1 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := i + len(old)
+
+		before, _ := utf8.DecodeLastRuneInString(s[:i])
+		after, _ := utf8.DecodeRuneInString(s[end:])
+		b.WriteString(s[:i])
+		if isWordRune(before) || isWordRune(after) {
+			b.WriteString(old)
+		} else {
+			b.WriteString(new)
+		}
+		s = s[end:]
+	}
+}
+
+// isWordRune checks if a rune can be part of a word
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// readFiles reads the files of a message into memory, so they can be both sent
+// and recorded.
+func readFiles(
+	data *discordgo.MessageSend,
+) (*discordgo.MessageSend, []session.File, error) {
+	files := data.Files
+	if data.File != nil {
+		files = append(files, data.File)
+	}
+	if len(files) == 0 {
+		return data, nil, nil
+	}
+
+	send := *data
+	send.File, send.Files = nil, nil
+
+	var recorded []session.File
+	for _, f := range files {
+		b, err := ioutil.ReadAll(f.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		send.Files = append(send.Files, &discordgo.File{
+			Name: f.Name, ContentType: f.ContentType, Reader: bytes.NewReader(b),
+		})
+		recorded = append(recorded, session.File{Name: f.Name, Size: len(b)})
+	}
+
+	return &send, recorded, nil
+}
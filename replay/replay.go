@@ -0,0 +1,277 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/console"
+	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/bwmarrin/discordgo"
+)
+
+type (
+	// Result is the outcome of replaying a transcript
+	Result struct {
+		// Actions are what the bot did during the replay
+		Actions []session.Action
+		// Recorded are the actions in the transcript, what the bot did when it
+		// was recorded
+		Recorded []session.Action
+	}
+
+	// player feeds transcript entries through the multiplexer
+	player struct {
+		mux  *multiplexer.Mux
+		fake *session.Fake
+
+		/* IDs of messages the bot sent when recording, and when replaying */
+		recorded, replayed []string
+	}
+)
+
+/* The bot's user, if the transcript doesn't start with a ready entry */
+var defaultBot = &discordgo.User{
+	ID: "100000000000000000", Username: "bot", Bot: true,
+}
+
+// Replay feeds a transcript through the multiplexer, using a fake session.
+// Each entry is fully handled before the next, so the bot's actions are in a
+// predictable order.
+func Replay(mux *multiplexer.Mux, r io.Reader) (*Result, error) {
+	p := &player{mux: mux}
+	result := &Result{}
+
+	dec := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		var e Entry
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", line, err)
+		}
+
+		if e.Type == EntryAction {
+			if e.Action == nil {
+				return nil, fmt.Errorf("entry %d: action is missing", line)
+			}
+			if e.Action.Type == session.ActionSend {
+				p.recorded = append(p.recorded, e.Action.MessageID)
+			}
+			result.Recorded = append(result.Recorded, *e.Action)
+			continue
+		}
+
+		if err := p.play(e); err != nil {
+			return nil, fmt.Errorf("entry %d: %w", line, err)
+		}
+		mux.Wait()
+		p.sent()
+	}
+
+	if p.fake != nil {
+		result.Actions = p.fake.Actions()
+	}
+	return result, nil
+}
+
+// Render describes actions as text, one or more lines each. IDs are numbered
+// in the order they first appear, since the IDs of messages sent while
+// replaying won't match the recorded ones.
+func Render(actions []session.Action) string {
+	ids := make(map[string]string)
+
+	var b strings.Builder
+	for _, a := range actions {
+		b.WriteString(idRE.ReplaceAllStringFunc(
+			console.Render(a), func(id string) string {
+				if _, ok := ids[id]; !ok {
+					ids[id] = fmt.Sprintf("#%d", len(ids)+1)
+				}
+				return ids[id]
+			},
+		))
+		b.WriteByte('\n')
+	}
+
+	return b.String()
+}
+
+// Diff compares two renderings line by line. Lines only in want are prefixed
+// with "-", and lines only in got with "+". An empty string means they match.
+func Diff(want, got string) string {
+	if want == got {
+		return ""
+	}
+
+	a := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+
+	/* Longest common subsequence, from the end of both */
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			out.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			out.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+
+	return out.String()
+}
+
+/* === Helper Functions === */
+
+// play feeds an event entry through the multiplexer
+func (p *player) play(e Entry) error {
+	if p.fake == nil {
+		bot := defaultBot
+		if e.Type == EntryReady && e.User != nil {
+			bot = e.User
+		}
+		p.fake = session.NewFake(bot)
+	}
+
+	switch e.Type {
+	case EntryReady:
+		p.mux.HandleEvent(p.fake, &discordgo.Ready{User: p.fake.State().User})
+	case EntryGuild:
+		if e.Guild == nil {
+			return fmt.Errorf("guild is missing")
+		}
+		if err := p.fake.AddGuild(e.Guild); err != nil {
+			return err
+		}
+		p.mux.HandleEvent(p.fake, &discordgo.GuildCreate{Guild: e.Guild})
+	case EntryMessage:
+		return p.message(e.Message)
+	case EntryMessageUpdate:
+		if e.Message == nil {
+			return fmt.Errorf("message is missing")
+		}
+		e.Message.ID = p.messageID(e.Message.ID)
+		p.fake.UpdateMessage(e.Message.ChannelID, e.Message.ID, e.Message.Content)
+		p.mux.HandleEvent(p.fake, &discordgo.MessageUpdate{Message: e.Message})
+	case EntryMessageDelete:
+		if e.Message == nil {
+			return fmt.Errorf("message is missing")
+		}
+		e.Message.ID = p.messageID(e.Message.ID)
+		p.fake.RemoveMessage(e.Message.ChannelID, e.Message.ID)
+		p.mux.HandleEvent(p.fake, &discordgo.MessageDelete{Message: e.Message})
+	case EntryReactionAdd, EntryReactionRemove:
+		if e.Reaction == nil {
+			return fmt.Errorf("reaction is missing")
+		}
+		e.Reaction.MessageID = p.messageID(e.Reaction.MessageID)
+		if e.Type == EntryReactionAdd {
+			p.mux.HandleEvent(p.fake, &discordgo.MessageReactionAdd{
+				MessageReaction: e.Reaction,
+			})
+			break
+		}
+		p.mux.HandleEvent(p.fake, &discordgo.MessageReactionRemove{
+			MessageReaction: e.Reaction,
+		})
+	case EntryMemberJoin:
+		if e.Member == nil || e.Member.User == nil {
+			return fmt.Errorf("member is missing")
+		}
+		if err := p.fake.AddMember(e.Member); err != nil {
+			return err
+		}
+		p.mux.HandleEvent(p.fake, &discordgo.GuildMemberAdd{Member: e.Member})
+	case EntryMemberLeave:
+		if e.Member == nil || e.Member.User == nil {
+			return fmt.Errorf("member is missing")
+		}
+		p.fake.State().MemberRemove(e.Member)
+		p.mux.HandleEvent(p.fake, &discordgo.GuildMemberRemove{Member: e.Member})
+	default:
+		return fmt.Errorf("unknown entry type %q", e.Type)
+	}
+
+	return nil
+}
+
+// message feeds a message through the multiplexer. The author is added to the
+// guild if they aren't already a member, and the bot's own messages are
+// skipped, since the fake already has the ones it sent while replaying.
+func (p *player) message(m *discordgo.Message) error {
+	if m == nil || m.Author == nil {
+		return fmt.Errorf("message or its author is missing")
+	}
+	if m.Author.ID == p.fake.State().User.ID {
+		return nil
+	}
+
+	if m.Member != nil && len(m.GuildID) != 0 {
+		if _, err := p.fake.State().Member(m.GuildID, m.Author.ID); err != nil {
+			member := *m.Member
+			member.GuildID, member.User = m.GuildID, m.Author
+			if err := p.fake.AddMember(&member); err != nil {
+				return err
+			}
+		}
+	} else {
+		p.fake.AddUser(m.Author)
+	}
+
+	/* The event is a copy, like the gateway's, so that editing the stored
+	message doesn't change the one being handled */
+	copied := *p.fake.AddMessage(m)
+	p.mux.HandleMessage(p.fake, &discordgo.MessageCreate{Message: &copied})
+	return nil
+}
+
+// sent notes the IDs of the messages the bot has sent while replaying
+func (p *player) sent() {
+	actions := p.fake.Actions()
+
+	p.replayed = p.replayed[:0]
+	for _, a := range actions {
+		if a.Type == session.ActionSend {
+			p.replayed = append(p.replayed, a.MessageID)
+		}
+	}
+}
+
+// messageID maps the ID of a message the bot sent while recording to the ID of
+// the same message sent while replaying. Other IDs are returned unchanged.
+func (p *player) messageID(id string) string {
+	for i, recorded := range p.recorded {
+		if recorded == id && i < len(p.replayed) {
+			return p.replayed[i]
+		}
+	}
+	return id
+}
@@ -0,0 +1,252 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+	"github.com/PulseDevelopmentGroup/0x626f74/reactor"
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/bwmarrin/discordgo"
+)
+
+type echoCommand struct{}
+
+func (echoCommand) Init(m *multiplexer.Mux) {}
+func (echoCommand) Handle(ctx *multiplexer.Context) {
+	ctx.ChannelSend(strings.Join(ctx.Arguments, " "))
+}
+func (echoCommand) HandleHelp(ctx *multiplexer.Context) bool { return false }
+func (echoCommand) Settings() *multiplexer.CommandSettings {
+	return &multiplexer.CommandSettings{Command: "echo", HelpText: "Echo"}
+}
+
+// pagesCommand sends an embed with two pages, which are flipped with reactions
+type pagesCommand struct{ react *reactor.Reactor }
+
+func (pagesCommand) Init(m *multiplexer.Mux) {}
+func (c pagesCommand) Handle(ctx *multiplexer.Context) {
+	p := reactor.NewPaginator([]*discordgo.MessageEmbed{
+		{Title: "Page 1"}, {Title: "Page 2"},
+	}, 0)
+	if msg, err := ctx.EmbedSend(p.Pages[0]); err == nil {
+		p.Attach(c.react, ctx.Session, msg)
+	}
+}
+func (pagesCommand) HandleHelp(ctx *multiplexer.Context) bool { return false }
+func (pagesCommand) Settings() *multiplexer.CommandSettings {
+	return &multiplexer.CommandSettings{Command: "pages", HelpText: "Pages"}
+}
+
+// welcome greets members who join, in the guild's first channel
+func welcome(s session.Session, e *discordgo.GuildMemberAdd) {
+	guild, err := s.State().Guild(e.GuildID)
+	if err != nil || len(guild.Channels) == 0 {
+		return
+	}
+	s.ChannelMessageSend(guild.Channels[0].ID, "Welcome "+e.User.Mention()+"!")
+}
+
+var update = flag.Bool("update", false, "Re-record the transcript and golden file")
+
+const (
+	transcriptPath = "testdata/transcript.jsonl"
+	goldenPath     = "testdata/transcript.golden"
+)
+
+/* Real looking IDs, so they're scrubbed */
+const (
+	botID     = "175928847299117063"
+	guildID   = "80351110224678912"
+	channelID = "81384788765712384"
+	aliceID   = "80351110224678913"
+	bobID     = "80351110224678914"
+	carolID   = "80351110224678915"
+)
+
+// newTestMux creates a multiplexer with the echo and pages commands, a simple
+// command, and listeners for reactions and members joining
+func newTestMux(t *testing.T) *multiplexer.Mux {
+	m, err := multiplexer.New("!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	react := reactor.New(nil)
+	m.Register(echoCommand{}, pagesCommand{react})
+	m.RegisterSimple(multiplexer.SimpleCommand{
+		Command: "hello", Content: "Hello!", HelpText: "Say hello",
+	})
+	m.Initialize()
+	m.OnReactionAdd("reactor", react.Handle)
+	m.OnMemberJoin("welcome", welcome)
+	return m
+}
+
+// record runs a conversation through the multiplexer, recording it to w
+func record(t *testing.T, w io.Writer) {
+	m := newTestMux(t)
+	rec := NewRecorder(w, "!")
+
+	bot := &discordgo.User{ID: botID, Username: "bot", Bot: true}
+	fake := session.NewFake(bot)
+	now := time.Date(2020, 8, 1, 12, 0, 0, 0, time.UTC)
+	fake.Now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	s := rec.Session(fake)
+
+	alice := &discordgo.User{ID: aliceID, Username: "alice"}
+	bob := &discordgo.User{ID: bobID, Username: "guild"}
+	guild := &discordgo.Guild{
+		ID: guildID, Name: "Secret Club",
+		Channels: []*discordgo.Channel{{ID: channelID, Name: "general"}},
+		Members:  []*discordgo.Member{{User: alice}, {User: bob}},
+	}
+
+	event := func(e interface{}) {
+		if err := rec.Event(e); err != nil {
+			t.Fatal(err)
+		}
+		if msg, ok := e.(*discordgo.MessageCreate); ok {
+			m.HandleMessage(s, msg)
+		} else {
+			m.HandleEvent(s, e)
+		}
+		m.Wait()
+	}
+	message := func(id string, author *discordgo.User, content string) {
+		msg := fake.AddMessage(&discordgo.Message{
+			ID: id, ChannelID: channelID, GuildID: guildID, Content: content,
+			Author: author, Member: &discordgo.Member{},
+		})
+		copied := *msg
+		event(&discordgo.MessageCreate{Message: &copied})
+	}
+
+	event(&discordgo.Ready{User: bot})
+	fake.AddGuild(guild)
+	event(&discordgo.GuildCreate{Guild: guild})
+
+	message("90000000000000001", alice, "hi guild, it's alice")
+	message("90000000000000002", bob, "!hello")
+	message("90000000000000003", alice, "!echo thanks guild, from alice")
+
+	edited, _ := fake.UpdateMessage(
+		channelID, "90000000000000003", "!echo thanks guild (and guildmates)",
+	)
+	event(&discordgo.MessageUpdate{Message: edited})
+
+	message("90000000000000004", bob, "!ehco typo")
+	edited, _ = fake.UpdateMessage(channelID, "90000000000000004", "!echo fixed")
+	event(&discordgo.MessageUpdate{Message: edited})
+
+	/* Flip to the second page of the bot's reply */
+	message("90000000000000005", alice, "!pages")
+	var pages string
+	for _, a := range fake.Actions() {
+		if a.Type == session.ActionSend {
+			pages = a.MessageID
+		}
+	}
+	event(&discordgo.MessageReactionAdd{MessageReaction: &discordgo.MessageReaction{
+		UserID: aliceID, MessageID: pages, ChannelID: channelID, GuildID: guildID,
+		Emoji: discordgo.Emoji{Name: reactor.NextPage},
+	}})
+
+	carol := &discordgo.Member{
+		GuildID: guildID, User: &discordgo.User{ID: carolID, Username: "carol"},
+	}
+	fake.AddMember(carol)
+	event(&discordgo.GuildMemberAdd{Member: carol})
+}
+
+func TestReplay(t *testing.T) {
+	if *update {
+		var buf bytes.Buffer
+		record(t, &buf)
+		if err := ioutil.WriteFile(transcriptPath, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Open(transcriptPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	result, err := Replay(newTestMux(t), f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Render(result.Actions)
+
+	if *update {
+		if err := ioutil.WriteFile(goldenPath, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := ioutil.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := Diff(string(want), got); len(diff) != 0 {
+		t.Errorf("replayed output doesn't match the golden file:\n%s", diff)
+	}
+	if diff := Diff(Render(result.Recorded), got); len(diff) != 0 {
+		t.Errorf("replayed output doesn't match the recording:\n%s", diff)
+	}
+}
+
+func TestRecordScrubs(t *testing.T) {
+	var buf bytes.Buffer
+	record(t, &buf)
+	transcript := buf.String()
+
+	for _, secret := range []string{
+		"alice", "carol", "Secret Club",
+		botID, guildID, channelID, aliceID, bobID, carolID,
+	} {
+		if strings.Contains(transcript, secret) {
+			t.Errorf("the transcript contains %q", secret)
+		}
+	}
+
+	var messages []string
+	for i, line := range strings.Split(strings.TrimSpace(transcript), "\n") {
+		var e Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("entry %d isn't valid: %v", i+1, err)
+		}
+
+		/* A user named "guild" doesn't break the keys containing it */
+		if e.Message != nil {
+			if len(e.Message.GuildID) == 0 {
+				t.Errorf("entry %d lost its guild ID: %s", i+1, line)
+			}
+			messages = append(messages, e.Message.Content)
+		}
+	}
+
+	want := []string{
+		"[scrubbed]",
+		"!hello",
+		"!echo thanks user-2, from user-1",
+		"!echo thanks user-2 (and guildmates)",
+		"!ehco typo",
+		"!echo fixed",
+		"!pages",
+	}
+	if strings.Join(messages, "\n") != strings.Join(want, "\n") {
+		t.Errorf("the messages were recorded as %q, want %q", messages, want)
+	}
+}
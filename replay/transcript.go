@@ -0,0 +1,81 @@
+package replay
+
+import (
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/bwmarrin/discordgo"
+)
+
+type (
+	// Entry is a line of a transcript: either an event received from Discord,
+	// or an action taken by the bot.
+	Entry struct {
+		Type EntryType `json:"type"`
+
+		// User is the bot's user, for ready entries
+		User *discordgo.User `json:"user,omitempty"`
+		// Guild is set for guild entries
+		Guild *discordgo.Guild `json:"guild,omitempty"`
+		// Message is set for message, message update and message delete
+		// entries
+		Message *discordgo.Message `json:"message,omitempty"`
+		// Reaction is set for reaction entries
+		Reaction *discordgo.MessageReaction `json:"reaction,omitempty"`
+		// Member is set for member join and leave entries
+		Member *discordgo.Member `json:"member,omitempty"`
+		// Action is set for action entries
+		Action *session.Action `json:"action,omitempty"`
+	}
+
+	// EntryType is the type of a transcript entry
+	EntryType string
+)
+
+const (
+	// EntryReady is the bot connecting to Discord
+	EntryReady EntryType = "ready"
+	// EntryGuild is a guild becoming available to the bot
+	EntryGuild EntryType = "guild"
+	// EntryMessage is a message being sent
+	EntryMessage EntryType = "message"
+	// EntryMessageUpdate is a message being edited
+	EntryMessageUpdate EntryType = "messageUpdate"
+	// EntryMessageDelete is a message being deleted
+	EntryMessageDelete EntryType = "messageDelete"
+	// EntryReactionAdd is a reaction being added to a message
+	EntryReactionAdd EntryType = "reactionAdd"
+	// EntryReactionRemove is a reaction being removed from a message
+	EntryReactionRemove EntryType = "reactionRemove"
+	// EntryMemberJoin is a member joining a guild
+	EntryMemberJoin EntryType = "memberJoin"
+	// EntryMemberLeave is a member leaving a guild
+	EntryMemberLeave EntryType = "memberLeave"
+	// EntryAction is something the bot did in response to an event
+	EntryAction EntryType = "action"
+)
+
+// entryFor converts an event from DiscordGo into a transcript entry. Events
+// which aren't recorded return false.
+func entryFor(event interface{}) (Entry, bool) {
+	switch e := event.(type) {
+	case *discordgo.Ready:
+		return Entry{Type: EntryReady, User: e.User}, true
+	case *discordgo.GuildCreate:
+		return Entry{Type: EntryGuild, Guild: e.Guild}, true
+	case *discordgo.MessageCreate:
+		return Entry{Type: EntryMessage, Message: e.Message}, true
+	case *discordgo.MessageUpdate:
+		return Entry{Type: EntryMessageUpdate, Message: e.Message}, true
+	case *discordgo.MessageDelete:
+		return Entry{Type: EntryMessageDelete, Message: e.Message}, true
+	case *discordgo.MessageReactionAdd:
+		return Entry{Type: EntryReactionAdd, Reaction: e.MessageReaction}, true
+	case *discordgo.MessageReactionRemove:
+		return Entry{Type: EntryReactionRemove, Reaction: e.MessageReaction}, true
+	case *discordgo.GuildMemberAdd:
+		return Entry{Type: EntryMemberJoin, Member: e.Member}, true
+	case *discordgo.GuildMemberRemove:
+		return Entry{Type: EntryMemberLeave, Member: e.Member}, true
+	}
+
+	return Entry{}, false
+}
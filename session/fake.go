@@ -0,0 +1,768 @@
+package session
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/snowflake"
+	"github.com/bwmarrin/discordgo"
+)
+
+type (
+	// Fake is an in-memory Session, for running commands without Discord.
+	// Guilds, channels, roles and members are kept in a DiscordGo state, and
+	// everything the bot does is recorded as an action. Initialized with
+	// NewFake().
+	Fake struct {
+		// OnAction is called with each action as it happens, if set
+		OnAction func(Action)
+		// Now is the fake's clock, which can be replaced to make message IDs
+		// and timestamps predictable.
+		Now func() time.Time
+
+		state    *discordgo.State
+		users    map[string]*discordgo.User
+		messages map[string][]*discordgo.Message // By channel, oldest first
+		actions  []Action
+		seq      uint64
+		mu       sync.Mutex
+	}
+
+	// Action is something the bot did through a Fake session
+	Action struct {
+		Type      ActionType `json:"type"`
+		GuildID   string     `json:"guildID,omitempty"`
+		ChannelID string     `json:"channelID,omitempty"`
+		MessageID string     `json:"messageID,omitempty"`
+		UserID    string     `json:"userID,omitempty"`
+		RoleID    string     `json:"roleID,omitempty"`
+		Emoji     string     `json:"emoji,omitempty"`
+
+		// Message is the message which was sent or edited
+		Message *discordgo.Message `json:"message,omitempty"`
+		// AllowedMentions are the mentions the message was allowed to make,
+		// if they were limited
+		AllowedMentions *discordgo.MessageAllowedMentions `json:"allowedMentions,omitempty"`
+		// Files are the files which were sent
+		Files []File `json:"files,omitempty"`
+		// Status is the status which was set
+		Status *discordgo.UpdateStatusData `json:"status,omitempty"`
+	}
+
+	// File is a file sent through a Fake session
+	File struct {
+		Name string `json:"name"`
+		Data []byte `json:"-"`
+		Size int    `json:"size"`
+	}
+
+	// ActionType is the type of an action
+	ActionType string
+)
+
+const (
+	// ActionSend is a message being sent
+	ActionSend ActionType = "send"
+	// ActionEdit is a message being edited
+	ActionEdit ActionType = "edit"
+	// ActionDelete is a message being deleted
+	ActionDelete ActionType = "delete"
+	// ActionTyping is the bot starting to type
+	ActionTyping ActionType = "typing"
+	// ActionReact is a reaction being added
+	ActionReact ActionType = "react"
+	// ActionUnreact is a reaction being removed
+	ActionUnreact ActionType = "unreact"
+	// ActionUnreactAll is every reaction being removed from a message
+	ActionUnreactAll ActionType = "unreactAll"
+	// ActionRoleAdd is a role being given to a member
+	ActionRoleAdd ActionType = "roleAdd"
+	// ActionRoleRemove is a role being taken from a member
+	ActionRoleRemove ActionType = "roleRemove"
+	// ActionStatus is the bot's status being set
+	ActionStatus ActionType = "status"
+)
+
+// JSON error codes Discord responds with, see
+// https://discord.com/developers/docs/topics/opcodes-and-status-codes
+const (
+	unknownChannel = 10003
+	unknownGuild   = 10004
+	unknownMember  = 10007
+	unknownMessage = 10008
+	unknownRole    = 10011
+	unknownUser    = 10013
+)
+
+// messageTypeReply is the type of messages sent as replies, which DiscordGo
+// doesn't define yet.
+const messageTypeReply discordgo.MessageType = 19
+
+// NewFake creates a fake session for the bot user
+func NewFake(bot *discordgo.User) *Fake {
+	state := discordgo.NewState()
+	state.User = bot
+
+	return &Fake{
+		Now:      time.Now,
+		state:    state,
+		users:    map[string]*discordgo.User{bot.ID: bot},
+		messages: make(map[string][]*discordgo.Message),
+	}
+}
+
+/* === Setup === */
+
+// AddGuild adds a guild, along with its channels, roles and members
+func (f *Fake) AddGuild(g *discordgo.Guild) error {
+	for _, c := range g.Channels {
+		c.GuildID = g.ID
+	}
+	for _, m := range g.Members {
+		m.GuildID = g.ID
+		f.AddUser(m.User)
+	}
+
+	return f.state.GuildAdd(g)
+}
+
+// AddChannel adds a channel to its guild
+func (f *Fake) AddChannel(c *discordgo.Channel) error {
+	return f.state.ChannelAdd(c)
+}
+
+// AddRole adds a role to the guild
+func (f *Fake) AddRole(guildID string, r *discordgo.Role) error {
+	return f.state.RoleAdd(guildID, r)
+}
+
+// AddMember adds a member to their guild
+func (f *Fake) AddMember(m *discordgo.Member) error {
+	f.AddUser(m.User)
+	return f.state.MemberAdd(m)
+}
+
+// AddUser adds a user, who doesn't need to be in any guild
+func (f *Fake) AddUser(u *discordgo.User) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.users[u.ID] = u
+}
+
+// AddMessage adds a message sent by someone other than the bot, filling in
+// its ID and timestamp if they aren't set. The stored message is returned.
+func (f *Fake) AddMessage(m *discordgo.Message) *discordgo.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(m.ID) == 0 {
+		m.ID = f.newID()
+	}
+	if len(m.Timestamp) == 0 {
+		m.Timestamp = f.timestamp()
+	}
+
+	f.messages[m.ChannelID] = append(f.messages[m.ChannelID], m)
+	return m
+}
+
+// UpdateMessage changes the content of a message sent by someone other than
+// the bot. The updated message is returned.
+func (f *Fake) UpdateMessage(
+	channelID, messageID, content string,
+) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, m := f.find(channelID, messageID)
+	if m == nil {
+		return nil, notFound(unknownMessage, "Unknown Message")
+	}
+
+	m.Content = content
+	m.EditedTimestamp = f.timestamp()
+	return copyMessage(m), nil
+}
+
+// RemoveMessage removes a message sent by someone other than the bot
+func (f *Fake) RemoveMessage(channelID, messageID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	i, m := f.find(channelID, messageID)
+	if m == nil {
+		return notFound(unknownMessage, "Unknown Message")
+	}
+
+	messages := f.messages[channelID]
+	f.messages[channelID] = append(messages[:i:i], messages[i+1:]...)
+	return nil
+}
+
+// NewID returns a new snowflake ID
+func (f *Fake) NewID() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.newID()
+}
+
+// Messages returns the messages in the channel, oldest first
+func (f *Fake) Messages(channelID string) []*discordgo.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]*discordgo.Message(nil), f.messages[channelID]...)
+}
+
+// Actions returns everything the bot has done, in order
+func (f *Fake) Actions() []Action {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Action(nil), f.actions...)
+}
+
+// ClearActions forgets everything the bot has done
+func (f *Fake) ClearActions() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.actions = nil
+}
+
+/* === Session === */
+
+// State is the fake's guilds, channels, roles and members
+func (f *Fake) State() *discordgo.State {
+	return f.state
+}
+
+// User returns the user with the ID
+func (f *Fake) User(userID string) (*discordgo.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	u, ok := f.users[userID]
+	if !ok {
+		return nil, notFound(unknownUser, "Unknown User")
+	}
+
+	user := *u
+	return &user, nil
+}
+
+// UserChannelCreate returns the DM channel with the user, creating it if it
+// doesn't exist.
+func (f *Fake) UserChannelCreate(
+	recipientID string,
+) (*discordgo.Channel, error) {
+	user, err := f.User(recipientID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range f.state.PrivateChannels {
+		if len(c.Recipients) != 0 && c.Recipients[0].ID == recipientID {
+			return c, nil
+		}
+	}
+
+	c := &discordgo.Channel{
+		ID:         f.NewID(),
+		Type:       discordgo.ChannelTypeDM,
+		Recipients: []*discordgo.User{user},
+	}
+	return c, f.state.ChannelAdd(c)
+}
+
+// UpdateStatusComplex records the bot's status
+func (f *Fake) UpdateStatusComplex(usd discordgo.UpdateStatusData) error {
+	f.record(Action{Type: ActionStatus, Status: &usd})
+	return nil
+}
+
+// Channel returns the channel with the ID
+func (f *Fake) Channel(channelID string) (*discordgo.Channel, error) {
+	c, err := f.state.Channel(channelID)
+	if err != nil {
+		return nil, notFound(unknownChannel, "Unknown Channel")
+	}
+	return c, nil
+}
+
+// ChannelTyping records the bot starting to type
+func (f *Fake) ChannelTyping(channelID string) error {
+	c, err := f.Channel(channelID)
+	if err != nil {
+		return err
+	}
+
+	f.record(Action{Type: ActionTyping, GuildID: c.GuildID, ChannelID: c.ID})
+	return nil
+}
+
+// ChannelMessage returns the message with the ID
+func (f *Fake) ChannelMessage(
+	channelID, messageID string,
+) (*discordgo.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, m := f.find(channelID, messageID)
+	if m == nil {
+		return nil, notFound(unknownMessage, "Unknown Message")
+	}
+	return copyMessage(m), nil
+}
+
+// ChannelMessages returns up to limit messages from the channel, newest first
+func (f *Fake) ChannelMessages(
+	channelID string, limit int, beforeID, afterID, aroundID string,
+) ([]*discordgo.Message, error) {
+	if _, err := f.Channel(channelID); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	messages := f.messages[channelID]
+	start, end := 0, len(messages)
+	if i, m := f.find(channelID, beforeID); m != nil {
+		end = i
+	}
+	if i, m := f.find(channelID, afterID); m != nil {
+		start = i + 1
+	}
+	if i, m := f.find(channelID, aroundID); m != nil {
+		start, end = i-limit/2, i+limit-limit/2
+		if start < 0 {
+			start = 0
+		}
+		if end > len(messages) {
+			end = len(messages)
+		}
+	}
+
+	/* After a message, the oldest are returned. Otherwise, the newest. */
+	if end-start > limit {
+		if len(afterID) != 0 {
+			end = start + limit
+		} else {
+			start = end - limit
+		}
+	}
+
+	var out []*discordgo.Message
+	for i := end - 1; i >= start; i-- {
+		out = append(out, copyMessage(messages[i]))
+	}
+	return out, nil
+}
+
+// ChannelMessageSend sends a message to the channel
+func (f *Fake) ChannelMessageSend(
+	channelID string, content string,
+) (*discordgo.Message, error) {
+	return f.ChannelMessageSendComplex(
+		channelID, &discordgo.MessageSend{Content: content},
+	)
+}
+
+// ChannelMessageSendEmbed sends an embed to the channel
+func (f *Fake) ChannelMessageSendEmbed(
+	channelID string, embed *discordgo.MessageEmbed,
+) (*discordgo.Message, error) {
+	return f.ChannelMessageSendComplex(
+		channelID, &discordgo.MessageSend{Embed: embed},
+	)
+}
+
+// ChannelMessageSendComplex sends a message (with embeds, files, etc.) to the
+// channel.
+func (f *Fake) ChannelMessageSendComplex(
+	channelID string, data *discordgo.MessageSend,
+) (*discordgo.Message, error) {
+	return f.send(channelID, data, nil)
+}
+
+// ChannelMessageSendReply sends a message as a reply to the referenced message
+func (f *Fake) ChannelMessageSendReply(
+	channelID string,
+	data *discordgo.MessageSend,
+	ref *discordgo.MessageReference,
+) (*discordgo.Message, error) {
+	return f.send(channelID, data, ref)
+}
+
+// ChannelMessageEditComplex edits one of the bot's messages
+func (f *Fake) ChannelMessageEditComplex(
+	edit *discordgo.MessageEdit,
+) (*discordgo.Message, error) {
+	f.mu.Lock()
+	_, m := f.find(edit.Channel, edit.ID)
+	if m == nil {
+		f.mu.Unlock()
+		return nil, notFound(unknownMessage, "Unknown Message")
+	}
+
+	if edit.Content != nil {
+		m.Content = *edit.Content
+	}
+	if edit.Embed != nil {
+		m.Embeds = []*discordgo.MessageEmbed{edit.Embed}
+	}
+	m.EditedTimestamp = f.timestamp()
+	edited := copyMessage(m)
+	f.mu.Unlock()
+
+	f.record(Action{
+		Type:            ActionEdit,
+		GuildID:         edited.GuildID,
+		ChannelID:       edited.ChannelID,
+		MessageID:       edited.ID,
+		Message:         edited,
+		AllowedMentions: edit.AllowedMentions,
+	})
+	return edited, nil
+}
+
+// ChannelMessageEditEmbed replaces the embed of one of the bot's messages
+func (f *Fake) ChannelMessageEditEmbed(
+	channelID, messageID string, embed *discordgo.MessageEmbed,
+) (*discordgo.Message, error) {
+	return f.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID: messageID, Channel: channelID, Embed: embed,
+	})
+}
+
+// ChannelMessageDelete deletes a message
+func (f *Fake) ChannelMessageDelete(channelID, messageID string) error {
+	f.mu.Lock()
+	i, m := f.find(channelID, messageID)
+	if m == nil {
+		f.mu.Unlock()
+		return notFound(unknownMessage, "Unknown Message")
+	}
+
+	messages := f.messages[channelID]
+	f.messages[channelID] = append(messages[:i:i], messages[i+1:]...)
+	f.mu.Unlock()
+
+	f.record(Action{
+		Type:      ActionDelete,
+		GuildID:   m.GuildID,
+		ChannelID: channelID,
+		MessageID: messageID,
+	})
+	return nil
+}
+
+// ChannelFileSend sends a file to the channel
+func (f *Fake) ChannelFileSend(
+	channelID, name string, r io.Reader,
+) (*discordgo.Message, error) {
+	return f.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Files: []*discordgo.File{{Name: name, Reader: r}},
+	})
+}
+
+// Guild returns the guild with the ID
+func (f *Fake) Guild(guildID string) (*discordgo.Guild, error) {
+	g, err := f.state.Guild(guildID)
+	if err != nil {
+		return nil, notFound(unknownGuild, "Unknown Guild")
+	}
+	return g, nil
+}
+
+// GuildRoles returns the guild's roles
+func (f *Fake) GuildRoles(guildID string) ([]*discordgo.Role, error) {
+	g, err := f.Guild(guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	f.state.RLock()
+	defer f.state.RUnlock()
+
+	return append([]*discordgo.Role(nil), g.Roles...), nil
+}
+
+// GuildMember returns the member of the guild
+func (f *Fake) GuildMember(guildID, userID string) (*discordgo.Member, error) {
+	m, err := f.state.Member(guildID, userID)
+	if err != nil {
+		return nil, notFound(unknownMember, "Unknown Member")
+	}
+
+	member := *m
+	member.Roles = append([]string(nil), m.Roles...)
+	return &member, nil
+}
+
+// GuildMemberRoleAdd gives the member a role
+func (f *Fake) GuildMemberRoleAdd(guildID, userID, roleID string) error {
+	return f.updateRoles(ActionRoleAdd, guildID, userID, roleID)
+}
+
+// GuildMemberRoleRemove takes a role from the member
+func (f *Fake) GuildMemberRoleRemove(guildID, userID, roleID string) error {
+	return f.updateRoles(ActionRoleRemove, guildID, userID, roleID)
+}
+
+// MessageReactionAdd adds one of the bot's reactions to a message
+func (f *Fake) MessageReactionAdd(channelID, messageID, emojiID string) error {
+	return f.react(ActionReact, channelID, messageID, emojiID, f.state.User.ID)
+}
+
+// MessageReactionRemove removes a user's reaction from a message
+func (f *Fake) MessageReactionRemove(
+	channelID, messageID, emojiID, userID string,
+) error {
+	return f.react(ActionUnreact, channelID, messageID, emojiID, userID)
+}
+
+// MessageReactionsRemoveAll removes every reaction from a message
+func (f *Fake) MessageReactionsRemoveAll(channelID, messageID string) error {
+	return f.react(ActionUnreactAll, channelID, messageID, "", "")
+}
+
+/* === Helper Functions === */
+
+// send stores a message sent by the bot, optionally as a reply
+func (f *Fake) send(
+	channelID string,
+	data *discordgo.MessageSend,
+	ref *discordgo.MessageReference,
+) (*discordgo.Message, error) {
+	c, err := f.Channel(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	files := data.Files
+	if data.File != nil {
+		files = append(files, data.File)
+	}
+
+	var sent []File
+	for _, file := range files {
+		b, err := ioutil.ReadAll(file.Reader)
+		if err != nil {
+			return nil, err
+		}
+		sent = append(sent, File{Name: file.Name, Data: b, Size: len(b)})
+	}
+
+	f.mu.Lock()
+	m := &discordgo.Message{
+		ID:        f.newID(),
+		ChannelID: channelID,
+		GuildID:   c.GuildID,
+		Content:   data.Content,
+		Timestamp: f.timestamp(),
+		Author:    f.state.User,
+		Type:      discordgo.MessageTypeDefault,
+	}
+	if data.Embed != nil {
+		m.Embeds = []*discordgo.MessageEmbed{data.Embed}
+	}
+	for _, file := range sent {
+		m.Attachments = append(m.Attachments, &discordgo.MessageAttachment{
+			ID:       f.newID(),
+			Filename: file.Name,
+			Size:     file.Size,
+			URL:      "attachment://" + file.Name,
+		})
+	}
+	if ref != nil {
+		m.Type = messageTypeReply
+		m.MessageReference = ref
+	}
+
+	f.messages[channelID] = append(f.messages[channelID], m)
+	m = copyMessage(m)
+	f.mu.Unlock()
+
+	f.record(Action{
+		Type:            ActionSend,
+		GuildID:         m.GuildID,
+		ChannelID:       channelID,
+		MessageID:       m.ID,
+		Message:         m,
+		Files:           sent,
+		AllowedMentions: data.AllowedMentions,
+	})
+	return m, nil
+}
+
+// updateRoles gives or takes a role from a member
+func (f *Fake) updateRoles(t ActionType, guildID, userID, roleID string) error {
+	if _, err := f.state.Role(guildID, roleID); err != nil {
+		return notFound(unknownRole, "Unknown Role")
+	}
+
+	member, err := f.GuildMember(guildID, userID)
+	if err != nil {
+		return err
+	}
+
+	var roles []string
+	for _, id := range member.Roles {
+		if id != roleID {
+			roles = append(roles, id)
+		}
+	}
+	if t == ActionRoleAdd {
+		roles = append(roles, roleID)
+	}
+	member.Roles = roles
+
+	if err := f.state.MemberAdd(member); err != nil {
+		return err
+	}
+
+	f.record(Action{Type: t, GuildID: guildID, UserID: userID, RoleID: roleID})
+	return nil
+}
+
+// react adds or removes reactions from a message
+func (f *Fake) react(
+	t ActionType, channelID, messageID, emojiID, userID string,
+) error {
+	f.mu.Lock()
+	_, m := f.find(channelID, messageID)
+	if m == nil {
+		f.mu.Unlock()
+		return notFound(unknownMessage, "Unknown Message")
+	}
+
+	switch t {
+	case ActionReact:
+		m.Reactions = addReaction(m.Reactions, emojiID)
+	case ActionUnreact:
+		m.Reactions = removeReaction(m.Reactions, emojiID)
+	case ActionUnreactAll:
+		m.Reactions = nil
+	}
+	guildID := m.GuildID
+	f.mu.Unlock()
+
+	f.record(Action{
+		Type:      t,
+		GuildID:   guildID,
+		ChannelID: channelID,
+		MessageID: messageID,
+		UserID:    userID,
+		Emoji:     emojiID,
+	})
+	return nil
+}
+
+// record stores the action, and passes it to OnAction
+func (f *Fake) record(a Action) {
+	f.mu.Lock()
+	f.actions = append(f.actions, a)
+	onAction := f.OnAction
+	f.mu.Unlock()
+
+	if onAction != nil {
+		onAction(a)
+	}
+}
+
+// find finds a message and its index. Must be called with the lock held.
+func (f *Fake) find(channelID, messageID string) (int, *discordgo.Message) {
+	if len(messageID) == 0 {
+		return -1, nil
+	}
+
+	for i, m := range f.messages[channelID] {
+		if m.ID == messageID {
+			return i, m
+		}
+	}
+	return -1, nil
+}
+
+// newID returns a new snowflake ID. Must be called with the lock held.
+func (f *Fake) newID() string {
+	f.seq++
+	return snowflake.Generate(f.Now(), f.seq)
+}
+
+func (f *Fake) timestamp() discordgo.Timestamp {
+	return discordgo.Timestamp(f.Now().UTC().Format(time.RFC3339))
+}
+
+func addReaction(
+	reactions []*discordgo.MessageReactions, emojiID string,
+) []*discordgo.MessageReactions {
+	for _, r := range reactions {
+		if r.Emoji.APIName() == emojiID {
+			r.Count++
+			return reactions
+		}
+	}
+
+	emoji := &discordgo.Emoji{Name: emojiID}
+	if i := strings.LastIndex(emojiID, ":"); i != -1 {
+		emoji = &discordgo.Emoji{Name: emojiID[:i], ID: emojiID[i+1:]}
+	}
+	return append(reactions, &discordgo.MessageReactions{
+		Emoji: emoji, Count: 1, Me: true,
+	})
+}
+
+func removeReaction(
+	reactions []*discordgo.MessageReactions, emojiID string,
+) []*discordgo.MessageReactions {
+	var out []*discordgo.MessageReactions
+	for _, r := range reactions {
+		if r.Emoji.APIName() == emojiID {
+			if r.Count--; r.Count == 0 {
+				continue
+			}
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// copyMessage copies a message, like it's been fetched from Discord again
+func copyMessage(m *discordgo.Message) *discordgo.Message {
+	c := *m
+	c.Embeds = append([]*discordgo.MessageEmbed(nil), m.Embeds...)
+	c.Reactions = nil
+	for _, r := range m.Reactions {
+		reaction := *r
+		c.Reactions = append(c.Reactions, &reaction)
+	}
+	return &c
+}
+
+// notFound creates the error Discord responds with when something doesn't
+// exist.
+func notFound(code int, message string) error {
+	body := fmt.Sprintf(`{"message": "%s", "code": %d}`, message, code)
+	return &discordgo.RESTError{
+		Response: &http.Response{
+			StatusCode: http.StatusNotFound,
+			Status:     "404 Not Found",
+		},
+		ResponseBody: []byte(body),
+		Message:      &discordgo.APIErrorMessage{Code: code, Message: message},
+	}
+}
@@ -0,0 +1,129 @@
+package session
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type (
+	// Session is the subset of DiscordGo's session used by the bot. Anything
+	// added here must also be added to Fake.
+	Session interface {
+		// State is the cache of guilds, channels and members the session
+		// has seen. State.User is the bot's user.
+		State() *discordgo.State
+
+		User(userID string) (*discordgo.User, error)
+		UserChannelCreate(recipientID string) (*discordgo.Channel, error)
+		UpdateStatusComplex(usd discordgo.UpdateStatusData) error
+
+		Channel(channelID string) (*discordgo.Channel, error)
+		ChannelTyping(channelID string) error
+		ChannelMessage(channelID, messageID string) (*discordgo.Message, error)
+		ChannelMessages(
+			channelID string, limit int, beforeID, afterID, aroundID string,
+		) ([]*discordgo.Message, error)
+		ChannelMessageSend(
+			channelID string, content string,
+		) (*discordgo.Message, error)
+		ChannelMessageSendEmbed(
+			channelID string, embed *discordgo.MessageEmbed,
+		) (*discordgo.Message, error)
+		ChannelMessageSendComplex(
+			channelID string, data *discordgo.MessageSend,
+		) (*discordgo.Message, error)
+		// ChannelMessageSendReply sends a message as a reply to the referenced
+		// message. Files can't be sent as replies.
+		ChannelMessageSendReply(
+			channelID string,
+			data *discordgo.MessageSend,
+			ref *discordgo.MessageReference,
+		) (*discordgo.Message, error)
+		ChannelMessageEditComplex(
+			m *discordgo.MessageEdit,
+		) (*discordgo.Message, error)
+		ChannelMessageEditEmbed(
+			channelID, messageID string, embed *discordgo.MessageEmbed,
+		) (*discordgo.Message, error)
+		ChannelMessageDelete(channelID, messageID string) error
+		ChannelFileSend(
+			channelID, name string, r io.Reader,
+		) (*discordgo.Message, error)
+
+		Guild(guildID string) (*discordgo.Guild, error)
+		GuildRoles(guildID string) ([]*discordgo.Role, error)
+		GuildMember(guildID, userID string) (*discordgo.Member, error)
+		GuildMemberRoleAdd(guildID, userID, roleID string) error
+		GuildMemberRoleRemove(guildID, userID, roleID string) error
+
+		MessageReactionAdd(channelID, messageID, emojiID string) error
+		MessageReactionRemove(
+			channelID, messageID, emojiID, userID string,
+		) error
+		MessageReactionsRemoveAll(channelID, messageID string) error
+	}
+
+	// Discord is a Session backed by a DiscordGo session. Initialized with
+	// Wrap().
+	Discord struct {
+		*discordgo.Session
+	}
+
+	// replySend is a MessageSend with a message reference, which DiscordGo's
+	// MessageSend doesn't support yet.
+	replySend struct {
+		*discordgo.MessageSend
+		Reference *discordgo.MessageReference `json:"message_reference"`
+	}
+)
+
+// Wrap wraps a DiscordGo session, so it can be used as a Session
+func Wrap(s *discordgo.Session) Session {
+	return Discord{s}
+}
+
+// State is the DiscordGo session's state
+func (d Discord) State() *discordgo.State {
+	return d.Session.State
+}
+
+// ChannelMessageSendReply sends a message as a reply to the referenced message.
+// DiscordGo doesn't support replies yet, so the request is made directly.
+func (d Discord) ChannelMessageSendReply(
+	channelID string,
+	data *discordgo.MessageSend,
+	ref *discordgo.MessageReference,
+) (*discordgo.Message, error) {
+	endpoint := discordgo.EndpointChannelMessages(channelID)
+	resp, err := d.Session.RequestWithBucketID(
+		"POST", endpoint, &replySend{data, ref}, endpoint,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var sent *discordgo.Message
+	if err := json.Unmarshal(resp, &sent); err != nil {
+		return nil, err
+	}
+	return sent, nil
+}
+
+// ContentWithMentionsReplaced replaces the mentions in the message's content
+// with the names of the users, roles and channels, using the session's state.
+func ContentWithMentionsReplaced(
+	s Session, m *discordgo.Message,
+) (string, error) {
+	/* DiscordGo only needs the state to replace mentions */
+	return m.ContentWithMoreMentionsReplaced(
+		&discordgo.Session{State: s.State(), StateEnabled: true},
+	)
+}
+
+/* Make sure both sessions implement Session */
+var (
+	_ Session = Discord{}
+	_ Session = (*Fake)(nil)
+)
@@ -0,0 +1,204 @@
+package snowflake
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+type (
+	// Mention is a mention found in a message
+	Mention struct {
+		Type MentionType
+		ID   string
+
+		// Name is set for emoji and slash command mentions
+		Name string
+		// Animated is set for animated emoji
+		Animated bool
+		// Time and Style are set for timestamp mentions
+		Time  time.Time
+		Style string
+	}
+
+	// MentionType is the type of a mention
+	MentionType int
+)
+
+const (
+	// UserMention is a user mention (<@id> or <@!id>)
+	UserMention MentionType = iota
+	// RoleMention is a role mention (<@&id>)
+	RoleMention
+	// ChannelMention is a channel mention (<#id>)
+	ChannelMention
+	// EmojiMention is a custom emoji (<:name:id> or <a:name:id>)
+	EmojiMention
+	// CommandMention is a slash command mention (</name:id>)
+	CommandMention
+	// TimestampMention is a timestamp (<t:unix> or <t:unix:style>)
+	TimestampMention
+)
+
+// Epoch is the Discord epoch (the first second of 2015) in milliseconds
+const Epoch = 1420070400000
+
+// Snowflakes are 64 bit integers. Anything from 2015 onward has at least 17
+// digits, and the largest possible has 20.
+const idPattern = `(\d{17,20})`
+
+var (
+	idRE = regexp.MustCompile(`^` + idPattern + `$`)
+
+	userRE      = regexp.MustCompile(`^<@!?` + idPattern + `>$`)
+	roleRE      = regexp.MustCompile(`^<@&` + idPattern + `>$`)
+	channelRE   = regexp.MustCompile(`^<#` + idPattern + `>$`)
+	emojiRE     = regexp.MustCompile(`^<(a?):(\w{2,32}):` + idPattern + `>$`)
+	commandRE   = regexp.MustCompile(`^</([-_\p{L}\p{N} ]{1,100}):` + idPattern + `>$`)
+	timestampRE = regexp.MustCompile(`^<t:(-?\d{1,13})(?::([tTdDfFR]))?>$`)
+
+	mentionRE = regexp.MustCompile(
+		`<(?:@[!&]?|#|a?:\w{2,32}:|/[-_\p{L}\p{N} ]{1,100}:)\d{17,20}>|` +
+			`<t:-?\d{1,13}(?::[tTdDfFR])?>`,
+	)
+)
+
+// Parse parses a snowflake ID, returning an error if it isn't valid
+func Parse(s string) (uint64, error) {
+	if !idRE.MatchString(s) {
+		return 0, fmt.Errorf("'%s' is not a valid ID", s)
+	}
+
+	/* 20 digit IDs can still be too big for 64 bits */
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("'%s' is not a valid ID", s)
+	}
+	return id, nil
+}
+
+// IsValid checks if the supplied string is a snowflake ID
+func IsValid(s string) bool {
+	_, err := Parse(s)
+	return err == nil
+}
+
+// Time returns the time the snowflake ID was created
+func Time(s string) (time.Time, error) {
+	id, err := Parse(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	ms := int64(id>>22) + Epoch
+	return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond)).UTC(), nil
+}
+
+// Generate creates a snowflake ID for the time. The sequence number tells
+// apart IDs created in the same millisecond, and wraps at 4096.
+func Generate(t time.Time, seq uint64) string {
+	ms := uint64(t.UnixNano()/int64(time.Millisecond)) - Epoch
+	return strconv.FormatUint(ms<<22|seq&0xfff, 10)
+}
+
+// ParseUser gets the user ID from a user mention
+func ParseUser(s string) (string, bool) {
+	return match(userRE, s)
+}
+
+// ParseRole gets the role ID from a role mention
+func ParseRole(s string) (string, bool) {
+	return match(roleRE, s)
+}
+
+// ParseChannel gets the channel ID from a channel mention
+func ParseChannel(s string) (string, bool) {
+	return match(channelRE, s)
+}
+
+// ParseEmoji parses a custom emoji
+func ParseEmoji(s string) (Mention, bool) {
+	m := emojiRE.FindStringSubmatch(s)
+	if m == nil || !IsValid(m[3]) {
+		return Mention{}, false
+	}
+
+	return Mention{
+		Type:     EmojiMention,
+		ID:       m[3],
+		Name:     m[2],
+		Animated: m[1] == "a",
+	}, true
+}
+
+// ParseCommand parses a slash command mention
+func ParseCommand(s string) (Mention, bool) {
+	m := commandRE.FindStringSubmatch(s)
+	if m == nil || !IsValid(m[2]) {
+		return Mention{}, false
+	}
+
+	return Mention{Type: CommandMention, ID: m[2], Name: m[1]}, true
+}
+
+// ParseTimestamp parses a timestamp mention
+func ParseTimestamp(s string) (Mention, bool) {
+	m := timestampRE.FindStringSubmatch(s)
+	if m == nil {
+		return Mention{}, false
+	}
+
+	unix, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return Mention{}, false
+	}
+
+	return Mention{
+		Type:  TimestampMention,
+		Time:  time.Unix(unix, 0).UTC(),
+		Style: m[2],
+	}, true
+}
+
+// ParseMention parses a mention of any type
+func ParseMention(s string) (Mention, bool) {
+	if id, ok := ParseUser(s); ok {
+		return Mention{Type: UserMention, ID: id}, true
+	}
+	if id, ok := ParseRole(s); ok {
+		return Mention{Type: RoleMention, ID: id}, true
+	}
+	if id, ok := ParseChannel(s); ok {
+		return Mention{Type: ChannelMention, ID: id}, true
+	}
+	if m, ok := ParseEmoji(s); ok {
+		return m, true
+	}
+	if m, ok := ParseCommand(s); ok {
+		return m, true
+	}
+	return ParseTimestamp(s)
+}
+
+// FindMentions finds every mention (of any type) in the supplied content
+func FindMentions(content string) []Mention {
+	var out []Mention
+	for _, s := range mentionRE.FindAllString(content, -1) {
+		if m, ok := ParseMention(s); ok {
+			out = append(out, m)
+		}
+	}
+
+	return out
+}
+
+// match returns the ID captured by the regex, if it's a valid snowflake
+func match(re *regexp.Regexp, s string) (string, bool) {
+	m := re.FindStringSubmatch(s)
+	if m == nil || !IsValid(m[1]) {
+		return "", false
+	}
+
+	return m[1], true
+}
@@ -0,0 +1,209 @@
+package snowflake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		want  uint64
+		valid bool
+	}{
+		{"17 digits", "80351110224678912", 80351110224678912, true},
+		{"18 digits", "175928847299117063", 175928847299117063, true},
+		{"19 digits", "1234567890123456789", 1234567890123456789, true},
+		{"20 digits", "12345678901234567890", 12345678901234567890, true},
+		{"largest", "18446744073709551615", 18446744073709551615, true},
+		{"overflowing", "18446744073709551616", 0, false},
+		{"overflowing 20 digits", "99999999999999999999", 0, false},
+		{"16 digits", "1234567890123456", 0, false},
+		{"21 digits", "123456789012345678901", 0, false},
+		{"empty", "", 0, false},
+		{"letters", "abcdefghijklmnopqr", 0, false},
+		{"mixed", "17592884729911706a", 0, false},
+		{"negative", "-175928847299117063", 0, false},
+		{"spaces", " 175928847299117063 ", 0, false},
+		{"mention", "<@175928847299117063>", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if (err == nil) != tt.valid {
+				t.Fatalf("Parse(%q) error = %v, want valid = %v", tt.in, err, tt.valid)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %d, want %d", tt.in, got, tt.want)
+			}
+			if IsValid(tt.in) != tt.valid {
+				t.Errorf("IsValid(%q) = %v, want %v", tt.in, !tt.valid, tt.valid)
+			}
+		})
+	}
+}
+
+func TestTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+		err  bool
+	}{
+		{
+			in:   "175928847299117063",
+			want: time.Date(2016, 4, 30, 11, 18, 25, 796*int(time.Millisecond), time.UTC),
+		},
+		{
+			in:   "80351110224678912",
+			want: time.Date(2015, 8, 10, 17, 26, 37, 529*int(time.Millisecond), time.UTC),
+		},
+		{in: "not an id", err: true},
+		{in: "99999999999999999999", err: true},
+	}
+
+	for _, tt := range tests {
+		got, err := Time(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("Time(%q) error = %v, want error = %v", tt.in, err, tt.err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("Time(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	id := Generate(now, 5)
+
+	got, err := Time(id)
+	if err != nil {
+		t.Fatalf("Generate made an invalid ID %q: %v", id, err)
+	}
+	if !got.Equal(now) {
+		t.Errorf("Time(Generate(%v)) = %v", now, got)
+	}
+	if Generate(now, 6) == id {
+		t.Error("Generate made the same ID for different sequence numbers")
+	}
+}
+
+func TestParseMentions(t *testing.T) {
+	const id = "175928847299117063"
+
+	tests := []struct {
+		name    string
+		in      string
+		user    bool
+		role    bool
+		channel bool
+	}{
+		{name: "user", in: "<@" + id + ">", user: true},
+		{name: "nickname", in: "<@!" + id + ">", user: true},
+		{name: "role", in: "<@&" + id + ">", role: true},
+		{name: "channel", in: "<#" + id + ">", channel: true},
+		{name: "bare ID", in: id},
+		{name: "short ID", in: "<@1234>"},
+		{name: "overflowing ID", in: "<@99999999999999999999>"},
+		{name: "trailing text", in: "<@" + id + "> hi"},
+		{name: "leading text", in: "hi <@" + id + ">"},
+		{name: "unclosed", in: "<@" + id},
+		{name: "both prefixes", in: "<@!&" + id + ">"},
+		{name: "emoji", in: "<:name:" + id + ">"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := func(kind string, got string, ok, want bool) {
+				if ok != want {
+					t.Errorf("Parse%s(%q) ok = %v, want %v", kind, tt.in, ok, want)
+				}
+				if ok && got != id {
+					t.Errorf("Parse%s(%q) = %q, want %q", kind, tt.in, got, id)
+				}
+			}
+
+			got, ok := ParseUser(tt.in)
+			check("User", got, ok, tt.user)
+			got, ok = ParseRole(tt.in)
+			check("Role", got, ok, tt.role)
+			got, ok = ParseChannel(tt.in)
+			check("Channel", got, ok, tt.channel)
+		})
+	}
+}
+
+func TestParseMention(t *testing.T) {
+	const id = "175928847299117063"
+
+	tests := []struct {
+		in   string
+		want Mention
+		ok   bool
+	}{
+		{"<@" + id + ">", Mention{Type: UserMention, ID: id}, true},
+		{"<@!" + id + ">", Mention{Type: UserMention, ID: id}, true},
+		{"<@&" + id + ">", Mention{Type: RoleMention, ID: id}, true},
+		{"<#" + id + ">", Mention{Type: ChannelMention, ID: id}, true},
+		{
+			"<:pog:" + id + ">",
+			Mention{Type: EmojiMention, ID: id, Name: "pog"}, true,
+		},
+		{
+			"<a:pog:" + id + ">",
+			Mention{Type: EmojiMention, ID: id, Name: "pog", Animated: true}, true,
+		},
+		{
+			"</role give:" + id + ">",
+			Mention{Type: CommandMention, ID: id, Name: "role give"}, true,
+		},
+		{
+			"<t:1618953630:R>",
+			Mention{
+				Type:  TimestampMention,
+				Time:  time.Unix(1618953630, 0).UTC(),
+				Style: "R",
+			}, true,
+		},
+		{"<t:1618953630>", Mention{
+			Type: TimestampMention, Time: time.Unix(1618953630, 0).UTC(),
+		}, true},
+		{"<t:1618953630:X>", Mention{}, false},
+		{"@everyone", Mention{}, false},
+		{id, Mention{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseMention(tt.in)
+		if ok != tt.ok {
+			t.Errorf("ParseMention(%q) ok = %v, want %v", tt.in, ok, tt.ok)
+			continue
+		}
+		if got.Type != tt.want.Type || got.ID != tt.want.ID ||
+			got.Name != tt.want.Name || got.Animated != tt.want.Animated ||
+			!got.Time.Equal(tt.want.Time) || got.Style != tt.want.Style {
+			t.Errorf("ParseMention(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFindMentions(t *testing.T) {
+	const id = "175928847299117063"
+
+	content := "hey <@" + id + "> and <@&" + id + ">, see <#" + id +
+		"> (not <@1234> or <@&" + id + ")"
+	got := FindMentions(content)
+
+	want := []MentionType{UserMention, RoleMention, ChannelMention}
+	if len(got) != len(want) {
+		t.Fatalf("FindMentions found %d mentions, want %d: %+v", len(got), len(want), got)
+	}
+	for i, m := range got {
+		if m.Type != want[i] || m.ID != id {
+			t.Errorf("mention %d = %+v, want type %d", i, m, want[i])
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package stats
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Export writes every invocation from (inclusive) until to (exclusive) in the
+// format, either "csv" (with a header row) or "json" (one object per line).
+func (s *Store) Export(w io.Writer, format string, from, to time.Time) error {
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{
+			"time", "command", "userID", "guildID", "channelID",
+			"durationMs", "outcome", "rateLimited",
+		})
+
+		err := s.Range(from, to, func(inv Invocation) error {
+			return cw.Write([]string{
+				inv.Time.UTC().Format(time.RFC3339Nano),
+				inv.Command,
+				inv.UserID,
+				inv.GuildID,
+				inv.ChannelID,
+				strconv.FormatFloat(
+					float64(inv.Duration)/float64(time.Millisecond), 'f', 3, 64,
+				),
+				inv.Outcome,
+				strconv.FormatBool(inv.RateLimited),
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		cw.Flush()
+		return cw.Error()
+	case "json":
+		enc := json.NewEncoder(w)
+		return s.Range(from, to, func(inv Invocation) error {
+			return enc.Encode(inv)
+		})
+	}
+
+	return fmt.Errorf("unknown export format %q, use csv or json", format)
+}
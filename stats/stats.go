@@ -0,0 +1,257 @@
+package stats
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+	bolt "go.etcd.io/bbolt"
+)
+
+type (
+	// Store records command invocations to an on-disk database. Invocations
+	// are written in the background, so recording never slows down handling
+	// commands. Initialized with Open().
+	Store struct {
+		// OnError is called when invocations can't be written, if set
+		OnError func(error)
+
+		db      *bolt.DB
+		pending chan Invocation
+		done    chan struct{}
+		closed  bool
+		mu      sync.RWMutex
+	}
+
+	// Invocation is a single use of a command
+	Invocation struct {
+		Time        time.Time     `json:"time"`
+		Command     string        `json:"command"`
+		UserID      string        `json:"userID"`
+		GuildID     string        `json:"guildID"`
+		ChannelID   string        `json:"channelID"`
+		Duration    time.Duration `json:"duration"`
+		Outcome     string        `json:"outcome"`
+		RateLimited bool          `json:"rateLimited"`
+	}
+)
+
+var invocationsBucket = []byte("invocations")
+
+// ErrInUse is returned by Open when the database is already open, ie. by the
+// running bot, which holds it for as long as it runs
+var ErrInUse = errors.New("the database is in use by another process")
+
+/* Invocations waiting to be written, beyond which new ones are dropped */
+const maxPending = 1024
+
+// Open opens (or creates) the database at the path. An error is returned if
+// it's already open, ie. by the running bot.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if err == bolt.ErrTimeout {
+		return nil, fmt.Errorf("%s: %w", path, ErrInUse)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(invocationsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s := &Store{
+		db:      db,
+		pending: make(chan Invocation, maxPending),
+		done:    make(chan struct{}),
+	}
+	go s.write()
+	return s, nil
+}
+
+// Close writes any invocations which haven't been written, and closes the
+// database.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.pending)
+	}
+	s.mu.Unlock()
+	<-s.done
+
+	return s.db.Close()
+}
+
+// Record records an invocation. Returns false if it was dropped, because too
+// many are waiting to be written or the store is closed.
+func (s *Store) Record(inv Invocation) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return false
+	}
+
+	select {
+	case s.pending <- inv:
+		return true
+	default:
+		return false
+	}
+}
+
+// Observe is used as a multiplexer observer, recording every command handled
+func (s *Store) Observe(
+	ctx *multiplexer.Context, outcome multiplexer.Outcome, d time.Duration,
+) {
+	/* Commands which weren't found could be anything, so aren't named */
+	command := ctx.Command
+	if outcome == multiplexer.OutcomeNotFound {
+		command = ""
+	}
+
+	s.Record(Invocation{
+		Time:        time.Now().Add(-d),
+		Command:     command,
+		UserID:      ctx.Message.Author.ID,
+		GuildID:     ctx.Message.GuildID,
+		ChannelID:   ctx.Message.ChannelID,
+		Duration:    d,
+		Outcome:     string(outcome),
+		RateLimited: outcome == multiplexer.OutcomeRateLimited,
+	})
+}
+
+// Range calls fn with every invocation from (inclusive) until to (exclusive),
+// oldest first. A zero from or to is unbounded. Stops at the first error.
+func (s *Store) Range(from, to time.Time, fn func(Invocation) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(invocationsBucket).Cursor()
+
+		var k, v []byte
+		if from.IsZero() {
+			k, v = c.First()
+		} else {
+			k, v = c.Seek(timeKey(from))
+		}
+
+		end := timeKey(to)
+		for ; k != nil; k, v = c.Next() {
+			if !to.IsZero() && string(k[:8]) >= string(end) {
+				break
+			}
+
+			var inv Invocation
+			if err := json.Unmarshal(v, &inv); err != nil {
+				return err
+			}
+			if err := fn(inv); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ParseWindow parses how far back to look, as a Go duration (ie. "12h") or a
+// number of days or weeks (ie. "7d", "2w").
+func ParseWindow(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+
+	var (
+		d   time.Duration
+		err error
+	)
+	if unit != 0 {
+		var n int
+		n, err = strconv.Atoi(s[:len(s)-1])
+		d = time.Duration(n) * unit
+	} else {
+		d, err = time.ParseDuration(s)
+	}
+
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%q isn't a valid window, try 24h, 7d or 4w", s)
+	}
+	return d, nil
+}
+
+/* === Helper Functions === */
+
+// write writes invocations as they're recorded, in batches, until the store
+// is closed
+func (s *Store) write() {
+	defer close(s.done)
+
+	for inv := range s.pending {
+		batch := []Invocation{inv}
+
+		/* Take whatever else is waiting, so it's written at once */
+	drain:
+		for len(batch) < 100 {
+			select {
+			case inv, ok := <-s.pending:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, inv)
+			default:
+				break drain
+			}
+		}
+
+		err := s.db.Update(func(tx *bolt.Tx) error {
+			b := tx.Bucket(invocationsBucket)
+			for _, inv := range batch {
+				v, err := json.Marshal(inv)
+				if err != nil {
+					return err
+				}
+
+				/* Keys sort by time, the sequence keeps them unique */
+				seq, err := b.NextSequence()
+				if err != nil {
+					return err
+				}
+				key := make([]byte, 16)
+				copy(key, timeKey(inv.Time))
+				binary.BigEndian.PutUint64(key[8:], seq)
+
+				if err := b.Put(key, v); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil && s.OnError != nil {
+			s.OnError(err)
+		}
+	}
+}
+
+// timeKey encodes a time so that keys sort in time order
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
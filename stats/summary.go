@@ -0,0 +1,128 @@
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/multiplexer"
+)
+
+type (
+	// Summary is how commands were used over a window of time
+	Summary struct {
+		From, To time.Time
+
+		// Uses includes rate limited and failed uses
+		Uses, RateLimited, Failed int
+
+		// Commands and Users are sorted by uses, most first
+		Commands []Count
+		Users    []Count
+		// Trend is the number of uses over time, split into equal buckets
+		Trend []Bucket
+	}
+
+	// Count is how many times a command was used, or a user used commands
+	Count struct {
+		Key         string
+		Uses        int
+		RateLimited int
+	}
+
+	// Bucket is how many uses there were in part of the window
+	Bucket struct {
+		Start time.Time
+		Uses  int
+	}
+
+	// Filter limits a summary to a guild and/or a command, if set
+	Filter struct {
+		GuildID, Command string
+	}
+)
+
+// Summarize summarizes the uses of commands from (inclusive) until to
+// (exclusive), with the trend split into the supplied number of buckets.
+// Messages which didn't match a command aren't counted.
+func (s *Store) Summarize(
+	from, to time.Time, f Filter, buckets int,
+) (*Summary, error) {
+	if buckets < 1 {
+		buckets = 1
+	}
+
+	sum := &Summary{From: from, To: to, Trend: make([]Bucket, buckets)}
+	size := to.Sub(from) / time.Duration(buckets)
+	for i := range sum.Trend {
+		sum.Trend[i].Start = from.Add(size * time.Duration(i))
+	}
+
+	commands := make(map[string]*Count)
+	users := make(map[string]*Count)
+
+	err := s.Range(from, to, func(inv Invocation) error {
+		if len(inv.Command) == 0 ||
+			(len(f.GuildID) != 0 && inv.GuildID != f.GuildID) ||
+			(len(f.Command) != 0 && inv.Command != f.Command) {
+			return nil
+		}
+
+		sum.Uses++
+		switch multiplexer.Outcome(inv.Outcome) {
+		case multiplexer.OutcomeError, multiplexer.OutcomePanic:
+			sum.Failed++
+		}
+		if inv.RateLimited {
+			sum.RateLimited++
+		}
+
+		count(commands, inv.Command, inv.RateLimited)
+		count(users, inv.UserID, inv.RateLimited)
+
+		if size > 0 {
+			i := int(inv.Time.Sub(from) / size)
+			if i >= buckets {
+				i = buckets - 1
+			}
+			sum.Trend[i].Uses++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sum.Commands = sorted(commands)
+	sum.Users = sorted(users)
+	return sum, nil
+}
+
+/* === Helper Functions === */
+
+func count(counts map[string]*Count, key string, rateLimited bool) {
+	c, ok := counts[key]
+	if !ok {
+		c = &Count{Key: key}
+		counts[key] = c
+	}
+
+	c.Uses++
+	if rateLimited {
+		c.RateLimited++
+	}
+}
+
+func sorted(counts map[string]*Count) []Count {
+	out := make([]Count, 0, len(counts))
+	for _, c := range counts {
+		out = append(out, *c)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Uses != out[j].Uses {
+			return out[i].Uses > out[j].Uses
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
@@ -0,0 +1,241 @@
+package tags
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Store is a persistent collection of tags, saved as JSON to the path it
+	// was opened with. Initialized with Open().
+	Store struct {
+		path string
+		tags map[string]*Tag
+
+		mu sync.RWMutex
+	}
+
+	// Tag is a user-defined simple command created at runtime
+	Tag struct {
+		Name    string    `json:"name"`
+		Content string    `json:"content"`
+		OwnerID string    `json:"ownerID"`
+		Owner   string    `json:"owner"`
+		Created time.Time `json:"created"`
+		Edited  time.Time `json:"edited,omitempty"`
+	}
+)
+
+const (
+	// MaxNameLength is the longest name a tag may have
+	MaxNameLength = 32
+	// MaxContentLength is the longest content a tag may have, which is the
+	// maximum length of a Discord message
+	MaxContentLength = 2000
+)
+
+var nameRE = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// Open loads the tags stored at the supplied path. If no file exists at the
+// path, an empty store is returned and the file is created on the first save.
+// If the path is empty, the tags are only kept in memory.
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path: path,
+		tags: make(map[string]*Tag),
+	}
+
+	if len(path) == 0 {
+		return s, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return s, nil
+	}
+
+	var tags []*Tag
+	if err := json.Unmarshal(data, &tags); err != nil {
+		return nil, fmt.Errorf("unable to parse tags file %s: %v", path, err)
+	}
+
+	for _, t := range tags {
+		s.tags[strings.ToLower(t.Name)] = t
+	}
+
+	return s, nil
+}
+
+// ValidName checks that the supplied name can be used for a tag
+func ValidName(name string) error {
+	if len(name) == 0 || len(name) > MaxNameLength {
+		return fmt.Errorf(
+			"tag names must be between 1 and %d characters", MaxNameLength,
+		)
+	}
+
+	if !nameRE.MatchString(name) {
+		return fmt.Errorf(
+			"tag names may only contain lowercase letters, numbers, `-` and `_`",
+		)
+	}
+
+	return nil
+}
+
+// Get returns the tag with the supplied name
+func (s *Store) Get(name string) (Tag, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tags[strings.ToLower(name)]
+	if !ok {
+		return Tag{}, false
+	}
+	return *t, true
+}
+
+// List returns every tag in the store, sorted by name
+func (s *Store) List() []Tag {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]Tag, 0, len(s.tags))
+	for _, t := range s.tags {
+		out = append(out, *t)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Add creates a new tag and saves the store
+func (s *Store) Add(t Tag) error {
+	t.Name = strings.ToLower(t.Name)
+	if err := ValidName(t.Name); err != nil {
+		return err
+	}
+
+	if err := validContent(t.Content); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tags[t.Name]; ok {
+		return fmt.Errorf("tag `%s` already exists", t.Name)
+	}
+
+	if t.Created.IsZero() {
+		t.Created = time.Now()
+	}
+	s.tags[t.Name] = &t
+
+	if err := s.save(); err != nil {
+		/* Undo the change, so it matches what's saved */
+		delete(s.tags, t.Name)
+		return err
+	}
+	return nil
+}
+
+// Edit replaces the content of an existing tag and saves the store
+func (s *Store) Edit(name, content string) error {
+	if err := validContent(content); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tags[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("tag `%s` does not exist", name)
+	}
+
+	previous := *t
+	t.Content = content
+	t.Edited = time.Now()
+
+	if err := s.save(); err != nil {
+		/* Undo the change, so it matches what's saved */
+		*t = previous
+		return err
+	}
+	return nil
+}
+
+// Remove deletes a tag and saves the store
+func (s *Store) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name = strings.ToLower(name)
+	t, ok := s.tags[name]
+	if !ok {
+		return fmt.Errorf("tag `%s` does not exist", name)
+	}
+	delete(s.tags, name)
+
+	if err := s.save(); err != nil {
+		/* Undo the change, so it matches what's saved */
+		s.tags[name] = t
+		return err
+	}
+	return nil
+}
+
+// save writes the store to disk. Must be called with the lock held.
+func (s *Store) save() error {
+	if len(s.path) == 0 {
+		return nil
+	}
+
+	tags := make([]*Tag, 0, len(s.tags))
+	for _, t := range s.tags {
+		tags = append(tags, t)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+
+	data, err := json.MarshalIndent(tags, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	/* Write to a temporary file first so a failed write can't eat the tags */
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.path)
+}
+
+func validContent(content string) error {
+	if len(strings.TrimSpace(content)) == 0 {
+		return fmt.Errorf("tags can't be empty")
+	}
+
+	if len(content) > MaxContentLength {
+		return fmt.Errorf(
+			"tags can't be longer than %d characters", MaxContentLength,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package tags
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFailedSavesAreUndone(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	s, err := Open(filepath.Join(dir, "tags.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	/* The directory doesn't exist yet, so saving fails */
+	if err := s.Add(Tag{Name: "hi", Content: "hello"}); err == nil {
+		t.Fatal("adding a tag which couldn't be saved didn't fail")
+	}
+	if _, ok := s.Get("hi"); ok {
+		t.Error("a tag which couldn't be saved was added")
+	}
+
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(Tag{Name: "hi", Content: "hello"}); err != nil {
+		t.Fatalf("adding the tag again failed: %v", err)
+	}
+
+	os.RemoveAll(dir)
+
+	if err := s.Edit("hi", "goodbye"); err == nil {
+		t.Error("editing a tag which couldn't be saved didn't fail")
+	}
+	if tag, _ := s.Get("hi"); tag.Content != "hello" || !tag.Edited.IsZero() {
+		t.Errorf("a failed edit changed the tag to %+v", tag)
+	}
+
+	if err := s.Remove("hi"); err == nil {
+		t.Error("removing a tag which couldn't be saved didn't fail")
+	}
+	if _, ok := s.Get("hi"); !ok {
+		t.Error("a failed remove removed the tag")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	s, err := Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Add(Tag{Name: "Hi", Content: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(Tag{Name: "hi", Content: "again"}); err == nil {
+		t.Error("a tag was added twice")
+	}
+	if err := s.Edit("HI", "goodbye"); err != nil {
+		t.Fatal(err)
+	}
+	if tag, ok := s.Get("hi"); !ok || tag.Content != "goodbye" {
+		t.Errorf("the edited tag is %+v", tag)
+	}
+	if err := s.Remove("hi"); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.List()) != 0 {
+		t.Errorf("tags are left after removing them: %+v", s.List())
+	}
+}
@@ -0,0 +1,275 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Exporter exports finished traces in OpenTelemetry's OTLP/JSON format,
+	// to a collector and/or a file. Traces are exported in the background, in
+	// batches. Initialized with NewExporter().
+	Exporter struct {
+		// OnError is called when traces can't be exported, if set
+		OnError func(error)
+
+		service  string
+		endpoint string
+		file     string
+		client   *http.Client
+		pending  chan []SpanData
+		done     chan struct{}
+		closed   bool
+		mu       sync.RWMutex
+	}
+
+	/* OTLP/JSON, as accepted by a collector's /v1/traces endpoint */
+
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              Kind            `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+const (
+	/* Traces waiting to be exported, beyond which new ones are dropped */
+	maxPending = 256
+	/* Most traces exported at once */
+	maxBatch = 64
+	/* How long to wait for the collector */
+	exportTimeout = 10 * time.Second
+
+	/* OTLP status codes */
+	statusOK    = 1
+	statusError = 2
+)
+
+// NewExporter creates an exporter for the service. Traces are sent to the
+// collector at the endpoint (ie. "http://localhost:4318") and/or appended to
+// the file, one request per line. Either can be empty.
+func NewExporter(service, endpoint, file string) *Exporter {
+	if len(endpoint) != 0 && !strings.HasSuffix(endpoint, "/v1/traces") {
+		endpoint = strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	}
+
+	e := &Exporter{
+		service:  service,
+		endpoint: endpoint,
+		file:     file,
+		client:   &http.Client{Timeout: exportTimeout},
+		pending:  make(chan []SpanData, maxPending),
+		done:     make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// Export queues a trace to be exported. Returns false if it was dropped,
+// because too many are waiting or the exporter is closed.
+func (e *Exporter) Export(t *Trace) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.closed {
+		return false
+	}
+
+	select {
+	case e.pending <- t.Spans():
+		return true
+	default:
+		return false
+	}
+}
+
+// Close exports any traces which are waiting
+func (e *Exporter) Close() {
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.pending)
+	}
+	e.mu.Unlock()
+	<-e.done
+}
+
+/* === Helper Functions === */
+
+// run exports traces as they're queued, until the exporter is closed
+func (e *Exporter) run() {
+	defer close(e.done)
+
+	for spans := range e.pending {
+		batch := spans
+
+		/* Take whatever else is waiting, so it's exported at once */
+	drain:
+		for i := 1; i < maxBatch; i++ {
+			select {
+			case spans, ok := <-e.pending:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, spans...)
+			default:
+				break drain
+			}
+		}
+
+		if err := e.write(batch); err != nil && e.OnError != nil {
+			e.OnError(err)
+		}
+	}
+}
+
+// write sends spans to the collector and appends them to the file. Both are
+// tried, even if one fails.
+func (e *Exporter) write(spans []SpanData) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	if len(e.endpoint) != 0 {
+		if err := e.post(body); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(e.file) != 0 {
+		if err := e.append(body); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("unable to export traces: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// post sends an OTLP request to the collector
+func (e *Exporter) post(body []byte) error {
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("collector responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// append appends an OTLP request to the file
+func (e *Exporter) append(body []byte) error {
+	f, err := os.OpenFile(e.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(body, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// request converts spans into an OTLP request
+func (e *Exporter) request(spans []SpanData) otlpRequest {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		status := otlpStatus{Code: statusOK}
+		if len(s.Error) != 0 {
+			status = otlpStatus{Code: statusError, Message: s.Error}
+		}
+
+		out = append(out, otlpSpan{
+			TraceID:           s.TraceID,
+			SpanID:            s.ID,
+			ParentSpanID:      s.ParentID,
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        attributes(s.Attributes),
+			Status:            status,
+		})
+	}
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: attributes(map[string]string{
+			"service.name": e.service,
+		})},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: e.service},
+			Spans: out,
+		}},
+	}}}
+}
+
+// attributes converts attributes into OTLP's format, sorted by key
+func attributes(attrs map[string]string) []otlpAttribute {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make([]otlpAttribute, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, otlpAttribute{Key: k, Value: otlpValue{StringValue: attrs[k]}})
+	}
+	return out
+}
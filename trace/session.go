@@ -0,0 +1,239 @@
+package trace
+
+import (
+	"io"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/bwmarrin/discordgo"
+)
+
+// tracedSession records Discord API calls as spans below its parent span
+type tracedSession struct {
+	session.Session
+	parent func() *Span
+}
+
+// WrapSession wraps a session, so each API call made with it is recorded as a
+// span below the span the parent function returns at the time. Calls to State
+// aren't recorded, as they don't make requests.
+func WrapSession(s session.Session, parent func() *Span) session.Session {
+	if t, ok := s.(tracedSession); ok {
+		s = t.Session
+	}
+	return tracedSession{Session: s, parent: parent}
+}
+
+func (t tracedSession) User(userID string) (*discordgo.User, error) {
+	span := t.start("User")
+	u, err := t.Session.User(userID)
+	end(span, err)
+	return u, err
+}
+
+func (t tracedSession) UserChannelCreate(
+	recipientID string,
+) (*discordgo.Channel, error) {
+	span := t.start("UserChannelCreate")
+	c, err := t.Session.UserChannelCreate(recipientID)
+	end(span, err)
+	return c, err
+}
+
+func (t tracedSession) UpdateStatusComplex(
+	usd discordgo.UpdateStatusData,
+) error {
+	span := t.start("UpdateStatusComplex")
+	err := t.Session.UpdateStatusComplex(usd)
+	end(span, err)
+	return err
+}
+
+func (t tracedSession) Channel(channelID string) (*discordgo.Channel, error) {
+	span := t.start("Channel", channelID)
+	c, err := t.Session.Channel(channelID)
+	end(span, err)
+	return c, err
+}
+
+func (t tracedSession) ChannelTyping(channelID string) error {
+	span := t.start("ChannelTyping", channelID)
+	err := t.Session.ChannelTyping(channelID)
+	end(span, err)
+	return err
+}
+
+func (t tracedSession) ChannelMessage(
+	channelID, messageID string,
+) (*discordgo.Message, error) {
+	span := t.start("ChannelMessage", channelID)
+	m, err := t.Session.ChannelMessage(channelID, messageID)
+	end(span, err)
+	return m, err
+}
+
+func (t tracedSession) ChannelMessages(
+	channelID string, limit int, beforeID, afterID, aroundID string,
+) ([]*discordgo.Message, error) {
+	span := t.start("ChannelMessages", channelID)
+	m, err := t.Session.ChannelMessages(
+		channelID, limit, beforeID, afterID, aroundID,
+	)
+	end(span, err)
+	return m, err
+}
+
+func (t tracedSession) ChannelMessageSend(
+	channelID string, content string,
+) (*discordgo.Message, error) {
+	span := t.start("ChannelMessageSend", channelID)
+	m, err := t.Session.ChannelMessageSend(channelID, content)
+	end(span, err)
+	return m, err
+}
+
+func (t tracedSession) ChannelMessageSendEmbed(
+	channelID string, embed *discordgo.MessageEmbed,
+) (*discordgo.Message, error) {
+	span := t.start("ChannelMessageSendEmbed", channelID)
+	m, err := t.Session.ChannelMessageSendEmbed(channelID, embed)
+	end(span, err)
+	return m, err
+}
+
+func (t tracedSession) ChannelMessageSendComplex(
+	channelID string, data *discordgo.MessageSend,
+) (*discordgo.Message, error) {
+	span := t.start("ChannelMessageSendComplex", channelID)
+	m, err := t.Session.ChannelMessageSendComplex(channelID, data)
+	end(span, err)
+	return m, err
+}
+
+func (t tracedSession) ChannelMessageSendReply(
+	channelID string,
+	data *discordgo.MessageSend,
+	ref *discordgo.MessageReference,
+) (*discordgo.Message, error) {
+	span := t.start("ChannelMessageSendReply", channelID)
+	m, err := t.Session.ChannelMessageSendReply(channelID, data, ref)
+	end(span, err)
+	return m, err
+}
+
+func (t tracedSession) ChannelMessageEditComplex(
+	edit *discordgo.MessageEdit,
+) (*discordgo.Message, error) {
+	span := t.start("ChannelMessageEditComplex", edit.Channel)
+	m, err := t.Session.ChannelMessageEditComplex(edit)
+	end(span, err)
+	return m, err
+}
+
+func (t tracedSession) ChannelMessageEditEmbed(
+	channelID, messageID string, embed *discordgo.MessageEmbed,
+) (*discordgo.Message, error) {
+	span := t.start("ChannelMessageEditEmbed", channelID)
+	m, err := t.Session.ChannelMessageEditEmbed(channelID, messageID, embed)
+	end(span, err)
+	return m, err
+}
+
+func (t tracedSession) ChannelMessageDelete(channelID, messageID string) error {
+	span := t.start("ChannelMessageDelete", channelID)
+	err := t.Session.ChannelMessageDelete(channelID, messageID)
+	end(span, err)
+	return err
+}
+
+func (t tracedSession) ChannelFileSend(
+	channelID, name string, r io.Reader,
+) (*discordgo.Message, error) {
+	span := t.start("ChannelFileSend", channelID)
+	m, err := t.Session.ChannelFileSend(channelID, name, r)
+	end(span, err)
+	return m, err
+}
+
+func (t tracedSession) Guild(guildID string) (*discordgo.Guild, error) {
+	span := t.start("Guild")
+	g, err := t.Session.Guild(guildID)
+	end(span, err)
+	return g, err
+}
+
+func (t tracedSession) GuildRoles(guildID string) ([]*discordgo.Role, error) {
+	span := t.start("GuildRoles")
+	r, err := t.Session.GuildRoles(guildID)
+	end(span, err)
+	return r, err
+}
+
+func (t tracedSession) GuildMember(
+	guildID, userID string,
+) (*discordgo.Member, error) {
+	span := t.start("GuildMember")
+	m, err := t.Session.GuildMember(guildID, userID)
+	end(span, err)
+	return m, err
+}
+
+func (t tracedSession) GuildMemberRoleAdd(guildID, userID, roleID string) error {
+	span := t.start("GuildMemberRoleAdd")
+	err := t.Session.GuildMemberRoleAdd(guildID, userID, roleID)
+	end(span, err)
+	return err
+}
+
+func (t tracedSession) GuildMemberRoleRemove(
+	guildID, userID, roleID string,
+) error {
+	span := t.start("GuildMemberRoleRemove")
+	err := t.Session.GuildMemberRoleRemove(guildID, userID, roleID)
+	end(span, err)
+	return err
+}
+
+func (t tracedSession) MessageReactionAdd(
+	channelID, messageID, emojiID string,
+) error {
+	span := t.start("MessageReactionAdd", channelID)
+	err := t.Session.MessageReactionAdd(channelID, messageID, emojiID)
+	end(span, err)
+	return err
+}
+
+func (t tracedSession) MessageReactionRemove(
+	channelID, messageID, emojiID, userID string,
+) error {
+	span := t.start("MessageReactionRemove", channelID)
+	err := t.Session.MessageReactionRemove(channelID, messageID, emojiID, userID)
+	end(span, err)
+	return err
+}
+
+func (t tracedSession) MessageReactionsRemoveAll(
+	channelID, messageID string,
+) error {
+	span := t.start("MessageReactionsRemoveAll", channelID)
+	err := t.Session.MessageReactionsRemoveAll(channelID, messageID)
+	end(span, err)
+	return err
+}
+
+/* === Helper Functions === */
+
+// start starts the span for an API call, in the channel if there is one
+func (t tracedSession) start(method string, channelID ...string) *Span {
+	span := t.parent().Child("discord." + method)
+	span.SetKind(KindClient)
+	if len(channelID) != 0 {
+		span.SetAttribute("discord.channel", channelID[0])
+	}
+	return span
+}
+
+// end ends the span for an API call, which failed if there's an error
+func end(span *Span, err error) {
+	span.Fail(err)
+	span.End()
+}
@@ -0,0 +1,226 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+type (
+	// Trace is the spans recorded while handling something, ie. a command
+	// invocation. Its ID is used to find everything logged about it.
+	// Initialized with New().
+	Trace struct {
+		ID string
+		// Root is the span covering the whole trace, which the other spans
+		// are below
+		Root *Span
+
+		spans []*Span
+		mu    sync.Mutex
+	}
+
+	// Span is a stage of a trace, ie. checking permissions or an API call.
+	// Started with Trace.Start() or Span.Child().
+	Span struct {
+		ID       string
+		ParentID string
+
+		trace      *Trace
+		kind       Kind
+		name       string
+		start, end time.Time
+		attributes map[string]string
+		err        string
+		mu         sync.Mutex
+	}
+
+	// SpanData is a snapshot of a span, for exporting
+	SpanData struct {
+		TraceID    string
+		ID         string
+		ParentID   string
+		Name       string
+		Kind       Kind
+		Start      time.Time
+		End        time.Time
+		Attributes map[string]string
+		// Error is why the span failed, if it did
+		Error string
+	}
+
+	// Kind is what a span represents, as defined by OpenTelemetry
+	Kind int
+
+	// contextKey is the key spans are stored under in a context.Context
+	contextKey struct{}
+)
+
+const (
+	// KindInternal is a stage within the bot
+	KindInternal Kind = 1
+	// KindServer is handling something sent to the bot, ie. a message
+	KindServer Kind = 2
+	// KindClient is a request made by the bot, ie. an API call
+	KindClient Kind = 3
+)
+
+// New starts a trace, with a root span of the given name
+func New(name string) *Trace {
+	t := &Trace{ID: newID(16)}
+	t.Root = t.Start(name, "")
+	t.Root.SetKind(KindServer)
+	return t
+}
+
+// Start starts a span within the trace, below the parent span (or at the top
+// of the trace if the parent ID is empty).
+func (t *Trace) Start(name, parentID string) *Span {
+	s := &Span{
+		ID:       newID(8),
+		ParentID: parentID,
+		trace:    t,
+		kind:     KindInternal,
+		name:     name,
+		start:    time.Now(),
+	}
+
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+	return s
+}
+
+// Spans returns a snapshot of every span in the trace. Spans which haven't
+// ended yet are given the time the root span ended (or now).
+func (t *Trace) Spans() []SpanData {
+	t.mu.Lock()
+	spans := append([]*Span(nil), t.spans...)
+	t.mu.Unlock()
+
+	end := t.Root.data().End
+	if end.IsZero() {
+		end = time.Now()
+	}
+
+	out := make([]SpanData, 0, len(spans))
+	for _, s := range spans {
+		d := s.data()
+		if d.End.IsZero() {
+			d.End = end
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// Child starts a span below this one
+func (s *Span) Child(name string) *Span {
+	return s.trace.Start(name, s.ID)
+}
+
+// TraceID returns the ID of the span's trace
+func (s *Span) TraceID() string {
+	return s.trace.ID
+}
+
+// SetName renames the span, ie. once what it's doing is known
+func (s *Span) SetName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.name = name
+}
+
+// SetKind sets what the span represents, which is KindInternal by default
+func (s *Span) SetKind(kind Kind) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.kind = kind
+}
+
+// SetAttribute records something about the span
+func (s *Span) SetAttribute(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.attributes == nil {
+		s.attributes = make(map[string]string)
+	}
+	s.attributes[key] = value
+}
+
+// Fail marks the span as failed. Nil errors are ignored.
+func (s *Span) Fail(err error) {
+	if err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err = err.Error()
+}
+
+// End ends the span. Only the first call has any effect.
+func (s *Span) End() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.end.IsZero() {
+		s.end = time.Now()
+	}
+}
+
+// WithSpan returns a context carrying the span, so requests made with it are
+// recorded below the span (see Transport).
+func WithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, contextKey{}, s)
+}
+
+// FromContext returns the span carried by the context, if there is one
+func FromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(contextKey{}).(*Span)
+	return s
+}
+
+/* === Helper Functions === */
+
+// data takes a snapshot of the span
+func (s *Span) data() SpanData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attributes := make(map[string]string, len(s.attributes))
+	for k, v := range s.attributes {
+		attributes[k] = v
+	}
+
+	return SpanData{
+		TraceID:    s.trace.ID,
+		ID:         s.ID,
+		ParentID:   s.ParentID,
+		Name:       s.name,
+		Kind:       s.kind,
+		Start:      s.start,
+		End:        s.end,
+		Attributes: attributes,
+		Error:      s.err,
+	}
+}
+
+// newID creates a random ID of n bytes, hex encoded
+func newID(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		/* Only used to tell traces apart, so the time will do */
+		now := time.Now().UnixNano()
+		for i := range b {
+			b[i] = byte(now >> (8 * (i % 8)))
+		}
+	}
+	return hex.EncodeToString(b)
+}
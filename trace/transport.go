@@ -0,0 +1,59 @@
+package trace
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// InvocationHeader is the header outbound requests carry the trace ID in, so
+// they can be matched up with the invocation which made them
+const InvocationHeader = "X-Invocation-ID"
+
+// Transport records requests made with a span in their context (see
+// WithSpan) as spans below it, and adds the trace to their headers. Requests
+// without a span are sent as they are.
+type Transport struct {
+	// Base is the transport requests are sent with, http.DefaultTransport if
+	// it's nil
+	Base http.RoundTripper
+}
+
+// RoundTrip sends a request, recording it if it's part of a trace
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	parent := FromContext(req.Context())
+	if parent == nil {
+		return base.RoundTrip(req)
+	}
+
+	span := parent.Child("HTTP " + req.Method)
+	span.SetKind(KindClient)
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.host", req.URL.Host)
+	span.SetAttribute("http.path", req.URL.Path)
+	defer span.End()
+
+	/* Requests mustn't be changed by transports, so headers go on a copy */
+	req = req.Clone(req.Context())
+	req.Header.Set(InvocationHeader, span.TraceID())
+	req.Header.Set("traceparent", fmt.Sprintf(
+		"00-%s-%s-01", span.TraceID(), span.ID,
+	))
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		span.Fail(err)
+		return nil, err
+	}
+
+	span.SetAttribute("http.status_code", strconv.Itoa(resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.Fail(fmt.Errorf("%s", resp.Status))
+	}
+	return resp, nil
+}
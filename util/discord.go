@@ -0,0 +1,213 @@
+package util
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// MaxMessageLength is the maximum length of a Discord message
+	MaxMessageLength = 2000
+
+	// EmbedColor is the default color of the bot's embeds
+	EmbedColor = 0xfdd329
+
+	/* Limits Discord puts on embeds, in characters */
+
+	// MaxEmbedTitle is the maximum length of an embed's title
+	MaxEmbedTitle = 256
+	// MaxEmbedDescription is the maximum length of an embed's description
+	MaxEmbedDescription = 4096
+	// MaxFieldName is the maximum length of an embed field's name
+	MaxFieldName = 256
+	// MaxFieldValue is the maximum length of an embed field's value
+	MaxFieldValue = 1024
+	// MaxEmbedFooter is the maximum length of an embed's footer
+	MaxEmbedFooter = 2048
+	// MaxEmbed is the maximum length of everything in an embed together
+	MaxEmbed = 6000
+)
+
+// StyleEmbed applies the bot's shared styling to an embed, without overriding
+// anything which has already been set.
+func StyleEmbed(embed *discordgo.MessageEmbed) *discordgo.MessageEmbed {
+	if embed.Color == 0 {
+		embed.Color = EmbedColor
+	}
+
+	return embed
+}
+
+// LimitEmbed truncates everything in the embed to Discord's limits, and fills
+// in empty field values (which Discord rejects)
+func LimitEmbed(embed *discordgo.MessageEmbed) {
+	embed.Title = Truncate(embed.Title, MaxEmbedTitle)
+	embed.Description = Truncate(embed.Description, MaxEmbedDescription)
+	if embed.Footer != nil {
+		embed.Footer.Text = Truncate(embed.Footer.Text, MaxEmbedFooter)
+	}
+
+	size := utf8.RuneCountInString(embed.Title) +
+		utf8.RuneCountInString(embed.Description)
+	if embed.Footer != nil {
+		size += utf8.RuneCountInString(embed.Footer.Text)
+	}
+	if embed.Author != nil {
+		embed.Author.Name = Truncate(embed.Author.Name, MaxEmbedTitle)
+		size += utf8.RuneCountInString(embed.Author.Name)
+	}
+
+	/* The description and field values are what's cut if it's too long */
+	values := []*string{&embed.Description}
+	for _, f := range embed.Fields {
+		f.Name = Truncate(f.Name, MaxFieldName)
+		if len(f.Name) == 0 {
+			f.Name = "-"
+		}
+		f.Value = Truncate(f.Value, MaxFieldValue)
+		if len(f.Value) == 0 {
+			f.Value = "-"
+		}
+		size += utf8.RuneCountInString(f.Name) + utf8.RuneCountInString(f.Value)
+		values = append(values, &f.Value)
+	}
+
+	/* Take whatever's over the limit from the longest values */
+	for size > MaxEmbed {
+		longest := values[0]
+		for _, v := range values {
+			if utf8.RuneCountInString(*v) > utf8.RuneCountInString(*longest) {
+				longest = v
+			}
+		}
+
+		n := utf8.RuneCountInString(*longest)
+		cut := size - MaxEmbed
+		if cut > n-1 {
+			cut = n - 1
+		}
+		if cut <= 0 {
+			break
+		}
+		*longest = Truncate(*longest, n-cut)
+		size -= cut
+	}
+}
+
+// Truncate shortens a string to at most n characters, marking where it was cut
+func Truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n-1]) + "…"
+}
+
+// SendReply sends a message to the channel as a reply to the referenced
+// message. Files can't be sent as replies, so messages with files are sent
+// normally.
+func SendReply(
+	session session.Session,
+	channelID string,
+	msg *discordgo.MessageSend,
+	ref *discordgo.MessageReference,
+) (*discordgo.Message, error) {
+	if ref == nil || len(msg.Files) != 0 || msg.File != nil {
+		return session.ChannelMessageSendComplex(channelID, msg)
+	}
+
+	return session.ChannelMessageSendReply(channelID, msg, ref)
+}
+
+// SendDM sends a direct message to the user. Content which is too long for
+// one message is split, and the last message sent is returned.
+func SendDM(
+	session session.Session, userID string, msg *discordgo.MessageSend,
+) (*discordgo.Message, error) {
+	ch, err := session.UserChannelCreate(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := SplitMessage(msg.Content, MaxMessageLength)
+	if len(chunks) > 1 {
+		for _, c := range chunks[:len(chunks)-1] {
+			if _, err := session.ChannelMessageSend(ch.ID, c); err != nil {
+				return nil, err
+			}
+		}
+
+		last := *msg
+		last.Content = chunks[len(chunks)-1]
+		msg = &last
+	}
+
+	return session.ChannelMessageSendComplex(ch.ID, msg)
+}
+
+// DMFallback is the message sent in place of a direct message when a user
+// doesn't accept direct messages. What would have been sent isn't repeated, as
+// it was meant to be private.
+const DMFallback = "I couldn't DM you. Allow direct messages from server " +
+	"members, then try again."
+
+// SplitMessage splits text into chunks no longer than the limit. Text is split
+// on newlines where possible, then spaces, and never in the middle of a
+// character. Code blocks which are split are closed and re-opened.
+func SplitMessage(text string, limit int) []string {
+	var (
+		chunks []string
+		open   bool // Whether a code block is open at the start of the chunk
+	)
+
+	for len(text) > 0 {
+		prefix := ""
+		if open {
+			prefix = "```\n"
+		}
+
+		/* Leave room for re-opening and closing a code block */
+		room := limit - len(prefix) - len("\n```")
+		if len(prefix)+len(text) <= limit {
+			chunks = append(chunks, prefix+text)
+			break
+		}
+
+		cut := splitPoint(text, room)
+		chunk := prefix + text[:cut]
+		text = strings.TrimLeft(text[cut:], "\n ")
+
+		if strings.Count(chunk, "```")%2 == 1 {
+			chunk += "\n```"
+			open = true
+		} else {
+			open = false
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
+}
+
+// splitPoint finds the best place to split the text, at or before the limit
+func splitPoint(text string, limit int) int {
+	if limit <= 0 {
+		limit = 1
+	}
+
+	if i := strings.LastIndex(text[:limit], "\n"); i > limit/2 {
+		return i
+	}
+	if i := strings.LastIndex(text[:limit], " "); i > limit/2 {
+		return i
+	}
+
+	/* Don't cut a multi-byte character in half */
+	for limit > 1 && !utf8.RuneStart(text[limit]) {
+		limit--
+	}
+	return limit
+}
@@ -0,0 +1,132 @@
+package util
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/session"
+	"github.com/bwmarrin/discordgo"
+)
+
+func embedSize(e *discordgo.MessageEmbed) int {
+	size := utf8.RuneCountInString(e.Title) +
+		utf8.RuneCountInString(e.Description)
+	if e.Footer != nil {
+		size += utf8.RuneCountInString(e.Footer.Text)
+	}
+	if e.Author != nil {
+		size += utf8.RuneCountInString(e.Author.Name)
+	}
+	for _, f := range e.Fields {
+		size += utf8.RuneCountInString(f.Name) + utf8.RuneCountInString(f.Value)
+	}
+	return size
+}
+
+func TestLimitEmbed(t *testing.T) {
+	long := func(n int) string { return strings.Repeat("é", n) }
+	fields := func(n, length int) []*discordgo.MessageEmbedField {
+		var out []*discordgo.MessageEmbedField
+		for i := 0; i < n; i++ {
+			out = append(out, &discordgo.MessageEmbedField{
+				Name: "name", Value: long(length),
+			})
+		}
+		return out
+	}
+
+	tests := []struct {
+		name  string
+		embed *discordgo.MessageEmbed
+	}{
+		{"empty", &discordgo.MessageEmbed{}},
+		{"long title", &discordgo.MessageEmbed{Title: long(300)}},
+		{"long description", &discordgo.MessageEmbed{Description: long(5000)}},
+		{
+			"long description and footer",
+			&discordgo.MessageEmbed{
+				Description: long(4096),
+				Footer:      &discordgo.MessageEmbedFooter{Text: long(2048)},
+			},
+		},
+		{"many fields", &discordgo.MessageEmbed{Fields: fields(10, 1024)}},
+		{
+			"description and fields",
+			&discordgo.MessageEmbed{
+				Description: long(4000), Fields: fields(5, 1024),
+			},
+		},
+		{"empty fields", &discordgo.MessageEmbed{Fields: fields(2, 0)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			LimitEmbed(tt.embed)
+
+			if size := embedSize(tt.embed); size > MaxEmbed {
+				t.Errorf("embed is %d characters, want at most %d", size, MaxEmbed)
+			}
+			if n := utf8.RuneCountInString(tt.embed.Title); n > MaxEmbedTitle {
+				t.Errorf("title is %d characters", n)
+			}
+			if n := utf8.RuneCountInString(tt.embed.Description); n > MaxEmbedDescription {
+				t.Errorf("description is %d characters", n)
+			}
+			for _, f := range tt.embed.Fields {
+				if n := utf8.RuneCountInString(f.Value); n > MaxFieldValue || n == 0 {
+					t.Errorf("field value is %d characters", n)
+				}
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"hello", 5, "hello"},
+		{"hello", 10, "hello"},
+		{"hello world", 5, "hell…"},
+		{"ééééé", 3, "éé…"},
+	}
+
+	for _, tt := range tests {
+		if got := Truncate(tt.in, tt.n); got != tt.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestSendDM(t *testing.T) {
+	f := session.NewFake(&discordgo.User{ID: "1", Username: "bot", Bot: true})
+	f.AddUser(&discordgo.User{ID: "2", Username: "user"})
+
+	content := strings.Repeat("word ", 1000)
+	if _, err := SendDM(f, "2", &discordgo.MessageSend{Content: content}); err != nil {
+		t.Fatalf("SendDM failed: %v", err)
+	}
+
+	actions := f.Actions()
+	if len(actions) != 3 {
+		t.Fatalf("SendDM sent %d messages, want 3", len(actions))
+	}
+	var sent strings.Builder
+	for _, a := range actions {
+		if n := len(a.Message.Content); n > MaxMessageLength {
+			t.Errorf("sent a message of %d characters", n)
+		}
+		sent.WriteString(a.Message.Content + " ")
+	}
+	if strings.Fields(sent.String())[999] != "word" ||
+		len(strings.Fields(sent.String())) != 1000 {
+		t.Error("the split messages don't add up to the content")
+	}
+
+	if _, err := SendDM(f, "unknown", &discordgo.MessageSend{Content: "hi"}); err == nil {
+		t.Error("SendDM to an unknown user succeeded")
+	}
+}
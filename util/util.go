@@ -5,6 +5,8 @@ import (
 	"os"
 	"regexp"
 	"strings"
+
+	"github.com/PulseDevelopmentGroup/0x626f74/snowflake"
 )
 
 /* === Helpers === */
@@ -38,6 +40,22 @@ func ArrayContains(array []string, value string, ignoreCase bool) bool {
 	return false
 }
 
+// TrimFields removes the first n space separated fields from the supplied
+// string, returning the rest of it untouched (newlines and formatting intact).
+func TrimFields(input string, n int) string {
+	for i := 0; i < n; i++ {
+		input = strings.TrimLeft(input, " \t\n")
+
+		end := strings.IndexAny(input, " \t\n")
+		if end == -1 {
+			return ""
+		}
+		input = input[end:]
+	}
+
+	return strings.TrimLeft(input, " \t\n")
+}
+
 // IsURL checks the provided string to see if it's a valid URL.
 func IsURL(test string) bool {
 	_, err := url.ParseRequestURI(test)
@@ -53,23 +71,36 @@ func IsURL(test string) bool {
 	return true
 }
 
+// GetMsgURL builds the link to a message
 func GetMsgURL(guildID, channelID, messageID string) string {
-	return "https://discordapp.com/channels/" +
+	return "https://discord.com/channels/" +
 		guildID + "/" + channelID + "/" + messageID
 }
 
+// ParseMsgURL gets the guild, channel and message IDs from a message link.
+// Links from the old discordapp.com domain, and the PTB and Canary clients
+// are supported.
+func ParseMsgURL(link string) (guildID, channelID, messageID string, ok bool) {
+	m := msgURLRE.FindStringSubmatch(strings.Trim(link, "<>"))
+	if m == nil {
+		return "", "", "", false
+	}
+
+	return m[1], m[2], m[3], true
+}
+
 var (
-	idRE      = regexp.MustCompile(`^\d{18}`)
-	botRE     = regexp.MustCompile(`<@&\d{18}>`)
-	userRE    = regexp.MustCompile(`<@!*\d{18}>`)
-	channelRE = regexp.MustCompile(`<#\d{18}>`)
+	idExtractRE = regexp.MustCompile(`\d{17,20}`)
 
-	idExtractRE = regexp.MustCompile(`\d{18}`)
+	msgURLRE = regexp.MustCompile(
+		`^https?://(?:(?:ptb|canary)\.)?discord(?:app)?\.com/channels/` +
+			`(\d{17,20}|@me)/(\d{17,20})/(\d{17,20})/?$`,
+	)
 )
 
 // IsID checks if the supplied string is a Discord ID
 func IsID(test string) bool {
-	return idRE.MatchString(test)
+	return snowflake.IsValid(test)
 }
 
 // GetID returns the ID (if there is one) from the supplied string
@@ -77,17 +108,20 @@ func GetID(input string) string {
 	return idExtractRE.FindString(input)
 }
 
-// IsBot checks if the supplied string is mentioning a bot
-func IsBot(test string) bool {
-	return botRE.MatchString(test)
+// IsRole checks if the supplied string is mentioning a role
+func IsRole(test string) bool {
+	_, ok := snowflake.ParseRole(test)
+	return ok
 }
 
 // IsUser checks if the supplied string is mentioning a user
 func IsUser(test string) bool {
-	return userRE.MatchString(test)
+	_, ok := snowflake.ParseUser(test)
+	return ok
 }
 
 // IsChannel checks if the supplied string is mentioning a channel
 func IsChannel(test string) bool {
-	return channelRE.MatchString(test)
+	_, ok := snowflake.ParseChannel(test)
+	return ok
 }
@@ -0,0 +1,62 @@
+package util
+
+import "testing"
+
+func TestMentions(t *testing.T) {
+	const id = "175928847299117063"
+
+	tests := []struct {
+		name    string
+		in      string
+		isID    bool
+		user    bool
+		role    bool
+		channel bool
+	}{
+		{name: "ID", in: id, isID: true},
+		{name: "user", in: "<@" + id + ">", user: true},
+		{name: "nickname", in: "<@!" + id + ">", user: true},
+		{name: "role", in: "<@&" + id + ">", role: true},
+		{name: "channel", in: "<#" + id + ">", channel: true},
+		{name: "short ID", in: "1234"},
+		{name: "overflowing ID", in: "99999999999999999999"},
+		{name: "ID in text", in: "id " + id},
+		{name: "mention in text", in: "hi <@" + id + ">"},
+		{name: "mention with text after", in: "<#" + id + "> hi"},
+		{name: "empty", in: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsID(tt.in); got != tt.isID {
+				t.Errorf("IsID(%q) = %v, want %v", tt.in, got, tt.isID)
+			}
+			if got := IsUser(tt.in); got != tt.user {
+				t.Errorf("IsUser(%q) = %v, want %v", tt.in, got, tt.user)
+			}
+			if got := IsRole(tt.in); got != tt.role {
+				t.Errorf("IsRole(%q) = %v, want %v", tt.in, got, tt.role)
+			}
+			if got := IsChannel(tt.in); got != tt.channel {
+				t.Errorf("IsChannel(%q) = %v, want %v", tt.in, got, tt.channel)
+			}
+		})
+	}
+}
+
+func TestGetID(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"175928847299117063", "175928847299117063"},
+		{"<@!175928847299117063>", "175928847299117063"},
+		{"<#175928847299117063>", "175928847299117063"},
+		{"no id here", ""},
+	}
+
+	for _, tt := range tests {
+		if got := GetID(tt.in); got != tt.want {
+			t.Errorf("GetID(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
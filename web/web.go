@@ -0,0 +1,134 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type (
+	// Server serves health checks, metrics and anything else registered with
+	// Handle over HTTP. Initialized with New().
+	Server struct {
+		http   *http.Server
+		mux    *http.ServeMux
+		checks []check
+		mu     sync.RWMutex
+	}
+
+	// Check reports whether part of the bot is ready, returning why it isn't
+	Check func() error
+
+	check struct {
+		name string
+		fn   Check
+	}
+
+	// checkResult is the JSON response of the readiness endpoint
+	checkResult struct {
+		Ready  bool              `json:"ready"`
+		Checks map[string]string `json:"checks"`
+	}
+)
+
+// New creates a server which will listen on the address (ie. ":8080"), and
+// serves /healthz and /readyz. /metrics is served by the supplied handler, if
+// it isn't nil.
+func New(addr string, metrics http.Handler) *Server {
+	s := &Server{mux: http.NewServeMux()}
+	s.http = &http.Server{
+		Addr:         addr,
+		Handler:      s.mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+
+	s.mux.HandleFunc("/healthz", s.healthz)
+	s.mux.HandleFunc("/readyz", s.readyz)
+	if metrics != nil {
+		s.mux.Handle("/metrics", metrics)
+	}
+
+	return s
+}
+
+// AddCheck adds a readiness check. The bot is only ready once every check
+// passes.
+func (s *Server) AddCheck(name string, c Check) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checks = append(s.checks, check{name, c})
+}
+
+// Handle registers a handler for the pattern, as with http.ServeMux
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.mux.Handle(pattern, h)
+}
+
+// Start starts listening, and serves requests in the background. An error is
+// returned if the address can't be listened on.
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.http.Addr)
+	if err != nil {
+		return err
+	}
+
+	go s.http.Serve(ln)
+	return nil
+}
+
+// Close stops the server, waiting a few seconds for requests to finish
+func (s *Server) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.http.Shutdown(ctx)
+}
+
+/* === Handlers === */
+
+// healthz responds as long as the process is alive
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok\n"))
+}
+
+// readyz runs every check, responding with 503 if any of them fail
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	checks := make([]check, len(s.checks))
+	copy(checks, s.checks)
+	s.mu.RUnlock()
+
+	result := checkResult{Ready: true, Checks: make(map[string]string)}
+	for _, c := range checks {
+		if err := c.fn(); err != nil {
+			result.Ready = false
+			result.Checks[c.name] = err.Error()
+			continue
+		}
+		result.Checks[c.name] = "ok"
+	}
+
+	status := http.StatusOK
+	if !result.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	WriteJSON(w, status, result)
+}
+
+/* === Helper Functions === */
+
+// WriteJSON writes a JSON response with the status code
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}